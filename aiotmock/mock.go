@@ -0,0 +1,374 @@
+// Package aiotmock cung cấp một bản giả lập của aiot.API dùng trong unit test.
+//
+// Mỗi phương thức của Client ghi lại lời gọi và gọi hàm XxxFunc tương ứng nếu
+// được gán, ngược lại trả về giá trị rỗng và lỗi nil.
+package aiotmock
+
+import (
+	"sync"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+var _ aiot.API = (*Client)(nil)
+
+// Call là một lời gọi đã được ghi lại
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+type Client struct {
+	TokenFunc         func(email, password string) (string, error)
+	TokenVerifyFunc   func(token string) (bool, error)
+	ResetPasswordFunc func(token, newPW, oldPW string) error
+	UserProfileFunc   func(token string) (aiot.User, error)
+
+	ListThingsByUserFunc   func(token string, opts *aiot.ListThingsByUserOptions) ([]aiot.Thing, int, error)
+	CreateThingFunc        func(token string, in aiot.CreateThingInput) error
+	DeleteThingFunc        func(token, thingID string) error
+	ThingProfileFunc       func(token, thingID string) (aiot.Thing, error)
+	UpdateThingFunc        func(token string, in aiot.UpdateThingInput) error
+	RotateThingKeyFunc     func(token, thingID string) (string, error)
+	PatchThingMetadataFunc func(token, thingID string, patch aiot.Metadata) (aiot.Thing, error)
+	ListChannelByThingFunc func(token, thingID string, opts *aiot.ListChannelByThingOptions) ([]aiot.Channel, int, error)
+	ConnectFunc            func(token string, channelIDs []string, thingIDs []string) error
+	DisconnectFunc         func(token, channelID, thingID string) error
+
+	CreateChannelFunc        func(token string, in aiot.CreateChannelInput) error
+	UpdateChannelFunc        func(token string, in aiot.UpdateChannelInput) error
+	PatchChannelMetadataFunc func(token, channelID string, patch aiot.Metadata) (aiot.Channel, error)
+	DeleteChannelFunc        func(token, channelID string) error
+	ChannelProfileFunc       func(token, channelID string) (aiot.Channel, error)
+	ListAllChannelFunc       func(token string, opts *aiot.ListAllChannelOptions) ([]aiot.Channel, int, error)
+	ListChannelByUserFunc    func(token string, opts *aiot.ListChannelByUserOptions) ([]aiot.Channel, int, error)
+
+	CreateGatewayFunc            func(token string, in aiot.CreateGatewayInput) error
+	UpdateGatewayFunc            func(token string, in aiot.UpdateGatewayInput) error
+	DeleteGatewayFunc            func(token, id string) error
+	GatewayProfileFunc           func(token, id string) (aiot.Gateway, error)
+	ListGatewayFunc              func(token string) ([]aiot.Gateway, error)
+	GatewayStatusFunc            func(token string) (map[string]bool, error)
+	GatewayActiveDeviceCountFunc func(token, gateID string) (int, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Tất cả lời gọi theo thứ tự
+func (m *Client) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Call(nil), m.calls...)
+}
+
+// Các lời gọi đến một phương thức
+func (m *Client) CallsTo(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []Call
+	for _, c := range m.calls {
+		if c.Method == method {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// Xóa các lời gọi đã ghi lại
+func (m *Client) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = nil
+}
+
+func (m *Client) record(method string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+func (m *Client) Token(email, password string) (string, error) {
+	m.record("Token", email, password)
+
+	if m.TokenFunc != nil {
+		return m.TokenFunc(email, password)
+	}
+
+	return "", nil
+}
+
+func (m *Client) TokenVerify(token string) (bool, error) {
+	m.record("TokenVerify", token)
+
+	if m.TokenVerifyFunc != nil {
+		return m.TokenVerifyFunc(token)
+	}
+
+	return false, nil
+}
+
+func (m *Client) ResetPassword(token, newPW, oldPW string) error {
+	m.record("ResetPassword", token, newPW, oldPW)
+
+	if m.ResetPasswordFunc != nil {
+		return m.ResetPasswordFunc(token, newPW, oldPW)
+	}
+
+	return nil
+}
+
+func (m *Client) UserProfile(token string) (aiot.User, error) {
+	m.record("UserProfile", token)
+
+	if m.UserProfileFunc != nil {
+		return m.UserProfileFunc(token)
+	}
+
+	return aiot.User{}, nil
+}
+
+func (m *Client) ListThingsByUser(token string, opts *aiot.ListThingsByUserOptions) ([]aiot.Thing, int, error) {
+	m.record("ListThingsByUser", token, opts)
+
+	if m.ListThingsByUserFunc != nil {
+		return m.ListThingsByUserFunc(token, opts)
+	}
+
+	return nil, 0, nil
+}
+
+func (m *Client) CreateThing(token string, in aiot.CreateThingInput) error {
+	m.record("CreateThing", token, in)
+
+	if m.CreateThingFunc != nil {
+		return m.CreateThingFunc(token, in)
+	}
+
+	return nil
+}
+
+func (m *Client) DeleteThing(token, thingID string) error {
+	m.record("DeleteThing", token, thingID)
+
+	if m.DeleteThingFunc != nil {
+		return m.DeleteThingFunc(token, thingID)
+	}
+
+	return nil
+}
+
+func (m *Client) ThingProfile(token, thingID string) (aiot.Thing, error) {
+	m.record("ThingProfile", token, thingID)
+
+	if m.ThingProfileFunc != nil {
+		return m.ThingProfileFunc(token, thingID)
+	}
+
+	return aiot.Thing{}, nil
+}
+
+func (m *Client) UpdateThing(token string, in aiot.UpdateThingInput) error {
+	m.record("UpdateThing", token, in)
+
+	if m.UpdateThingFunc != nil {
+		return m.UpdateThingFunc(token, in)
+	}
+
+	return nil
+}
+
+func (m *Client) RotateThingKey(token, thingID string) (string, error) {
+	m.record("RotateThingKey", token, thingID)
+
+	if m.RotateThingKeyFunc != nil {
+		return m.RotateThingKeyFunc(token, thingID)
+	}
+
+	return "", nil
+}
+
+func (m *Client) PatchThingMetadata(token, thingID string, patch aiot.Metadata) (aiot.Thing, error) {
+	m.record("PatchThingMetadata", token, thingID, patch)
+
+	if m.PatchThingMetadataFunc != nil {
+		return m.PatchThingMetadataFunc(token, thingID, patch)
+	}
+
+	return aiot.Thing{}, nil
+}
+
+func (m *Client) ListChannelByThing(token, thingID string, opts *aiot.ListChannelByThingOptions) ([]aiot.Channel, int, error) {
+	m.record("ListChannelByThing", token, thingID, opts)
+
+	if m.ListChannelByThingFunc != nil {
+		return m.ListChannelByThingFunc(token, thingID, opts)
+	}
+
+	return nil, 0, nil
+}
+
+func (m *Client) Connect(token string, channelIDs []string, thingIDs []string) error {
+	m.record("Connect", token, channelIDs, thingIDs)
+
+	if m.ConnectFunc != nil {
+		return m.ConnectFunc(token, channelIDs, thingIDs)
+	}
+
+	return nil
+}
+
+func (m *Client) Disconnect(token, channelID, thingID string) error {
+	m.record("Disconnect", token, channelID, thingID)
+
+	if m.DisconnectFunc != nil {
+		return m.DisconnectFunc(token, channelID, thingID)
+	}
+
+	return nil
+}
+
+func (m *Client) CreateChannel(token string, in aiot.CreateChannelInput) error {
+	m.record("CreateChannel", token, in)
+
+	if m.CreateChannelFunc != nil {
+		return m.CreateChannelFunc(token, in)
+	}
+
+	return nil
+}
+
+func (m *Client) UpdateChannel(token string, in aiot.UpdateChannelInput) error {
+	m.record("UpdateChannel", token, in)
+
+	if m.UpdateChannelFunc != nil {
+		return m.UpdateChannelFunc(token, in)
+	}
+
+	return nil
+}
+
+func (m *Client) PatchChannelMetadata(token, channelID string, patch aiot.Metadata) (aiot.Channel, error) {
+	m.record("PatchChannelMetadata", token, channelID, patch)
+
+	if m.PatchChannelMetadataFunc != nil {
+		return m.PatchChannelMetadataFunc(token, channelID, patch)
+	}
+
+	return aiot.Channel{}, nil
+}
+
+func (m *Client) DeleteChannel(token, channelID string) error {
+	m.record("DeleteChannel", token, channelID)
+
+	if m.DeleteChannelFunc != nil {
+		return m.DeleteChannelFunc(token, channelID)
+	}
+
+	return nil
+}
+
+func (m *Client) ChannelProfile(token, channelID string) (aiot.Channel, error) {
+	m.record("ChannelProfile", token, channelID)
+
+	if m.ChannelProfileFunc != nil {
+		return m.ChannelProfileFunc(token, channelID)
+	}
+
+	return aiot.Channel{}, nil
+}
+
+func (m *Client) ListAllChannel(token string, opts *aiot.ListAllChannelOptions) ([]aiot.Channel, int, error) {
+	m.record("ListAllChannel", token, opts)
+
+	if m.ListAllChannelFunc != nil {
+		return m.ListAllChannelFunc(token, opts)
+	}
+
+	return nil, 0, nil
+}
+
+func (m *Client) ListChannelByUser(token string, opts *aiot.ListChannelByUserOptions) ([]aiot.Channel, int, error) {
+	m.record("ListChannelByUser", token, opts)
+
+	if m.ListChannelByUserFunc != nil {
+		return m.ListChannelByUserFunc(token, opts)
+	}
+
+	return nil, 0, nil
+}
+
+func (m *Client) CreateGateway(token string, in aiot.CreateGatewayInput) error {
+	m.record("CreateGateway", token, in)
+
+	if m.CreateGatewayFunc != nil {
+		return m.CreateGatewayFunc(token, in)
+	}
+
+	return nil
+}
+
+func (m *Client) UpdateGateway(token string, in aiot.UpdateGatewayInput) error {
+	m.record("UpdateGateway", token, in)
+
+	if m.UpdateGatewayFunc != nil {
+		return m.UpdateGatewayFunc(token, in)
+	}
+
+	return nil
+}
+
+func (m *Client) DeleteGateway(token, id string) error {
+	m.record("DeleteGateway", token, id)
+
+	if m.DeleteGatewayFunc != nil {
+		return m.DeleteGatewayFunc(token, id)
+	}
+
+	return nil
+}
+
+func (m *Client) GatewayProfile(token, id string) (aiot.Gateway, error) {
+	m.record("GatewayProfile", token, id)
+
+	if m.GatewayProfileFunc != nil {
+		return m.GatewayProfileFunc(token, id)
+	}
+
+	return aiot.Gateway{}, nil
+}
+
+func (m *Client) ListGateway(token string) ([]aiot.Gateway, error) {
+	m.record("ListGateway", token)
+
+	if m.ListGatewayFunc != nil {
+		return m.ListGatewayFunc(token)
+	}
+
+	return nil, nil
+}
+
+func (m *Client) GatewayStatus(token string) (map[string]bool, error) {
+	m.record("GatewayStatus", token)
+
+	if m.GatewayStatusFunc != nil {
+		return m.GatewayStatusFunc(token)
+	}
+
+	return nil, nil
+}
+
+func (m *Client) GatewayActiveDeviceCount(token, gateID string) (int, error) {
+	m.record("GatewayActiveDeviceCount", token, gateID)
+
+	if m.GatewayActiveDeviceCountFunc != nil {
+		return m.GatewayActiveDeviceCountFunc(token, gateID)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,83 @@
+package aiotmock_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiotmock"
+	"github.com/stretchr/testify/require"
+)
+
+// renameAll là một hàm nghiệp vụ chỉ phụ thuộc vào aiot.ThingService.
+func renameAll(svc aiot.ThingService, token, prefix string) error {
+	things, _, err := svc.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	if err != nil {
+		return err
+	}
+
+	for i, t := range things {
+		err := svc.UpdateThing(token, aiot.UpdateThingInput{
+			ID:       t.ID,
+			Name:     fmt.Sprintf("%s-%d", prefix, i),
+			Metadata: t.Metadata,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Test_Mock_RecordsCalls(t *testing.T) {
+	require := require.New(t)
+
+	m := &aiotmock.Client{
+		ListThingsByUserFunc: func(token string, opts *aiot.ListThingsByUserOptions) ([]aiot.Thing, int, error) {
+			return []aiot.Thing{{ID: "t-1"}, {ID: "t-2"}}, 2, nil
+		},
+	}
+
+	require.NoError(renameAll(m, "token", "sensor"))
+
+	calls := m.CallsTo("UpdateThing")
+	require.Len(calls, 2)
+	require.Equal("token", calls[0].Args[0])
+	require.Equal(aiot.UpdateThingInput{ID: "t-2", Name: "sensor-1"}, calls[1].Args[1])
+	require.Len(m.Calls(), 3)
+
+	m.Reset()
+	require.Empty(m.Calls())
+}
+
+func Test_Mock_ScriptedError(t *testing.T) {
+	require := require.New(t)
+
+	errBoom := errors.New("boom")
+	m := &aiotmock.Client{
+		ListThingsByUserFunc: func(token string, opts *aiot.ListThingsByUserOptions) ([]aiot.Thing, int, error) {
+			return []aiot.Thing{{ID: "t-1"}}, 1, nil
+		},
+		UpdateThingFunc: func(token string, in aiot.UpdateThingInput) error {
+			return errBoom
+		},
+	}
+
+	require.ErrorIs(renameAll(m, "token", "sensor"), errBoom)
+}
+
+func Test_Mock_ZeroValues(t *testing.T) {
+	require := require.New(t)
+
+	var api aiot.API = &aiotmock.Client{}
+
+	token, err := api.Token("email", "password")
+	require.NoError(err)
+	require.Empty(token)
+
+	gateways, err := api.ListGateway(token)
+	require.NoError(err)
+	require.Nil(gateways)
+}
@@ -0,0 +1,38 @@
+package aiottest
+
+import (
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+// Thông tin đăng nhập của user do NewAccount tạo
+const (
+	AccountEmail    = "ops@aiot.vn"
+	AccountPassword = "secret"
+)
+
+// NewAccount khởi động một Server có sẵn user AccountEmail, đăng nhập và trả
+// về client kết nối tới server cùng token. Server được đóng khi test kết thúc.
+func NewAccount(t testing.TB) (aiot.Client, string) {
+	srv, token := NewAccountServer(t)
+
+	return aiot.NewClient(srv.URL), token
+}
+
+// NewAccountServer giống NewAccount nhưng trả về Server, dùng khi test cần tự
+// cấu hình client hoặc điều khiển server.
+func NewAccountServer(t testing.TB) (*Server, string) {
+	t.Helper()
+
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddUser(User{Email: AccountEmail, Password: AccountPassword})
+
+	token, err := aiot.NewClient(srv.URL).Token(AccountEmail, AccountPassword)
+	if err != nil {
+		t.Fatalf("aiottest: login %s: %v", AccountEmail, err)
+	}
+
+	return srv, token
+}
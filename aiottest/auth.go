@@ -0,0 +1,78 @@
+package aiottest
+
+import "net/http"
+
+func (s *Server) login(c *call) {
+	var body struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if !c.decode(&body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[body.Email]
+	if !ok || u.Password != body.Password {
+		c.error(http.StatusUnauthorized, CodeUnauthorized, "invalid email or password")
+		return
+	}
+
+	token := newToken()
+	s.tokens[token] = &session{email: u.Email, limit: s.tokenLimit}
+
+	c.json(http.StatusCreated, map[string]string{"token": "Bearer " + token})
+}
+
+func (s *Server) verify(c *call) {
+	c.json(http.StatusOK, map[string]bool{"valid": true})
+}
+
+func (s *Server) resetPassword(c *call) {
+	var body struct {
+		NewPassword string `json:"newPassword"`
+		OldPassword string `json:"oldPassword"`
+	}
+	if !c.decode(&body) {
+		return
+	}
+
+	if body.NewPassword == "" {
+		c.error(http.StatusBadRequest, CodeBadRequest, "new password must not be empty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.users[c.user]
+	if u.Password != body.OldPassword {
+		c.error(http.StatusForbidden, CodeForbidden, "old password is incorrect")
+		return
+	}
+
+	u.Password = body.NewPassword
+
+	c.json(http.StatusCreated, map[string]string{})
+}
+
+func (s *Server) userProfile(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.users[c.user]
+
+	c.json(http.StatusOK, map[string]interface{}{
+		"email":        u.Email,
+		"fullName":     u.Fullname,
+		"phoneNumber":  u.Phonenumber,
+		"desc":         u.Description,
+		"customerId":   u.CustomerId,
+		"userTypeId":   u.UserTypeId,
+		"userStatusId": u.UserStatusId,
+		"userGroupId":  u.UserGroupId,
+		"createdBy":    u.CreatedBy,
+	})
+}
@@ -0,0 +1,328 @@
+package aiottest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Thing và channel có cùng cấu trúc và cùng ngữ nghĩa CRUD trên gateway nên
+// dùng chung các handler bên dưới.
+
+type entityInput struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	Metadata map[string]interface{} `json:"metadata"`
+	// Chỉ dùng khi cập nhật thing, rỗng là giữ nguyên key
+	Key string `json:"key"`
+}
+
+func (s *Server) createEntity(c *call, kind string) {
+	var in entityInput
+	if !c.decode(&in) {
+		return
+	}
+
+	if strings.TrimSpace(in.Name) == "" {
+		c.error(http.StatusBadRequest, CodeBadRequest, "name must not be empty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := &entity{
+		ID:       newID(),
+		Key:      newID(),
+		Name:     in.Name,
+		Metadata: copyMetadata(in.Metadata),
+		Owner:    c.user,
+	}
+	s.store(kind)[e.ID] = e
+
+	c.w.Header().Set("Location", e.ID)
+	c.json(http.StatusCreated, map[string]string{"id": e.ID})
+}
+
+func (s *Server) updateEntity(c *call, kind string) {
+	var in entityInput
+	if !c.decode(&in) {
+		return
+	}
+
+	if strings.TrimSpace(in.Name) == "" {
+		c.error(http.StatusBadRequest, CodeBadRequest, "name must not be empty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.owned(c, kind, in.ID)
+	if !ok || !s.ifMatch(c, toJSON(e)) {
+		return
+	}
+
+	if kind == "thing" && in.Key != "" {
+		if !s.keyFree(c, e, in.Key) {
+			return
+		}
+		e.Key = in.Key
+	}
+
+	e.Name = in.Name
+	e.Metadata = copyMetadata(in.Metadata)
+
+	c.json(http.StatusOK, map[string]string{})
+}
+
+// keyFree báo key chưa được thing hoặc channel nào khác ngoài e dùng, nếu
+// đã được dùng thì trả về lỗi 409
+func (s *Server) keyFree(c *call, e *entity, key string) bool {
+	for _, store := range []map[string]*entity{s.things, s.channels} {
+		for _, other := range store {
+			if other != e && other.Key == key {
+				c.error(http.StatusConflict, CodeConflict, "key is already in use")
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (s *Server) entityProfile(c *call, kind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.owned(c, kind, c.params["id"])
+	if !ok {
+		return
+	}
+
+	s.setETag(c, toJSON(e))
+	c.json(http.StatusOK, toJSON(e))
+}
+
+func (s *Server) listEntities(c *call, kind string) {
+	var p page
+	if !c.decode(&p) || !p.validate(c) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []*entity
+	for _, e := range s.store(kind) {
+		if e.Owner == c.user {
+			items = append(items, e)
+		}
+	}
+
+	c.json(http.StatusOK, paginate(items, p))
+}
+
+func (s *Server) store(kind string) map[string]*entity {
+	if kind == "thing" {
+		return s.things
+	}
+
+	return s.channels
+}
+
+// owned trả về entity thuộc về user đang gọi. Entity của user khác được coi
+// như không tồn tại.
+func (s *Server) owned(c *call, kind, id string) (*entity, bool) {
+	e, ok := s.store(kind)[id]
+	if !ok || e.Owner != c.user {
+		c.error(http.StatusNotFound, CodeNotFound, kind+" not found: "+id)
+		return nil, false
+	}
+
+	return e, true
+}
+
+func (s *Server) createThing(c *call) {
+	s.createEntity(c, "thing")
+}
+
+func (s *Server) updateThing(c *call) {
+	s.updateEntity(c, "thing")
+}
+
+func (s *Server) thingProfile(c *call) {
+	s.entityProfile(c, "thing")
+}
+
+func (s *Server) listThings(c *call) {
+	s.listEntities(c, "thing")
+}
+
+// Xóa thing kéo theo các kết nối đến channel và gateway dùng thing đó.
+func (s *Server) deleteThing(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.owned(c, "thing", c.params["id"])
+	if !ok {
+		return
+	}
+
+	delete(s.things, e.ID)
+	delete(s.conns, e.ID)
+	for id, g := range s.gateways {
+		if g.ThingID == e.ID {
+			delete(s.gateways, id)
+		}
+	}
+
+	c.w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) createChannel(c *call) {
+	s.createEntity(c, "channel")
+}
+
+func (s *Server) updateChannel(c *call) {
+	s.updateEntity(c, "channel")
+}
+
+func (s *Server) channelProfile(c *call) {
+	s.entityProfile(c, "channel")
+}
+
+func (s *Server) listChannels(c *call) {
+	s.listEntities(c, "channel")
+}
+
+// listAllChannels liệt kê channel của mọi user trên nền tảng.
+func (s *Server) listAllChannels(c *call) {
+	var p page
+	if !c.decode(&p) || !p.validate(c) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []*entity
+	for _, e := range s.channels {
+		items = append(items, e)
+	}
+
+	c.json(http.StatusOK, paginate(items, p))
+}
+
+func (s *Server) deleteChannel(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.owned(c, "channel", c.params["id"])
+	if !ok {
+		return
+	}
+
+	delete(s.channels, e.ID)
+	for _, chans := range s.conns {
+		delete(chans, e.ID)
+	}
+
+	c.w.WriteHeader(http.StatusOK)
+}
+
+// listChannelsByThing liệt kê channel của user theo trạng thái kết nối với
+// thing. Gateway thật trả về các channel đang kết nối khi disconnected là
+// "true" và các channel chưa kết nối khi là "false"; server giả lập giữ
+// nguyên cách hiểu này.
+func (s *Server) listChannelsByThing(c *call) {
+	var body struct {
+		page
+		Disconnected string `json:"disconnected"`
+	}
+	if !c.decode(&body) || !body.validate(c) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.owned(c, "thing", c.params["id"])
+	if !ok {
+		return
+	}
+
+	connected := body.Disconnected != "false"
+
+	var items []*entity
+	for _, ch := range s.channels {
+		if ch.Owner == c.user && s.conns[t.ID][ch.ID] == connected {
+			items = append(items, ch)
+		}
+	}
+
+	c.json(http.StatusOK, paginate(items, body.page))
+}
+
+func (s *Server) connect(c *call) {
+	var body struct {
+		ChannelIDs []string `json:"channel_ids"`
+		ThingIDs   []string `json:"thing_ids"`
+	}
+	if !c.decode(&body) {
+		return
+	}
+
+	if len(body.ChannelIDs) == 0 || len(body.ThingIDs) == 0 {
+		c.error(http.StatusBadRequest, CodeBadRequest, "channel_ids and thing_ids must not be empty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range body.ThingIDs {
+		if _, ok := s.owned(c, "thing", id); !ok {
+			return
+		}
+	}
+	for _, id := range body.ChannelIDs {
+		if _, ok := s.owned(c, "channel", id); !ok {
+			return
+		}
+	}
+
+	for _, tid := range body.ThingIDs {
+		if s.conns[tid] == nil {
+			s.conns[tid] = make(map[string]bool)
+		}
+		for _, cid := range body.ChannelIDs {
+			s.conns[tid][cid] = true
+		}
+	}
+
+	c.json(http.StatusOK, map[string]string{})
+}
+
+func (s *Server) disconnect(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.owned(c, "thing", c.params["thingId"])
+	if !ok {
+		return
+	}
+
+	ch, ok := s.owned(c, "channel", c.params["channelId"])
+	if !ok {
+		return
+	}
+
+	if !s.conns[t.ID][ch.ID] {
+		c.error(http.StatusNotFound, CodeNotFound, "connection not found")
+		return
+	}
+
+	delete(s.conns[t.ID], ch.ID)
+
+	c.w.WriteHeader(http.StatusOK)
+}
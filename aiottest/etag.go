@@ -0,0 +1,52 @@
+package aiottest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Bật hoặc tắt ETag. Khi bật, response của các route xem thing, channel và
+// gateway có header ETag, và các route cập nhật từ chối request có header
+// If-Match không khớp với 412. Mặc định tắt, giống gateway thật.
+func (s *Server) EnableETags(enable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.etags = enable
+}
+
+func etag(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// setETag đặt header ETag cho response có nội dung v
+func (s *Server) setETag(c *call, v interface{}) {
+	if s.etags {
+		c.w.Header().Set("ETag", etag(v))
+	}
+}
+
+// ifMatch kiểm tra header If-Match với nội dung hiện tại v của đối tượng và
+// trả lời 412 nếu không khớp
+func (s *Server) ifMatch(c *call, v interface{}) bool {
+	match := c.r.Header.Get("If-Match")
+	if !s.etags || match == "" || match == "*" {
+		return true
+	}
+
+	current := etag(v)
+	for _, tag := range strings.Split(match, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == current {
+			return true
+		}
+	}
+
+	c.error(http.StatusPreconditionFailed, CodePreconditionFailed, "entity was modified, current etag is "+current)
+	return false
+}
@@ -0,0 +1,207 @@
+package aiottest
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+)
+
+// Route áp dụng cho mọi route khi cấu hình lỗi
+const AnyRoute = "*"
+
+// Fault mô tả cách gateway giả lập gây lỗi cho một route.
+//
+// Latency và Jitter được áp dụng trước, sau đó là một trong các lỗi theo thứ
+// tự ưu tiên: Reset, Status, Truncate, Malformed. Với Status và Reset, request
+// không được xử lý. Với Truncate và Malformed, request được xử lý bình thường
+// nhưng response gửi về bị hỏng.
+type Fault struct {
+	// Thời gian chờ cố định trước khi trả lời
+	Latency time.Duration
+	// Thời gian chờ ngẫu nhiên thêm vào, trong khoảng [0, Jitter)
+	Jitter time.Duration
+	// Trả về mã lỗi HTTP này thay vì xử lý request, ví dụ 503 hoặc 429
+	Status int
+	// Giá trị header Retry-After (làm tròn đến giây) đi kèm Status
+	RetryAfter time.Duration
+	// Đóng kết nối mà không trả lời
+	Reset bool
+	// Gửi response bị cắt giữa chừng
+	Truncate bool
+	// Gửi response có body không phải JSON hợp lệ
+	Malformed bool
+	// Số lần lỗi được áp dụng, 0 là luôn luôn
+	Times int
+}
+
+type faultState struct {
+	fault Fault
+	left  int
+}
+
+// Thêm lỗi cho route (dạng "METHOD /api-gw/v1/thing/{id}", xem Route) hoặc
+// AnyRoute. Các lỗi của cùng một route được áp dụng lần lượt theo thứ tự thêm
+// vào, lỗi có Times > 0 bị bỏ sau khi đã áp dụng đủ số lần.
+func (s *Server) InjectFault(route string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.faults == nil {
+		s.faults = make(map[string][]*faultState)
+	}
+
+	s.faults[route] = append(s.faults[route], &faultState{fault: f, left: f.Times})
+}
+
+// Xóa toàn bộ lỗi đã cấu hình
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// Mỗi token chỉ dùng được cho n request, sau đó gateway trả về lỗi token hết
+// hạn. n bằng 0 là không giới hạn. Chỉ áp dụng cho các token tạo sau lời gọi.
+func (s *Server) ExpireTokensAfter(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokenLimit = n
+}
+
+// Route trả về tên route (dạng "METHOD /api-gw/v1/thing/{id}") khớp với
+// request, dùng làm khóa cho InjectFault
+func (s *Server) Route(method, path string) (string, bool) {
+	rt, _, ok := s.match(method, path)
+	if !ok {
+		return "", false
+	}
+
+	return rt.name(), true
+}
+
+func (rt route) name() string {
+	return rt.method + " " + rt.pattern
+}
+
+// nextFault lấy lỗi kế tiếp của route, ưu tiên lỗi riêng của route trước lỗi
+// AnyRoute.
+func (s *Server) nextFault(name string) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range []string{name, AnyRoute} {
+		list := s.faults[key]
+		if len(list) == 0 {
+			continue
+		}
+
+		fs := list[0]
+		if fs.fault.Times > 0 {
+			fs.left--
+			if fs.left <= 0 {
+				s.faults[key] = list[1:]
+			}
+		}
+
+		return fs.fault, true
+	}
+
+	return Fault{}, false
+}
+
+// serveFault trả lời request theo f. Trả về false nếu request vẫn cần được
+// xử lý bình thường.
+func (s *Server) serveFault(w http.ResponseWriter, r *http.Request, f Fault, next func(http.ResponseWriter)) bool {
+	delay := f.Latency
+	if f.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(f.Jitter)))
+	}
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			return true
+		}
+	}
+
+	switch {
+	case f.Reset:
+		hijackClose(w)
+
+	case f.Status != 0:
+		if f.RetryAfter > 0 {
+			secs := int((f.RetryAfter + time.Second - 1) / time.Second)
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+		}
+		writeError(w, f.Status, statusCode(f.Status), http.StatusText(f.Status))
+
+	case f.Truncate:
+		rec := httptest.NewRecorder()
+		next(rec)
+
+		copyHeader(w.Header(), rec.Header())
+		w.Header().Set("Content-Length", strconv.Itoa(rec.Body.Len()+1))
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes()[:rec.Body.Len()/2])
+		hijackClose(w)
+
+	case f.Malformed:
+		rec := httptest.NewRecorder()
+		next(rec)
+
+		body := bytes.TrimRight(rec.Body.Bytes(), "\n")
+		if len(body) > 1 {
+			body = body[:len(body)-1]
+		}
+		body = append(body, []byte("<!--")...)
+
+		copyHeader(w.Header(), rec.Header())
+		w.Header().Del("Content-Length")
+		w.WriteHeader(rec.Code)
+		w.Write(body)
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+func hijackClose(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic("aiottest: ResponseWriter does not support hijacking")
+	}
+
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(err)
+	}
+	conn.Close()
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+func statusCode(status int) string {
+	switch status {
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
@@ -0,0 +1,145 @@
+package aiottest_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Fault_StatusTimes(t *testing.T) {
+	require := require.New(t)
+	srv, client, token := newServer(t)
+
+	route, ok := srv.Route(http.MethodGet, "/api-gw/v1/user/profile")
+	require.True(ok)
+	require.Equal("GET /api-gw/v1/user/profile", route)
+
+	srv.InjectFault(route, aiottest.Fault{Status: http.StatusServiceUnavailable, Times: 2})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.UserProfile(token)
+		require.Error(err)
+	}
+
+	up, err := client.UserProfile(token)
+	require.NoError(err)
+	require.Equal("a@aiot.vn", up.Email)
+}
+
+func Test_Fault_RetryAfter(t *testing.T) {
+	require := require.New(t)
+	srv, _, _ := newServer(t)
+
+	srv.InjectFault("GET /api-gw/v1/gateway/status", aiottest.Fault{
+		Status:     http.StatusTooManyRequests,
+		RetryAfter: 1500 * time.Millisecond,
+	})
+
+	resp, err := http.Get(srv.URL + "/api-gw/v1/gateway/status")
+	require.NoError(err)
+	resp.Body.Close()
+
+	require.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal("2", resp.Header.Get("Retry-After"))
+}
+
+func Test_Fault_Latency(t *testing.T) {
+	require := require.New(t)
+	srv, _, _ := newServer(t)
+
+	srv.InjectFault(aiottest.AnyRoute, aiottest.Fault{Latency: time.Second})
+
+	hc := http.Client{Timeout: 50 * time.Millisecond}
+	_, err := hc.Get(srv.URL + "/api-gw/v1/user/verify")
+	require.Error(err)
+
+	srv.ClearFaults()
+
+	resp, err := hc.Get(srv.URL + "/api-gw/v1/user/verify")
+	require.NoError(err)
+	resp.Body.Close()
+}
+
+func Test_Fault_BrokenResponses(t *testing.T) {
+	for name, f := range map[string]aiottest.Fault{
+		"malformed": {Malformed: true},
+		"truncate":  {Truncate: true},
+		"reset":     {Reset: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			srv, client, token := newServer(t)
+
+			srv.InjectFault("GET /api-gw/v1/user/profile", f)
+
+			_, err := client.UserProfile(token)
+			require.Error(err)
+
+			srv.ClearFaults()
+
+			_, err = client.UserProfile(token)
+			require.NoError(err)
+		})
+	}
+}
+
+func Test_Fault_ResetSkipsHandler(t *testing.T) {
+	require := require.New(t)
+	srv, client, token := newServer(t)
+
+	srv.InjectFault("POST /api-gw/v1/thing", aiottest.Fault{Reset: true, Times: 1})
+
+	err := client.CreateThing(token, aiot.CreateThingInput{Name: "thing-a"})
+	require.Error(err)
+
+	_, total, err := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Equal(0, total)
+}
+
+func Test_Fault_StatusSkipsHandler(t *testing.T) {
+	require := require.New(t)
+	srv, client, token := newServer(t)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "thing-a"}))
+	things, _, err := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+
+	srv.InjectFault("PUT /api-gw/v1/thing", aiottest.Fault{Status: http.StatusInternalServerError, Times: 1})
+
+	err = client.UpdateThing(token, aiot.UpdateThingInput{ID: things[0].ID, Name: "thing-b"})
+	require.Error(err)
+
+	srv.InjectFault("GET /api-gw/v1/thing/{id}", aiottest.Fault{Malformed: true, Times: 1})
+
+	_, err = client.ThingProfile(token, things[0].ID)
+	require.Error(err)
+
+	thing, err := client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.Equal("thing-a", thing.Name)
+}
+
+func Test_TokenExpiry(t *testing.T) {
+	require := require.New(t)
+	srv, client, _ := newServer(t)
+
+	srv.ExpireTokensAfter(2)
+
+	token, err := client.Token("a@aiot.vn", "pw")
+	require.NoError(err)
+
+	for i := 0; i < 2; i++ {
+		ok, err := client.TokenVerify(token)
+		require.NoError(err)
+		require.True(ok)
+	}
+
+	ok, err := client.TokenVerify(token)
+	require.Error(err)
+	require.False(ok)
+}
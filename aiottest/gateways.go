@@ -0,0 +1,204 @@
+package aiottest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type gatewayJSON struct {
+	GatewayID          string `json:"gatewayId"`
+	GatewayName        string `json:"gatewayName"`
+	GatewayDescription string `json:"gatewayDes"`
+	GatewayOwner       string `json:"gatewayOwner"`
+	ThingID            string `json:"thingId"`
+	ThingName          string `json:"thingName"`
+	ThingKey           string `json:"thingKey"`
+	ThingOwner         string `json:"thingOwner"`
+	Metadata           string `json:"metadata"`
+}
+
+// gatewayToJSON trả về gateway theo định dạng của gateway thật, trong đó
+// metadata của thing được mã hóa thành một chuỗi JSON.
+func (s *Server) gatewayToJSON(g *gateway) gatewayJSON {
+	out := gatewayJSON{
+		GatewayID:          g.ID,
+		GatewayName:        g.Name,
+		GatewayDescription: g.Description,
+		GatewayOwner:       g.Owner,
+		ThingID:            g.ThingID,
+	}
+
+	if t, ok := s.things[g.ThingID]; ok {
+		metadata, _ := json.Marshal(t.Metadata)
+
+		out.ThingName = t.Name
+		out.ThingKey = t.Key
+		out.ThingOwner = t.Owner
+		out.Metadata = string(metadata)
+	}
+
+	return out
+}
+
+func (s *Server) ownedGateway(c *call, id string) (*gateway, bool) {
+	g, ok := s.gateways[id]
+	if !ok || g.Owner != c.user {
+		c.error(http.StatusNotFound, CodeNotFound, "gateway not found: "+id)
+		return nil, false
+	}
+
+	return g, true
+}
+
+func (s *Server) createGateway(c *call) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		ThingID     string `json:"thingId"`
+	}
+	if !c.decode(&body) {
+		return
+	}
+
+	if strings.TrimSpace(body.Name) == "" {
+		c.error(http.StatusBadRequest, CodeBadRequest, "name must not be empty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.owned(c, "thing", body.ThingID); !ok {
+		return
+	}
+
+	for _, g := range s.gateways {
+		if g.ThingID == body.ThingID {
+			c.error(http.StatusConflict, CodeConflict, "thing is already used by gateway: "+g.ID)
+			return
+		}
+	}
+
+	g := &gateway{
+		ID:          newID(),
+		Name:        body.Name,
+		Description: body.Description,
+		Owner:       c.user,
+		ThingID:     body.ThingID,
+	}
+	s.gateways[g.ID] = g
+
+	c.json(http.StatusCreated, map[string]string{"id": g.ID})
+}
+
+func (s *Server) updateGateway(c *call) {
+	var body struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if !c.decode(&body) {
+		return
+	}
+
+	if strings.TrimSpace(body.Name) == "" {
+		c.error(http.StatusBadRequest, CodeBadRequest, "name must not be empty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.ownedGateway(c, body.ID)
+	if !ok || !s.ifMatch(c, s.gatewayToJSON(g)) {
+		return
+	}
+
+	g.Name = body.Name
+	g.Description = body.Description
+
+	c.json(http.StatusOK, map[string]string{})
+}
+
+func (s *Server) deleteGateway(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.ownedGateway(c, c.params["id"])
+	if !ok {
+		return
+	}
+
+	delete(s.gateways, g.ID)
+
+	c.w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) gatewayProfile(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.ownedGateway(c, c.params["id"])
+	if !ok {
+		return
+	}
+
+	s.setETag(c, s.gatewayToJSON(g))
+	c.json(http.StatusOK, s.gatewayToJSON(g))
+}
+
+func (s *Server) listGateways(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []gatewayJSON{}
+	for _, g := range s.userGateways(c.user) {
+		out = append(out, s.gatewayToJSON(g))
+	}
+
+	c.json(http.StatusOK, out)
+}
+
+func (s *Server) gatewayStatus(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := map[string]bool{}
+	for _, g := range s.userGateways(c.user) {
+		out[g.ID] = g.Online
+	}
+
+	c.json(http.StatusOK, out)
+}
+
+func (s *Server) activeDeviceCount(c *call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.ownedGateway(c, c.params["id"])
+	if !ok {
+		return
+	}
+
+	c.json(http.StatusOK, map[string]int{"count": g.ActiveCount})
+}
+
+func (s *Server) userGateways(user string) []*gateway {
+	var out []*gateway
+	for _, g := range s.gateways {
+		if g.Owner == user {
+			out = append(out, g)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name == out[j].Name {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].Name < out[j].Name
+	})
+
+	return out
+}
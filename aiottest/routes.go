@@ -0,0 +1,94 @@
+package aiottest
+
+import (
+	"net/http"
+	"strings"
+)
+
+const apiPrefix = "/api-gw/v1"
+
+type route struct {
+	method  string
+	pattern string
+	auth    bool
+	handler func(c *call)
+}
+
+func (s *Server) makeRoutes() []route {
+	// Các route có đoạn path cố định (list, getall, connect, ...) phải đứng
+	// trước các route dạng {id} cùng tiền tố.
+	return []route{
+		{http.MethodPost, "/api-gw/v1/user/login", false, s.login},
+		{http.MethodGet, "/api-gw/v1/user/verify", true, s.verify},
+		{http.MethodPost, "/api-gw/v1/user/reset-password", true, s.resetPassword},
+		{http.MethodGet, "/api-gw/v1/user/profile", true, s.userProfile},
+
+		{http.MethodGet, "/api-gw/v1/thing/list", true, s.listThings},
+		{http.MethodGet, "/api-gw/v1/thing/getall", true, s.listAllChannels},
+		{http.MethodPost, "/api-gw/v1/thing/connect", true, s.connect},
+		{http.MethodPost, "/api-gw/v1/thing", true, s.createThing},
+		{http.MethodPut, "/api-gw/v1/thing", true, s.updateThing},
+		{http.MethodGet, "/api-gw/v1/thing/{id}/channels", true, s.listChannelsByThing},
+		{http.MethodDelete, "/api-gw/v1/thing/{thingId}/channel/{channelId}", true, s.disconnect},
+		{http.MethodGet, "/api-gw/v1/thing/{id}", true, s.thingProfile},
+		{http.MethodDelete, "/api-gw/v1/thing/{id}", true, s.deleteThing},
+
+		{http.MethodGet, "/api-gw/v1/channel/list", true, s.listChannels},
+		{http.MethodPost, "/api-gw/v1/channel", true, s.createChannel},
+		{http.MethodPut, "/api-gw/v1/channel", true, s.updateChannel},
+		{http.MethodGet, "/api-gw/v1/channel/{id}", true, s.channelProfile},
+		{http.MethodDelete, "/api-gw/v1/channel/{id}", true, s.deleteChannel},
+
+		{http.MethodPost, "/api-gw/v1/gateway/create", true, s.createGateway},
+		{http.MethodPut, "/api-gw/v1/gateway/edit", true, s.updateGateway},
+		{http.MethodGet, "/api-gw/v1/gateway/list", true, s.listGateways},
+		{http.MethodGet, "/api-gw/v1/gateway/status", true, s.gatewayStatus},
+		{http.MethodGet, "/api-gw/v1/gateway/active-device-count/{id}", true, s.activeDeviceCount},
+		{http.MethodGet, "/api-gw/v1/gateway/{id}", true, s.gatewayProfile},
+		{http.MethodDelete, "/api-gw/v1/gateway/{id}", true, s.deleteGateway},
+	}
+}
+
+func (s *Server) match(method, path string) (route, map[string]string, bool) {
+	if !strings.HasPrefix(path, apiPrefix+"/") {
+		return route{}, nil, false
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	for _, rt := range s.routes {
+		if rt.method != method {
+			continue
+		}
+
+		if params, ok := matchPattern(rt.pattern, parts); ok {
+			return rt, params, true
+		}
+	}
+
+	return route{}, nil, false
+}
+
+func matchPattern(pattern string, parts []string) (map[string]string, bool) {
+	segs := strings.Split(strings.Trim(pattern, "/"), "/")
+	if len(segs) != len(parts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if parts[i] == "" {
+				return nil, false
+			}
+			params[seg[1:len(seg)-1]] = parts[i]
+			continue
+		}
+
+		if seg != parts[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
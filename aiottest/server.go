@@ -0,0 +1,357 @@
+// Package aiottest cung cấp một AIOT gateway giả lập chạy trong bộ nhớ, dùng
+// để kiểm thử aiot.Client mà không cần kết nối đến nền tảng thật.
+package aiottest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	CodeBadRequest   = "BAD_REQUEST"
+	CodeUnauthorized = "UNAUTHORIZED"
+	CodeForbidden    = "FORBIDDEN"
+	CodeNotFound     = "NOT_FOUND"
+	CodeConflict     = "CONFLICT"
+	CodeTokenExpired = "TOKEN_EXPIRED"
+
+	CodePreconditionFailed = "PRECONDITION_FAILED"
+
+	CodeTooManyRequests = "TOO_MANY_REQUESTS"
+	CodeUnavailable     = "SERVICE_UNAVAILABLE"
+	CodeInternal        = "INTERNAL_ERROR"
+
+	maxLimit     = 100
+	defaultLimit = 10
+)
+
+// User là tài khoản được đăng ký sẵn trên gateway giả lập
+type User struct {
+	Email        string
+	Password     string
+	Fullname     string
+	Phonenumber  string
+	Description  string
+	CustomerId   int64
+	UserTypeId   int64
+	UserStatusId int64
+	UserGroupId  int64
+	CreatedBy    string
+}
+
+type entity struct {
+	ID       string
+	Key      string
+	Name     string
+	Metadata map[string]interface{}
+	Owner    string
+}
+
+type gateway struct {
+	ID          string
+	Name        string
+	Description string
+	Owner       string
+	ThingID     string
+	Online      bool
+	ActiveCount int
+}
+
+// Server là AIOT gateway giả lập, phục vụ các route /api-gw/v1/... mà
+// aiot.Client sử dụng với trạng thái lưu trong bộ nhớ
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	routes     []route
+	faults     map[string][]*faultState
+	tokenLimit int
+	etags      bool
+
+	users    map[string]*User
+	tokens   map[string]*session
+	things   map[string]*entity
+	channels map[string]*entity
+	conns    map[string]map[string]bool
+	gateways map[string]*gateway
+}
+
+// Tạo mới và khởi động một gateway giả lập. Gọi Close để dừng server.
+func NewServer() *Server {
+	s := &Server{}
+	s.Reset()
+	s.routes = s.makeRoutes()
+	s.Server = httptest.NewServer(s)
+
+	return s
+}
+
+// Thêm một user có thể đăng nhập vào gateway
+func (s *Server) AddUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[u.Email] = &u
+}
+
+// Xóa toàn bộ dữ liệu, bao gồm user và token
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = make(map[string]*User)
+	s.tokens = make(map[string]*session)
+	s.things = make(map[string]*entity)
+	s.channels = make(map[string]*entity)
+	s.conns = make(map[string]map[string]bool)
+	s.gateways = make(map[string]*gateway)
+}
+
+// Đặt trạng thái online của một gateway, trả về false nếu gateway không tồn tại
+func (s *Server) SetGatewayOnline(gatewayID string, online bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.gateways[gatewayID]
+	if ok {
+		g.Online = online
+	}
+
+	return ok
+}
+
+// Đặt số thiết bị đang online của một gateway, trả về false nếu gateway không tồn tại
+func (s *Server) SetActiveDeviceCount(gatewayID string, count int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.gateways[gatewayID]
+	if ok {
+		g.ActiveCount = count
+	}
+
+	return ok
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt, params, ok := s.match(r.Method, r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusNotFound, CodeNotFound, "route not found")
+		return
+	}
+
+	serve := func(w http.ResponseWriter) {
+		c := &call{w: w, r: r, params: params}
+
+		if rt.auth {
+			user, code, message := s.authenticate(r)
+			if code != "" {
+				writeError(w, http.StatusUnauthorized, code, message)
+				return
+			}
+			c.user = user
+		}
+
+		rt.handler(c)
+	}
+
+	if f, ok := s.nextFault(rt.name()); ok && s.serveFault(w, r, f, serve) {
+		return
+	}
+
+	serve(w)
+}
+
+type session struct {
+	email string
+	limit int
+	uses  int
+}
+
+// authenticate trả về user sở hữu token, hoặc mã lỗi và thông báo lỗi nếu
+// token không hợp lệ hay đã hết hạn.
+func (s *Server) authenticate(r *http.Request) (string, string, string) {
+	fields := strings.Fields(r.Header.Get("Authorization"))
+	if len(fields) != 2 || fields[0] != "Bearer" {
+		return "", CodeUnauthorized, "missing or invalid credentials provided"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.tokens[fields[1]]
+	if !ok {
+		return "", CodeUnauthorized, "missing or invalid credentials provided"
+	}
+
+	sess.uses++
+	if sess.limit > 0 && sess.uses > sess.limit {
+		delete(s.tokens, fields[1])
+		return "", CodeTokenExpired, "token expired"
+	}
+
+	return sess.email, "", ""
+}
+
+type call struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	user   string
+	params map[string]string
+}
+
+// decode đọc JSON body của request vào v. Body rỗng được chấp nhận vì các
+// route danh sách dùng GET và có thể không gửi body.
+func (c *call) decode(v interface{}) bool {
+	if c.r.ContentLength == 0 {
+		return true
+	}
+
+	if err := json.NewDecoder(c.r.Body).Decode(v); err != nil {
+		writeError(c.w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("malformed request body: %s", err))
+		return false
+	}
+
+	return true
+}
+
+func (c *call) json(status int, v interface{}) {
+	c.w.Header().Set("Content-Type", "application/json")
+	c.w.WriteHeader(status)
+	json.NewEncoder(c.w).Encode(v)
+}
+
+func (c *call) error(status int, code, message string) {
+	writeError(c.w, status, code, message)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"errorCode":    code,
+		"errorMessage": message,
+	})
+}
+
+type page struct {
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	Order  string `json:"order"`
+	Dir    string `json:"dir"`
+}
+
+// validate kiểm tra tham số phân trang và điền giá trị mặc định.
+func (p *page) validate(c *call) bool {
+	if p.Limit == 0 {
+		p.Limit = defaultLimit
+	}
+	if p.Order == "" {
+		p.Order = "name"
+	}
+	if p.Dir == "" {
+		p.Dir = "desc"
+	}
+
+	switch {
+	case p.Offset < 0:
+		c.error(http.StatusBadRequest, CodeBadRequest, "offset must not be negative")
+	case p.Limit < 0 || p.Limit > maxLimit:
+		c.error(http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+	case p.Order != "name" && p.Order != "key" && p.Order != "id":
+		c.error(http.StatusBadRequest, CodeBadRequest, "invalid order: "+p.Order)
+	case p.Dir != "asc" && p.Dir != "desc":
+		c.error(http.StatusBadRequest, CodeBadRequest, "invalid dir: "+p.Dir)
+	default:
+		return true
+	}
+
+	return false
+}
+
+type entityJSON struct {
+	ID       string                 `json:"id"`
+	Key      string                 `json:"key"`
+	Name     string                 `json:"name"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type listJSON struct {
+	Total int          `json:"total"`
+	Data  []entityJSON `json:"data"`
+}
+
+// paginate sắp xếp và cắt danh sách theo tham số phân trang.
+func paginate(items []*entity, p page) listJSON {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := sortKey(items[i], p.Order), sortKey(items[j], p.Order)
+		if a == b {
+			a, b = items[i].ID, items[j].ID
+		}
+		if p.Dir == "asc" {
+			return a < b
+		}
+		return a > b
+	})
+
+	out := listJSON{Total: len(items), Data: []entityJSON{}}
+	for i := p.Offset; i < len(items) && i < p.Offset+p.Limit; i++ {
+		out.Data = append(out.Data, toJSON(items[i]))
+	}
+
+	return out
+}
+
+func sortKey(e *entity, order string) string {
+	switch order {
+	case "key":
+		return e.Key
+	case "id":
+		return e.ID
+	default:
+		return e.Name
+	}
+}
+
+func toJSON(e *entity) entityJSON {
+	return entityJSON{
+		ID:       e.ID,
+		Key:      e.Key,
+		Name:     e.Name,
+		Metadata: copyMetadata(e.Metadata),
+	}
+}
+
+func copyMetadata(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+
+	return out
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+func newToken() string {
+	var b [32]byte
+	rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
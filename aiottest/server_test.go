@@ -0,0 +1,134 @@
+package aiottest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/stretchr/testify/require"
+)
+
+func newServer(t *testing.T) (*aiottest.Server, aiot.Client, string) {
+	srv := aiottest.NewServer()
+	t.Cleanup(srv.Close)
+
+	srv.AddUser(aiottest.User{Email: "a@aiot.vn", Password: "pw"})
+	srv.AddUser(aiottest.User{Email: "b@aiot.vn", Password: "pw"})
+
+	client := aiot.NewClient(srv.URL)
+	token, err := client.Token("a@aiot.vn", "pw")
+	require.NoError(t, err)
+
+	return srv, client, token
+}
+
+func Test_Pagination(t *testing.T) {
+	require := require.New(t)
+	_, client, token := newServer(t)
+
+	for i := 1; i <= 5; i++ {
+		err := client.CreateThing(token, aiot.CreateThingInput{Name: fmt.Sprintf("thing-%d", i)})
+		require.NoError(err)
+	}
+
+	opts := aiot.NewListThingsByUserOptions().
+		SetOffset(1).
+		SetLimit(2).
+		SetDirection(aiot.DIRECTION_ASC)
+
+	things, total, err := client.ListThingsByUser(token, opts)
+	require.NoError(err)
+	require.Equal(5, total)
+	require.Len(things, 2)
+	require.Equal("thing-2", things[0].Name)
+	require.Equal("thing-3", things[1].Name)
+
+	things, total, err = client.ListThingsByUser(token, opts.SetOffset(4).SetDirection(aiot.DIRECTION_DESC))
+	require.NoError(err)
+	require.Equal(5, total)
+	require.Len(things, 1)
+	require.Equal("thing-1", things[0].Name)
+
+	_, _, err = client.ListThingsByUser(token, opts.SetLimit(1000))
+	require.Error(err)
+}
+
+func Test_Ownership(t *testing.T) {
+	require := require.New(t)
+	_, client, token := newServer(t)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "thing-a"}))
+	things, _, err := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Len(things, 1)
+
+	other, err := client.Token("b@aiot.vn", "pw")
+	require.NoError(err)
+
+	things2, total, err := client.ListThingsByUser(other, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Equal(0, total)
+	require.Empty(things2)
+
+	_, err = client.ThingProfile(other, things[0].ID)
+	require.Error(err)
+
+	require.Error(client.DeleteThing(other, things[0].ID))
+}
+
+func Test_ErrorBody(t *testing.T) {
+	require := require.New(t)
+	srv, _, _ := newServer(t)
+
+	body, _ := json.Marshal(map[string]string{"email": "a@aiot.vn", "password": "wrong"})
+	resp, err := http.Post(srv.URL+"/api-gw/v1/user/login", "application/json", bytes.NewReader(body))
+	require.NoError(err)
+	defer resp.Body.Close()
+
+	require.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	var e struct {
+		ErrorCode    string `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	}
+	require.NoError(json.NewDecoder(resp.Body).Decode(&e))
+	require.Equal(aiottest.CodeUnauthorized, e.ErrorCode)
+	require.NotEmpty(e.ErrorMessage)
+}
+
+func Test_GatewayState(t *testing.T) {
+	require := require.New(t)
+	srv, client, token := newServer(t)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "thing-a"}))
+	things, _, err := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+
+	require.NoError(client.CreateGateway(token, aiot.CreateGatewayInput{Name: "gw", ThingID: things[0].ID}))
+	require.Error(client.CreateGateway(token, aiot.CreateGatewayInput{Name: "gw-2", ThingID: things[0].ID}))
+
+	gateways, err := client.ListGateway(token)
+	require.NoError(err)
+	require.Len(gateways, 1)
+
+	require.True(srv.SetGatewayOnline(gateways[0].ID, true))
+	require.True(srv.SetActiveDeviceCount(gateways[0].ID, 7))
+
+	status, err := client.GatewayStatus(token)
+	require.NoError(err)
+	require.Equal(map[string]bool{gateways[0].ID: true}, status)
+
+	count, err := client.GatewayActiveDeviceCount(token, gateways[0].ID)
+	require.NoError(err)
+	require.Equal(7, count)
+
+	require.NoError(client.DeleteThing(token, things[0].ID))
+
+	gateways, err = client.ListGateway(token)
+	require.NoError(err)
+	require.Empty(gateways)
+}
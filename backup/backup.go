@@ -0,0 +1,151 @@
+// Package backup xuất toàn bộ dữ liệu của một tài khoản AIOT (thing, channel,
+// kết nối và gateway) ra một archive JSON có phiên bản, và nhập archive đó vào
+// một tài khoản hoặc môi trường khác.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+)
+
+// Phiên bản định dạng archive hiện tại
+const Version = 1
+
+// Archive là dữ liệu của một tài khoản tại thời điểm xuất
+type Archive struct {
+	Version     int          `json:"version"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	Things      []Thing      `json:"things"`
+	Channels    []Channel    `json:"channels"`
+	Connections []Connection `json:"connections"`
+	Gateways    []Gateway    `json:"gateways"`
+}
+
+type Thing struct {
+	ID       string        `json:"id"`
+	Key      string        `json:"key"`
+	Name     string        `json:"name"`
+	Metadata aiot.Metadata `json:"metadata,omitempty"`
+}
+
+type Channel struct {
+	ID       string        `json:"id"`
+	Key      string        `json:"key"`
+	Name     string        `json:"name"`
+	Metadata aiot.Metadata `json:"metadata,omitempty"`
+}
+
+// Connection là kết nối giữa thing và channel, theo id trong archive
+type Connection struct {
+	ThingID   string `json:"thingId"`
+	ChannelID string `json:"channelId"`
+}
+
+// Gateway tham chiếu tới thing của gateway theo id trong archive
+type Gateway struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ThingID     string `json:"thingId"`
+}
+
+// Export đọc toàn bộ dữ liệu của tài khoản sở hữu token
+func Export(api aiot.API, token string) (Archive, error) {
+	const op = "backup.Export"
+
+	s, err := inventory.Take(api, token)
+	if err != nil {
+		return Archive{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	a := Archive{
+		Version:     Version,
+		CreatedAt:   time.Now().UTC(),
+		Things:      []Thing{},
+		Channels:    []Channel{},
+		Connections: []Connection{},
+		Gateways:    []Gateway{},
+	}
+
+	for _, t := range s.Things {
+		a.Things = append(a.Things, Thing{ID: t.ID, Key: t.Key, Name: t.Name, Metadata: t.Metadata})
+	}
+	for _, c := range s.Channels {
+		a.Channels = append(a.Channels, Channel{ID: c.ID, Key: c.Key, Name: c.Name, Metadata: c.Metadata})
+	}
+	for _, c := range s.Connections {
+		a.Connections = append(a.Connections, Connection{ThingID: c.ThingID, ChannelID: c.ChannelID})
+	}
+	for _, g := range s.Gateways {
+		a.Gateways = append(a.Gateways, Gateway{ID: g.ID, Name: g.Name, Description: g.Description, ThingID: g.UnderlayThing.ID})
+	}
+
+	return a, nil
+}
+
+// Ghi archive dưới dạng JSON
+func (a Archive) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(a)
+}
+
+// Read đọc archive và kiểm tra phiên bản cùng các tham chiếu bên trong
+func Read(r io.Reader) (Archive, error) {
+	const op = "backup.Read"
+
+	var a Archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return Archive{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	if err := a.Validate(); err != nil {
+		return Archive{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return a, nil
+}
+
+// Validate kiểm tra phiên bản, id không trùng lặp và các kết nối, gateway
+// chỉ tham chiếu tới thing và channel có trong archive
+func (a Archive) Validate() error {
+	if a.Version != Version {
+		return fmt.Errorf("unsupported archive version %d", a.Version)
+	}
+
+	things := map[string]bool{}
+	for _, t := range a.Things {
+		if t.ID == "" || things[t.ID] {
+			return fmt.Errorf("missing or duplicate thing id %q", t.ID)
+		}
+		things[t.ID] = true
+	}
+
+	channels := map[string]bool{}
+	for _, c := range a.Channels {
+		if c.ID == "" || channels[c.ID] {
+			return fmt.Errorf("missing or duplicate channel id %q", c.ID)
+		}
+		channels[c.ID] = true
+	}
+
+	for _, c := range a.Connections {
+		if !things[c.ThingID] || !channels[c.ChannelID] {
+			return fmt.Errorf("connection %s -> %s references unknown thing or channel", c.ThingID, c.ChannelID)
+		}
+	}
+
+	for _, g := range a.Gateways {
+		if !things[g.ThingID] {
+			return fmt.Errorf("gateway %q references unknown thing %q", g.Name, g.ThingID)
+		}
+	}
+
+	return nil
+}
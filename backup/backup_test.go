@@ -0,0 +1,193 @@
+package backup_test
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/mobifone-aiot/aiot-go/backup"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+	"github.com/stretchr/testify/require"
+)
+
+// populate tạo 2 thing, 2 channel, 3 kết nối và 1 gateway
+func populate(t *testing.T, client aiot.Client, token string) inventory.Snapshot {
+	require := require.New(t)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor", Metadata: aiot.Metadata{"floor": "1"}}))
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "gw-thing"}))
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "telemetry"}))
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "alerts", Metadata: aiot.Metadata{"level": "high"}}))
+
+	s, err := inventory.Take(client, token)
+	require.NoError(err)
+
+	gwThing, sensor := s.Things[0], s.Things[1]
+	require.NoError(client.Connect(token, []string{s.Channels[0].ID, s.Channels[1].ID}, []string{sensor.ID}))
+	require.NoError(client.Connect(token, []string{s.Channels[1].ID}, []string{gwThing.ID}))
+	require.NoError(client.CreateGateway(token, aiot.CreateGatewayInput{Name: "gw-1", Description: "floor 1", ThingID: gwThing.ID}))
+
+	s, err = inventory.Take(client, token)
+	require.NoError(err)
+
+	return s
+}
+
+// shape mô tả dữ liệu theo tên để so sánh hai tài khoản. Kết nối được sắp
+// xếp theo id nên thứ tự thay đổi giữa hai tài khoản, kết quả được sắp xếp lại.
+func shape(s inventory.Snapshot) []string {
+	var out []string
+	for _, t := range s.Things {
+		floor, _ := t.Metadata.String("floor")
+		out = append(out, "thing "+t.Name+" "+floor)
+	}
+	for _, c := range s.Channels {
+		level, _ := c.Metadata.String("level")
+		out = append(out, "channel "+c.Name+" "+level)
+	}
+	for _, c := range s.Connections {
+		t, _ := s.Thing(c.ThingID)
+		ch, _ := s.Channel(c.ChannelID)
+		out = append(out, "connection "+t.Name+" "+ch.Name)
+	}
+	for _, g := range s.Gateways {
+		out = append(out, "gateway "+g.Name+" "+g.Description+" "+g.UnderlayThing.Name)
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+func Test_ExportImport(t *testing.T) {
+	require := require.New(t)
+
+	src, srcToken := aiottest.NewAccount(t)
+	source := populate(t, src, srcToken)
+
+	a, err := backup.Export(src, srcToken)
+	require.NoError(err)
+	require.Equal(backup.Version, a.Version)
+	require.Len(a.Things, 2)
+	require.Len(a.Connections, 3)
+	require.Equal(source.Things[1].Key, a.Things[1].Key)
+
+	var buf bytes.Buffer
+	require.NoError(a.Write(&buf))
+	a, err = backup.Read(&buf)
+	require.NoError(err)
+
+	dst, dstToken := aiottest.NewAccount(t)
+	report, err := backup.Import(dst, dstToken, a, nil)
+	require.NoError(err)
+	require.Len(report.Things, 2)
+	require.Len(report.Channels, 2)
+	require.Len(report.Gateways, 1)
+	require.Equal(3, report.Connections)
+
+	target, err := inventory.Take(dst, dstToken)
+	require.NoError(err)
+	require.Equal(shape(source), shape(target))
+
+	for _, t := range source.Things {
+		id, ok := report.ThingID(t.ID)
+		require.True(ok)
+		got, ok := target.Thing(id)
+		require.True(ok)
+		require.Equal(t.Name, got.Name)
+		// key được đặt lại để thiết bị vẫn kết nối được
+		require.Equal(t.Key, got.Key)
+	}
+	for _, m := range report.Things {
+		require.Equal(m.OldKey, m.NewKey)
+		require.Empty(m.KeyError)
+	}
+
+	id, ok := report.GatewayID(source.Gateways[0].ID)
+	require.True(ok)
+	require.Equal(target.Gateways[0].ID, id)
+
+	// nhập lại: mặc định báo lỗi trước khi thay đổi gì
+	_, err = backup.Import(dst, dstToken, a, nil)
+	require.EqualError(err, `backup.Import -> channel "alerts" already exists`)
+
+	// dùng lại đối tượng đã có, không tạo thêm
+	report, err = backup.Import(dst, dstToken, a, backup.NewOptions().SetConflictPolicy(backup.CONFLICT_REUSE))
+	require.NoError(err)
+	require.True(report.Things[0].Reused)
+	require.Equal(0, report.Connections)
+
+	again, err := inventory.Take(dst, dstToken)
+	require.NoError(err)
+	require.Equal(shape(target), shape(again))
+
+	// tạo mới dù trùng tên, key cũ đang được dùng nên thing mới giữ key mới
+	report, err = backup.Import(dst, dstToken, a, backup.NewOptions().SetConflictPolicy(backup.CONFLICT_CREATE))
+	require.NoError(err)
+	require.Equal(3, report.Connections)
+	for _, m := range report.Things {
+		require.NotEqual(m.OldKey, m.NewKey)
+		require.Contains(m.KeyError, "key is already in use")
+	}
+
+	again, err = inventory.Take(dst, dstToken)
+	require.NoError(err)
+	require.Len(again.Things, 4)
+	require.Len(again.Gateways, 2)
+}
+
+func Test_Read(t *testing.T) {
+	require := require.New(t)
+
+	_, err := backup.Read(strings.NewReader(`{"version": 2}`))
+	require.EqualError(err, "backup.Read -> unsupported archive version 2")
+
+	_, err = backup.Read(strings.NewReader(`{"version": 1, "things": [{"id": "t"}], "connections": [{"thingId": "t", "channelId": "c"}]}`))
+	require.EqualError(err, "backup.Read -> connection t -> c references unknown thing or channel")
+
+	_, err = backup.Read(strings.NewReader(`{"version": 1, "gateways": [{"name": "gw", "thingId": "t"}]}`))
+	require.EqualError(err, `backup.Read -> gateway "gw" references unknown thing "t"`)
+}
+
+func Test_ImportAmbiguous(t *testing.T) {
+	require := require.New(t)
+
+	src, srcToken := aiottest.NewAccount(t)
+	require.NoError(src.CreateThing(srcToken, aiot.CreateThingInput{Name: "sensor"}))
+	require.NoError(src.CreateThing(srcToken, aiot.CreateThingInput{Name: "sensor"}))
+
+	a, err := backup.Export(src, srcToken)
+	require.NoError(err)
+
+	// hai thing cùng tên không ánh xạ được id cũ sang id mới
+	dst, dstToken := aiottest.NewAccount(t)
+	_, err = backup.Import(dst, dstToken, a, backup.NewOptions().SetConflictPolicy(backup.CONFLICT_CREATE))
+	require.EqualError(err, `backup.Import -> thing "sensor" is ambiguous, several things in the archive have this name`)
+
+	s, err := inventory.Take(dst, dstToken)
+	require.NoError(err)
+	require.Empty(s.Things)
+}
+
+func Test_ImportNewKeys(t *testing.T) {
+	require := require.New(t)
+
+	src, srcToken := aiottest.NewAccount(t)
+	require.NoError(src.CreateThing(srcToken, aiot.CreateThingInput{Name: "sensor"}))
+
+	a, err := backup.Export(src, srcToken)
+	require.NoError(err)
+
+	dst, dstToken := aiottest.NewAccount(t)
+	report, err := backup.Import(dst, dstToken, a, backup.NewOptions().SetRestoreKeys(false))
+	require.NoError(err)
+	require.Len(report.Things, 1)
+	require.NotEqual(report.Things[0].OldKey, report.Things[0].NewKey)
+	require.Empty(report.Things[0].KeyError)
+
+	thing, err := dst.ThingProfile(dstToken, report.Things[0].NewID)
+	require.NoError(err)
+	require.Equal(report.Things[0].NewKey, thing.Key)
+}
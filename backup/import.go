@@ -0,0 +1,503 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+)
+
+type ConflictPolicy string
+
+var (
+	// Không nhập gì nếu tài khoản đích đã có thing, channel hoặc gateway
+	// trùng tên
+	CONFLICT_FAIL ConflictPolicy = "fail"
+	// Dùng lại đối tượng trùng tên đã có thay vì tạo mới
+	CONFLICT_REUSE ConflictPolicy = "reuse"
+	// Luôn tạo mới, kể cả khi trùng tên
+	CONFLICT_CREATE ConflictPolicy = "create"
+)
+
+type Options struct {
+	conflict    ConflictPolicy
+	restoreKeys bool
+}
+
+func NewOptions() *Options {
+	return &Options{
+		conflict:    CONFLICT_FAIL,
+		restoreKeys: true,
+	}
+}
+
+// Cách xử lý khi tài khoản đích đã có đối tượng trùng tên, mặc định là
+// CONFLICT_FAIL
+func (opts *Options) SetConflictPolicy(p ConflictPolicy) *Options {
+	opts.conflict = p
+	return opts
+}
+
+// Đặt lại key của thing mới tạo như trong archive để thiết bị đã cấu hình
+// key cũ vẫn kết nối được, mặc định là true. Nếu gateway từ chối key cũ (ví
+// dụ key đang được dùng), thing giữ key mới và lỗi được ghi vào
+// Mapping.KeyError.
+func (opts *Options) SetRestoreKeys(restore bool) *Options {
+	opts.restoreKeys = restore
+	return opts
+}
+
+// Mapping là id (và key) của một đối tượng trong archive và trong tài khoản
+// đích. Key của channel luôn thay đổi khi được tạo mới; key của thing chỉ
+// thay đổi khi không đặt lại được key cũ (xem Options.SetRestoreKeys).
+type Mapping struct {
+	Name   string `json:"name"`
+	OldID  string `json:"oldId"`
+	NewID  string `json:"newId"`
+	OldKey string `json:"oldKey,omitempty"`
+	NewKey string `json:"newKey,omitempty"`
+	// Lý do gateway từ chối key cũ
+	KeyError string `json:"keyError,omitempty"`
+	Reused   bool   `json:"reused,omitempty"`
+}
+
+// Report là kết quả của Import
+type Report struct {
+	Things      []Mapping `json:"things"`
+	Channels    []Mapping `json:"channels"`
+	Gateways    []Mapping `json:"gateways"`
+	Connections int       `json:"connections"`
+}
+
+// Ghi report dưới dạng JSON
+func (r Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// ThingID trả về id mới của thing có id oldID trong archive
+func (r Report) ThingID(oldID string) (string, bool) {
+	return lookup(r.Things, oldID)
+}
+
+// ChannelID trả về id mới của channel có id oldID trong archive
+func (r Report) ChannelID(oldID string) (string, bool) {
+	return lookup(r.Channels, oldID)
+}
+
+// GatewayID trả về id mới của gateway có id oldID trong archive
+func (r Report) GatewayID(oldID string) (string, bool) {
+	return lookup(r.Gateways, oldID)
+}
+
+func lookup(mappings []Mapping, oldID string) (string, bool) {
+	for _, m := range mappings {
+		if m.OldID == oldID {
+			return m.NewID, true
+		}
+	}
+
+	return "", false
+}
+
+// Import tạo lại nội dung của archive trong tài khoản sở hữu token: tạo
+// channel, thing, kết nối và gateway, ánh xạ id cũ sang id mới. Khi có lỗi,
+// Report chứa những gì đã được nhập.
+func Import(api aiot.API, token string, a Archive, opts *Options) (Report, error) {
+	const op = "backup.Import"
+
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	if err := a.Validate(); err != nil {
+		return Report{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	before, err := inventory.Take(api, token)
+	if err != nil {
+		return Report{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	im := importer{api: api, token: token, a: a, opts: opts, before: before}
+	if err := im.run(); err != nil {
+		return im.report, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return im.report, nil
+}
+
+type importer struct {
+	api    aiot.API
+	token  string
+	a      Archive
+	opts   *Options
+	before inventory.Snapshot
+	report Report
+}
+
+func (im *importer) run() error {
+	if err := im.checkNames(); err != nil {
+		return err
+	}
+
+	if im.opts.conflict == CONFLICT_FAIL {
+		if err := im.checkConflicts(); err != nil {
+			return err
+		}
+	}
+
+	if err := im.channels(); err != nil {
+		return err
+	}
+	if err := im.things(); err != nil {
+		return err
+	}
+	if err := im.connections(); err != nil {
+		return err
+	}
+
+	return im.gateways()
+}
+
+func (im *importer) checkConflicts() error {
+	for _, c := range im.a.Channels {
+		if len(channelsNamed(im.before.Channels, c.Name)) > 0 {
+			return fmt.Errorf("channel %q already exists", c.Name)
+		}
+	}
+	for _, t := range im.a.Things {
+		if len(thingsNamed(im.before.Things, t.Name)) > 0 {
+			return fmt.Errorf("thing %q already exists", t.Name)
+		}
+	}
+	for _, g := range im.a.Gateways {
+		if len(gatewaysNamed(im.before.Gateways, g.Name)) > 0 {
+			return fmt.Errorf("gateway %q already exists", g.Name)
+		}
+	}
+
+	return nil
+}
+
+// checkNames kiểm tra tên trong archive là duy nhất. Client không trả về id
+// của đối tượng vừa tạo nên đối tượng mới được tìm theo tên; hai đối tượng
+// cùng tên thì không biết id nào ứng với id nào trong archive.
+func (im *importer) checkNames() error {
+	type named struct{ kind, name string }
+
+	var names []named
+	for _, c := range im.a.Channels {
+		names = append(names, named{"channel", c.Name})
+	}
+	for _, t := range im.a.Things {
+		names = append(names, named{"thing", t.Name})
+	}
+	for _, g := range im.a.Gateways {
+		names = append(names, named{"gateway", g.Name})
+	}
+
+	seen := map[named]bool{}
+	for _, n := range names {
+		if seen[n] {
+			return fmt.Errorf("%s %q is ambiguous, several %ss in the archive have this name", n.kind, n.name, n.kind)
+		}
+		seen[n] = true
+	}
+
+	return nil
+}
+
+// reuse trả về đối tượng trùng tên được dùng lại, lỗi nếu có nhiều hơn một.
+func (im *importer) reuse(kind, name string, n int) (bool, error) {
+	if im.opts.conflict != CONFLICT_REUSE || n == 0 {
+		return false, nil
+	}
+	if n > 1 {
+		return false, fmt.Errorf("%s %q is ambiguous, several %ss have this name", kind, name, kind)
+	}
+
+	return true, nil
+}
+
+func (im *importer) channels() error {
+	var created []Channel
+
+	for _, c := range im.a.Channels {
+		existing := channelsNamed(im.before.Channels, c.Name)
+		ok, err := im.reuse("channel", c.Name, len(existing))
+		if err != nil {
+			return err
+		}
+		if ok {
+			im.report.Channels = append(im.report.Channels, Mapping{Name: c.Name, OldID: c.ID, NewID: existing[0].ID, OldKey: c.Key, NewKey: existing[0].Key, Reused: true})
+			continue
+		}
+
+		if err := im.api.CreateChannel(im.token, aiot.CreateChannelInput{Name: c.Name, Metadata: c.Metadata}); err != nil {
+			return fmt.Errorf("create channel %q: %w", c.Name, err)
+		}
+		created = append(created, c)
+	}
+
+	if len(created) == 0 {
+		return nil
+	}
+
+	// CreateChannel không trả về id, tìm channel mới theo tên
+	after, err := inventory.Channels(im.api, im.token)
+	if err != nil {
+		return err
+	}
+
+	known := map[string]bool{}
+	for _, c := range im.before.Channels {
+		known[c.ID] = true
+	}
+
+	fresh := map[string][]aiot.Channel{}
+	for _, c := range after {
+		if !known[c.ID] {
+			fresh[c.Name] = append(fresh[c.Name], c)
+		}
+	}
+
+	for _, c := range created {
+		if err := single("channel", c.Name, len(fresh[c.Name])); err != nil {
+			return err
+		}
+		n := fresh[c.Name][0]
+
+		im.report.Channels = append(im.report.Channels, Mapping{Name: c.Name, OldID: c.ID, NewID: n.ID, OldKey: c.Key, NewKey: n.Key})
+	}
+
+	return nil
+}
+
+func (im *importer) things() error {
+	var created []Thing
+
+	for _, t := range im.a.Things {
+		existing := thingsNamed(im.before.Things, t.Name)
+		ok, err := im.reuse("thing", t.Name, len(existing))
+		if err != nil {
+			return err
+		}
+		if ok {
+			im.report.Things = append(im.report.Things, Mapping{Name: t.Name, OldID: t.ID, NewID: existing[0].ID, OldKey: t.Key, NewKey: existing[0].Key, Reused: true})
+			continue
+		}
+
+		if err := im.api.CreateThing(im.token, aiot.CreateThingInput{Name: t.Name, Metadata: t.Metadata}); err != nil {
+			return fmt.Errorf("create thing %q: %w", t.Name, err)
+		}
+		created = append(created, t)
+	}
+
+	if len(created) == 0 {
+		return nil
+	}
+
+	// CreateThing không trả về id, tìm thing mới theo tên
+	after, err := inventory.Things(im.api, im.token)
+	if err != nil {
+		return err
+	}
+
+	known := map[string]bool{}
+	for _, t := range im.before.Things {
+		known[t.ID] = true
+	}
+
+	fresh := map[string][]aiot.Thing{}
+	for _, t := range after {
+		if !known[t.ID] {
+			fresh[t.Name] = append(fresh[t.Name], t)
+		}
+	}
+
+	for _, t := range created {
+		if err := single("thing", t.Name, len(fresh[t.Name])); err != nil {
+			return err
+		}
+		n := fresh[t.Name][0]
+
+		m := Mapping{Name: t.Name, OldID: t.ID, NewID: n.ID, OldKey: t.Key, NewKey: n.Key}
+		if err := im.restoreKey(&m, n); err != nil {
+			return err
+		}
+		im.report.Things = append(im.report.Things, m)
+	}
+
+	return nil
+}
+
+// restoreKey đặt key của thing mới n thành key trong archive. Khi gateway từ
+// chối key, m giữ key mới và lý do; lỗi khác (ví dụ lỗi kết nối) được trả về.
+func (im *importer) restoreKey(m *Mapping, n aiot.Thing) error {
+	if !im.opts.restoreKeys || m.OldKey == "" || m.OldKey == n.Key {
+		return nil
+	}
+
+	err := im.api.UpdateThing(im.token, aiot.UpdateThingInput{
+		ID:       n.ID,
+		Name:     n.Name,
+		Metadata: n.Metadata,
+		Key:      m.OldKey,
+		Version:  n.Version,
+	})
+
+	var urlErr *url.Error
+	switch {
+	case err == nil:
+		m.NewKey = m.OldKey
+	case errors.As(err, &urlErr) || !aiot.Is(aiot.KIND_INVALID, err) && !aiot.Is(aiot.KIND_OTHER, err):
+		return fmt.Errorf("restore key of thing %q: %w", m.Name, err)
+	default:
+		m.KeyError = err.Error()
+	}
+
+	return nil
+}
+
+// connections kết nối lại thing với channel, mỗi thing một lần gọi Connect.
+// Kết nối đã có sẵn (với thing và channel được dùng lại) được bỏ qua.
+func (im *importer) connections() error {
+	existing := map[inventory.Connection]bool{}
+	for _, c := range im.before.Connections {
+		existing[c] = true
+	}
+
+	var order []string
+	channels := map[string][]string{}
+
+	for _, c := range im.a.Connections {
+		thingID, _ := im.report.ThingID(c.ThingID)
+		channelID, _ := im.report.ChannelID(c.ChannelID)
+
+		if existing[inventory.Connection{ThingID: thingID, ChannelID: channelID}] {
+			continue
+		}
+
+		if _, ok := channels[thingID]; !ok {
+			order = append(order, thingID)
+		}
+		channels[thingID] = append(channels[thingID], channelID)
+	}
+
+	for _, thingID := range order {
+		if err := im.api.Connect(im.token, channels[thingID], []string{thingID}); err != nil {
+			return fmt.Errorf("connect thing %s: %w", thingID, err)
+		}
+		im.report.Connections += len(channels[thingID])
+	}
+
+	return nil
+}
+
+func (im *importer) gateways() error {
+	var created []Gateway
+
+	for _, g := range im.a.Gateways {
+		existing := gatewaysNamed(im.before.Gateways, g.Name)
+		ok, err := im.reuse("gateway", g.Name, len(existing))
+		if err != nil {
+			return err
+		}
+		if ok {
+			im.report.Gateways = append(im.report.Gateways, Mapping{Name: g.Name, OldID: g.ID, NewID: existing[0].ID, Reused: true})
+			continue
+		}
+
+		thingID, _ := im.report.ThingID(g.ThingID)
+		in := aiot.CreateGatewayInput{Name: g.Name, Description: g.Description, ThingID: thingID}
+		if err := im.api.CreateGateway(im.token, in); err != nil {
+			return fmt.Errorf("create gateway %q: %w", g.Name, err)
+		}
+		created = append(created, g)
+	}
+
+	if len(created) == 0 {
+		return nil
+	}
+
+	after, err := im.api.ListGateway(im.token)
+	if err != nil {
+		return err
+	}
+
+	known := map[string]bool{}
+	for _, g := range im.before.Gateways {
+		known[g.ID] = true
+	}
+
+	fresh := map[string][]aiot.Gateway{}
+	for _, g := range after {
+		if !known[g.ID] {
+			fresh[g.Name] = append(fresh[g.Name], g)
+		}
+	}
+
+	for _, g := range created {
+		if err := single("gateway", g.Name, len(fresh[g.Name])); err != nil {
+			return err
+		}
+		n := fresh[g.Name][0]
+
+		im.report.Gateways = append(im.report.Gateways, Mapping{Name: g.Name, OldID: g.ID, NewID: n.ID})
+	}
+
+	return nil
+}
+
+// single kiểm tra có đúng một đối tượng mới với tên name, nếu nhiều hơn (ví
+// dụ do người khác tạo cùng lúc) thì không xác định được id mới.
+func single(kind, name string, n int) error {
+	switch n {
+	case 0:
+		return fmt.Errorf("created %s %q not found", kind, name)
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("created %s %q is ambiguous, several new %ss have this name", kind, name, kind)
+	}
+}
+
+func thingsNamed(things []aiot.Thing, name string) []aiot.Thing {
+	var out []aiot.Thing
+	for _, t := range things {
+		if t.Name == name {
+			out = append(out, t)
+		}
+	}
+
+	return out
+}
+
+func channelsNamed(channels []aiot.Channel, name string) []aiot.Channel {
+	var out []aiot.Channel
+	for _, c := range channels {
+		if c.Name == name {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+func gatewaysNamed(gateways []aiot.Gateway, name string) []aiot.Gateway {
+	var out []aiot.Gateway
+	for _, g := range gateways {
+		if g.Name == name {
+			out = append(out, g)
+		}
+	}
+
+	return out
+}
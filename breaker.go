@@ -0,0 +1,241 @@
+package aiot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type CircuitState string
+
+var (
+	// Request được gửi bình thường
+	CIRCUIT_CLOSED CircuitState = "closed"
+	// Request bị từ chối ngay với KIND_CIRCUIT_OPEN
+	CIRCUIT_OPEN CircuitState = "open"
+	// Cho một số request thử để quyết định đóng hay mở lại
+	CIRCUIT_HALF_OPEN CircuitState = "half-open"
+)
+
+// Lỗi trả về khi circuit breaker đang mở
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker cấu hình circuit breaker của Client. Lỗi kết nối và response
+// 5xx được tính là thất bại, các response khác là thành công. Các trường bằng
+// 0 dùng giá trị mặc định.
+type CircuitBreaker struct {
+	// Tỉ lệ thất bại trong một cửa sổ để mở circuit, mặc định 0.5
+	FailureRatio float64
+	// Số request tối thiểu trong cửa sổ trước khi xét tỉ lệ, mặc định 10
+	MinRequests int
+	// Độ dài cửa sổ đếm khi circuit đóng, mặc định 10 giây
+	Window time.Duration
+	// Thời gian circuit mở trước khi chuyển sang half-open, mặc định 30 giây
+	OpenTimeout time.Duration
+	// Số request thử khi half-open, tất cả thành công thì circuit đóng lại,
+	// mặc định 1
+	HalfOpenRequests int
+	// Hàm được gọi mỗi khi trạng thái thay đổi
+	OnStateChange func(from, to CircuitState)
+}
+
+// Kết quả của một request đối với circuit breaker
+type result uint8
+
+const (
+	resultSuccess result = iota
+	resultFailure
+	// Request không được gửi đi, ví dụ vì vượt giới hạn tốc độ
+	resultSkipped
+)
+
+type breaker struct {
+	cfg CircuitBreaker
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitState
+	gen      uint64
+	expires  time.Time
+	requests int
+	failures int
+	inFlight int
+}
+
+func newBreaker(cfg *CircuitBreaker) *breaker {
+	if cfg == nil {
+		return nil
+	}
+
+	b := &breaker{cfg: *cfg, now: time.Now, state: CIRCUIT_CLOSED}
+	if b.cfg.FailureRatio <= 0 {
+		b.cfg.FailureRatio = 0.5
+	}
+	if b.cfg.MinRequests <= 0 {
+		b.cfg.MinRequests = 10
+	}
+	if b.cfg.Window <= 0 {
+		b.cfg.Window = 10 * time.Second
+	}
+	if b.cfg.OpenTimeout <= 0 {
+		b.cfg.OpenTimeout = 30 * time.Second
+	}
+	if b.cfg.HalfOpenRequests <= 0 {
+		b.cfg.HalfOpenRequests = 1
+	}
+	b.expires = b.now().Add(b.cfg.Window)
+
+	return b
+}
+
+// allow kiểm tra request có được gửi không và trả về hàm ghi nhận kết quả.
+// Kết quả của request bắt đầu trong trạng thái trước bị bỏ qua.
+func (b *breaker) allow() (func(result), error) {
+	if b == nil {
+		return func(result) {}, nil
+	}
+
+	b.mu.Lock()
+	notify := b.tick(b.now())
+	open := b.state == CIRCUIT_OPEN ||
+		b.state == CIRCUIT_HALF_OPEN && b.inFlight+b.requests >= b.cfg.HalfOpenRequests
+	if !open {
+		b.inFlight++
+	}
+	gen := b.gen
+	b.mu.Unlock()
+
+	notify()
+
+	if open {
+		return nil, ErrCircuitOpen
+	}
+
+	return func(r result) {
+		b.mu.Lock()
+		notify := b.done(gen, r)
+		b.mu.Unlock()
+
+		notify()
+	}, nil
+}
+
+// done ghi nhận kết quả, b.mu phải được giữ
+func (b *breaker) done(gen uint64, r result) func() {
+	now := b.now()
+	notify := b.tick(now)
+	if gen != b.gen {
+		return notify
+	}
+
+	b.inFlight--
+	if r == resultSkipped {
+		return notify
+	}
+
+	failed := r == resultFailure
+	b.requests++
+	if failed {
+		b.failures++
+	}
+
+	switch b.state {
+	case CIRCUIT_CLOSED:
+		if b.requests >= b.cfg.MinRequests && float64(b.failures) >= b.cfg.FailureRatio*float64(b.requests) {
+			return chain(notify, b.setState(CIRCUIT_OPEN, now))
+		}
+	case CIRCUIT_HALF_OPEN:
+		if failed {
+			return chain(notify, b.setState(CIRCUIT_OPEN, now))
+		}
+		if b.requests >= b.cfg.HalfOpenRequests {
+			return chain(notify, b.setState(CIRCUIT_CLOSED, now))
+		}
+	}
+
+	return notify
+}
+
+// tick chuyển trạng thái theo thời gian: bắt đầu cửa sổ mới khi đóng, sang
+// half-open khi hết thời gian mở. Half-open chỉ kết thúc theo kết quả của
+// các request thử. b.mu phải được giữ.
+func (b *breaker) tick(now time.Time) func() {
+	if b.state == CIRCUIT_HALF_OPEN || now.Before(b.expires) {
+		return func() {}
+	}
+
+	switch b.state {
+	case CIRCUIT_CLOSED:
+		b.reset(now)
+	case CIRCUIT_OPEN:
+		return b.setState(CIRCUIT_HALF_OPEN, now)
+	}
+
+	return func() {}
+}
+
+func (b *breaker) reset(now time.Time) {
+	b.gen++
+	b.requests, b.failures, b.inFlight = 0, 0, 0
+
+	switch b.state {
+	case CIRCUIT_CLOSED:
+		b.expires = now.Add(b.cfg.Window)
+	case CIRCUIT_OPEN:
+		b.expires = now.Add(b.cfg.OpenTimeout)
+	}
+}
+
+// setState đổi trạng thái và trả về hàm gọi OnStateChange, được gọi sau
+// khi b.mu đã được nhả. b.mu phải được giữ.
+func (b *breaker) setState(state CircuitState, now time.Time) func() {
+	from := b.state
+	b.state = state
+	b.reset(now)
+
+	if b.cfg.OnStateChange == nil {
+		return func() {}
+	}
+
+	return func() {
+		b.cfg.OnStateChange(from, state)
+	}
+}
+
+func (b *breaker) currentState() CircuitState {
+	if b == nil {
+		return CIRCUIT_CLOSED
+	}
+
+	b.mu.Lock()
+	notify := b.tick(b.now())
+	state := b.state
+	b.mu.Unlock()
+
+	notify()
+
+	return state
+}
+
+func chain(fns ...func()) func() {
+	return func() {
+		for _, fn := range fns {
+			fn()
+		}
+	}
+}
+
+// outcome phân loại kết quả của một lần gửi request. Request bị người gọi hủy
+// không phải lỗi của gateway nên không được tính.
+func outcome(resp *http.Response, err error) result {
+	if errors.Is(err, context.Canceled) {
+		return resultSkipped
+	}
+	if err != nil || resp.StatusCode >= 500 {
+		return resultFailure
+	}
+
+	return resultSuccess
+}
@@ -0,0 +1,105 @@
+package aiot_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CircuitBreaker(t *testing.T) {
+	require := require.New(t)
+
+	var mu sync.Mutex
+	var transitions []string
+	srv, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetCircuitBreaker(aiot.CircuitBreaker{
+			FailureRatio: 0.5,
+			MinRequests:  4,
+			Window:       time.Minute,
+			OpenTimeout:  50 * time.Millisecond,
+			OnStateChange: func(from, to aiot.CircuitState) {
+				mu.Lock()
+				transitions = append(transitions, string(from)+" -> "+string(to))
+				mu.Unlock()
+			},
+		}))
+
+	unavailable := aiottest.Fault{Status: http.StatusServiceUnavailable, Times: 3}
+	srv.InjectFault(aiottest.AnyRoute, unavailable)
+
+	for i := 0; i < 4; i++ {
+		_, err := client.UserProfile(token)
+		require.False(aiot.Is(aiot.KIND_CIRCUIT_OPEN, err))
+	}
+	require.Equal(aiot.CIRCUIT_OPEN, client.CircuitState())
+
+	_, err := client.UserProfile(token)
+	require.True(aiot.Is(aiot.KIND_CIRCUIT_OPEN, err), err)
+	require.True(errors.Is(err, aiot.ErrCircuitOpen))
+	require.False(aiot.Is(aiot.KIND_RATE_LIMITED, err))
+
+	// request thử thất bại thì mở lại
+	time.Sleep(60 * time.Millisecond)
+	require.Equal(aiot.CIRCUIT_HALF_OPEN, client.CircuitState())
+	srv.InjectFault(aiottest.AnyRoute, aiottest.Fault{Status: http.StatusBadGateway, Times: 1})
+	_, err = client.UserProfile(token)
+	require.Error(err)
+	require.False(aiot.Is(aiot.KIND_CIRCUIT_OPEN, err))
+	require.Equal(aiot.CIRCUIT_OPEN, client.CircuitState())
+
+	// request thử thành công thì đóng lại
+	time.Sleep(60 * time.Millisecond)
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+	require.Equal(aiot.CIRCUIT_CLOSED, client.CircuitState())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal([]string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	}, transitions)
+}
+
+func Test_CircuitBreakerIgnoresClientErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, client, _ := limitedClient(t, aiot.NewClientOptions().
+		SetCircuitBreaker(aiot.CircuitBreaker{MinRequests: 2}))
+
+	for i := 0; i < 5; i++ {
+		_, err := client.UserProfile("invalid-token")
+		require.Error(err)
+	}
+	require.Equal(aiot.CIRCUIT_CLOSED, client.CircuitState())
+	require.Equal(aiot.CIRCUIT_CLOSED, aiot.NewClient("").CircuitState())
+}
+
+func Test_CircuitBreakerIgnoresCancel(t *testing.T) {
+	require := require.New(t)
+
+	srv, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetCircuitBreaker(aiot.CircuitBreaker{MinRequests: 2, Window: time.Minute}))
+	srv.InjectFault(aiottest.AnyRoute, aiottest.Fault{Latency: time.Second})
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		_, err := client.WithContext(ctx).UserProfile(token)
+		require.True(errors.Is(err, context.Canceled), err)
+	}
+
+	// request bị người gọi hủy không phải lỗi của gateway
+	require.Equal(aiot.CIRCUIT_CLOSED, client.CircuitState())
+}
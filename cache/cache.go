@@ -0,0 +1,281 @@
+// Package cache bọc một aiot.API và lưu kết quả của ThingProfile,
+// ChannelProfile và GatewayProfile trong bộ nhớ.
+//
+// Mỗi loại đối tượng có TTL riêng, tổng số phần tử được giới hạn (bỏ phần tử
+// ít dùng nhất trước), các lời gọi giống nhau đồng thời chỉ gửi một request,
+// và các thao tác sửa, xóa, kết nối thực hiện qua cùng Client sẽ tự động xóa
+// các phần tử liên quan. Thay đổi từ nơi khác chỉ được thấy sau khi hết TTL.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+type Options struct {
+	thingTTL   time.Duration
+	channelTTL time.Duration
+	gatewayTTL time.Duration
+	maxEntries int
+	now        func() time.Time
+}
+
+func NewOptions() *Options {
+	return &Options{
+		thingTTL:   30 * time.Second,
+		channelTTL: 30 * time.Second,
+		gatewayTTL: 30 * time.Second,
+		maxEntries: 10000,
+		now:        time.Now,
+	}
+}
+
+// Thời gian lưu kết quả ThingProfile, 0 là không lưu
+func (opts *Options) SetThingTTL(ttl time.Duration) *Options {
+	opts.thingTTL = ttl
+	return opts
+}
+
+// Thời gian lưu kết quả ChannelProfile, 0 là không lưu
+func (opts *Options) SetChannelTTL(ttl time.Duration) *Options {
+	opts.channelTTL = ttl
+	return opts
+}
+
+// Thời gian lưu kết quả GatewayProfile, 0 là không lưu
+func (opts *Options) SetGatewayTTL(ttl time.Duration) *Options {
+	opts.gatewayTTL = ttl
+	return opts
+}
+
+// Số phần tử tối đa, 0 là không giới hạn
+func (opts *Options) SetMaxEntries(n int) *Options {
+	opts.maxEntries = n
+	return opts
+}
+
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+const (
+	kindThing   = "thing"
+	kindChannel = "channel"
+	kindGateway = "gateway"
+)
+
+// key phân biệt theo token vì mỗi người dùng chỉ được xem đối tượng của mình
+type key struct {
+	kind  string
+	id    string
+	token string
+}
+
+type entry struct {
+	key     key
+	value   interface{}
+	expires time.Time
+}
+
+type flight struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
+}
+
+// Client hiện thực aiot.API. Các phương thức không được lưu đi thẳng tới API
+// bên trong.
+type Client struct {
+	aiot.API
+
+	opts *Options
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[key]*list.Element
+	flights map[key]*flight
+	// gen tăng mỗi khi có phần tử bị xóa khỏi cache, để kết quả của các
+	// request bắt đầu trước đó không được lưu lại
+	gen   uint64
+	stats Stats
+}
+
+var _ aiot.API = (*Client)(nil)
+
+// New tạo Client lưu kết quả của api
+func New(api aiot.API, opts *Options) *Client {
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	return &Client{
+		API:     api,
+		opts:    opts,
+		lru:     list.New(),
+		entries: map[key]*list.Element{},
+		flights: map[key]*flight{},
+	}
+}
+
+// get trả về giá trị còn hạn trong cache, hoặc gọi fetch (một lần cho các lời
+// gọi đồng thời cùng key) và lưu kết quả nếu không có lỗi.
+func (c *Client) get(k key, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	if ttl <= 0 {
+		return fetch()
+	}
+
+	c.mu.Lock()
+
+	if el, ok := c.entries[k]; ok {
+		e := el.Value.(*entry)
+		if c.opts.now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return e.value, nil
+		}
+		c.remove(el)
+	}
+
+	c.stats.Misses++
+
+	if f, ok := c.flights[k]; ok {
+		c.mu.Unlock()
+		f.wg.Wait()
+		return f.value, f.err
+	}
+
+	f := &flight{}
+	f.wg.Add(1)
+	c.flights[k] = f
+	gen := c.gen
+	c.mu.Unlock()
+
+	f.value, f.err = fetch()
+
+	c.mu.Lock()
+	if c.flights[k] == f {
+		delete(c.flights, k)
+	}
+	if f.err == nil && c.gen == gen {
+		c.add(k, f.value, ttl)
+	}
+	c.mu.Unlock()
+
+	f.wg.Done()
+	return f.value, f.err
+}
+
+func (c *Client) add(k key, value interface{}, ttl time.Duration) {
+	if el, ok := c.entries[k]; ok {
+		c.remove(el)
+	}
+
+	c.entries[k] = c.lru.PushFront(&entry{key: k, value: value, expires: c.opts.now().Add(ttl)})
+
+	for c.opts.maxEntries > 0 && c.lru.Len() > c.opts.maxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Client) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.entries, e.key)
+}
+
+// invalidate xóa mọi phần tử của đối tượng, với mọi token.
+func (c *Client) invalidate(kind string, ids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*entry)
+		if e.key.kind == kind && contains(ids, e.key.id) {
+			c.remove(el)
+		}
+		el = next
+	}
+
+	for k := range c.flights {
+		if k.kind == kind && contains(ids, k.id) {
+			delete(c.flights, k)
+		}
+	}
+}
+
+// invalidateKind xóa mọi phần tử của một loại đối tượng.
+func (c *Client) invalidateKind(kind string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*entry).key.kind == kind {
+			c.remove(el)
+		}
+		el = next
+	}
+
+	for k := range c.flights {
+		if k.kind == kind {
+			delete(c.flights, k)
+		}
+	}
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Xóa thing khỏi cache
+func (c *Client) InvalidateThing(id string) {
+	c.invalidate(kindThing, id)
+}
+
+// Xóa channel khỏi cache
+func (c *Client) InvalidateChannel(id string) {
+	c.invalidate(kindChannel, id)
+}
+
+// Xóa gateway khỏi cache
+func (c *Client) InvalidateGateway(id string) {
+	c.invalidate(kindGateway, id)
+}
+
+// Xóa toàn bộ cache
+func (c *Client) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	c.lru.Init()
+	c.entries = map[key]*list.Element{}
+	c.flights = map[key]*flight{}
+}
+
+func (c *Client) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Entries = c.lru.Len()
+	return s
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiotmock"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newCache(opts *Options) (*Client, *aiotmock.Client, *clock) {
+	clk := &clock{now: time.Unix(0, 0)}
+	opts.now = clk.Now
+
+	mock := &aiotmock.Client{
+		ThingProfileFunc: func(token, id string) (aiot.Thing, error) {
+			return aiot.Thing{ID: id, Name: "thing-" + id, Metadata: aiot.Metadata{"floor": "1"}}, nil
+		},
+		ChannelProfileFunc: func(token, id string) (aiot.Channel, error) {
+			return aiot.Channel{ID: id}, nil
+		},
+		GatewayProfileFunc: func(token, id string) (aiot.Gateway, error) {
+			return aiot.Gateway{ID: id, UnderlayThing: aiot.Thing{ID: "t1"}}, nil
+		},
+	}
+
+	return New(mock, opts), mock, clk
+}
+
+func Test_TTL(t *testing.T) {
+	require := require.New(t)
+	c, mock, clk := newCache(NewOptions().SetThingTTL(time.Minute).SetChannelTTL(0))
+
+	for i := 0; i < 3; i++ {
+		thing, err := c.ThingProfile("tok", "t1")
+		require.NoError(err)
+		require.Equal("thing-t1", thing.Name)
+	}
+	require.Len(mock.CallsTo("ThingProfile"), 1)
+
+	// token khác là phần tử khác
+	_, err := c.ThingProfile("other", "t1")
+	require.NoError(err)
+	require.Len(mock.CallsTo("ThingProfile"), 2)
+
+	clk.Advance(time.Minute)
+	_, err = c.ThingProfile("tok", "t1")
+	require.NoError(err)
+	require.Len(mock.CallsTo("ThingProfile"), 3)
+
+	// TTL 0 là không lưu
+	c.ChannelProfile("tok", "c1")
+	c.ChannelProfile("tok", "c1")
+	require.Len(mock.CallsTo("ChannelProfile"), 2)
+
+	require.Equal(Stats{Hits: 2, Misses: 3, Entries: 2}, c.Stats())
+}
+
+func Test_CopiesMetadata(t *testing.T) {
+	require := require.New(t)
+	c, _, _ := newCache(NewOptions())
+
+	thing, err := c.ThingProfile("tok", "t1")
+	require.NoError(err)
+	thing.Metadata["floor"] = "changed"
+
+	thing, err = c.ThingProfile("tok", "t1")
+	require.NoError(err)
+	require.Equal("1", thing.Metadata["floor"])
+}
+
+func Test_Errors(t *testing.T) {
+	require := require.New(t)
+	c, mock, _ := newCache(NewOptions())
+
+	mock.ThingProfileFunc = func(token, id string) (aiot.Thing, error) {
+		return aiot.Thing{}, errors.New("boom")
+	}
+
+	_, err := c.ThingProfile("tok", "t1")
+	require.EqualError(err, "boom")
+	_, err = c.ThingProfile("tok", "t1")
+	require.EqualError(err, "boom")
+	require.Len(mock.CallsTo("ThingProfile"), 2)
+}
+
+func Test_MaxEntries(t *testing.T) {
+	require := require.New(t)
+	c, mock, _ := newCache(NewOptions().SetMaxEntries(2))
+
+	c.ThingProfile("tok", "t1")
+	c.ThingProfile("tok", "t2")
+	c.ThingProfile("tok", "t1") // t1 được dùng gần nhất
+	c.ThingProfile("tok", "t3") // bỏ t2
+
+	require.Equal(2, c.Stats().Entries)
+	require.Equal(uint64(1), c.Stats().Evictions)
+
+	mock.Reset()
+	c.ThingProfile("tok", "t1")
+	c.ThingProfile("tok", "t3")
+	require.Empty(mock.Calls())
+
+	c.ThingProfile("tok", "t2")
+	require.Len(mock.CallsTo("ThingProfile"), 1)
+}
+
+func Test_Singleflight(t *testing.T) {
+	require := require.New(t)
+	c, mock, _ := newCache(NewOptions())
+
+	var calls int32
+	release := make(chan struct{})
+	mock.ThingProfileFunc = func(token, id string) (aiot.Thing, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return aiot.Thing{ID: id}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			thing, err := c.ThingProfile("tok", "t1")
+			require.NoError(err)
+			require.Equal("t1", thing.ID)
+		}()
+	}
+
+	require.Eventually(func() bool {
+		return c.Stats().Misses == 10
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_Invalidation(t *testing.T) {
+	require := require.New(t)
+	c, mock, _ := newCache(NewOptions())
+
+	warm := func() {
+		c.ThingProfile("tok", "t1")
+		c.ThingProfile("tok", "t2")
+		c.ChannelProfile("tok", "c1")
+		c.GatewayProfile("tok", "g1")
+		mock.Reset()
+	}
+	fetched := func() []string {
+		c.ThingProfile("tok", "t1")
+		c.ThingProfile("tok", "t2")
+		c.ChannelProfile("tok", "c1")
+		c.GatewayProfile("tok", "g1")
+
+		var out []string
+		for _, call := range mock.Calls() {
+			out = append(out, call.Method+" "+call.Args[1].(string))
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name string
+		op   func()
+		want []string
+	}{
+		{"UpdateThing", func() { c.UpdateThing("tok", aiot.UpdateThingInput{ID: "t1"}) }, []string{"ThingProfile t1", "GatewayProfile g1"}},
+		{"DeleteThing", func() { c.DeleteThing("tok", "t2") }, []string{"ThingProfile t2", "GatewayProfile g1"}},
+		{"Connect", func() { c.Connect("tok", []string{"c1"}, []string{"t1", "t2"}) }, []string{"ThingProfile t1", "ThingProfile t2", "ChannelProfile c1"}},
+		{"Disconnect", func() { c.Disconnect("tok", "c1", "t1") }, []string{"ThingProfile t1", "ChannelProfile c1"}},
+		{"UpdateChannel", func() { c.UpdateChannel("tok", aiot.UpdateChannelInput{ID: "c1"}) }, []string{"ChannelProfile c1"}},
+		{"DeleteChannel", func() { c.DeleteChannel("tok", "c1") }, []string{"ChannelProfile c1"}},
+		{"UpdateGateway", func() { c.UpdateGateway("tok", aiot.UpdateGatewayInput{ID: "g1"}) }, []string{"GatewayProfile g1"}},
+		{"DeleteGateway", func() { c.DeleteGateway("tok", "g1") }, []string{"GatewayProfile g1"}},
+		{"InvalidateThing", func() { c.InvalidateThing("t2") }, []string{"ThingProfile t2"}},
+		{"Purge", c.Purge, []string{"ThingProfile t1", "ThingProfile t2", "ChannelProfile c1", "GatewayProfile g1"}},
+	} {
+		warm()
+		tc.op()
+		mock.Reset()
+		require.Equal(tc.want, fetched(), tc.name)
+	}
+}
+
+// Kết quả của request bắt đầu trước khi đối tượng bị sửa không được lưu lại
+func Test_StaleFetch(t *testing.T) {
+	require := require.New(t)
+	c, mock, _ := newCache(NewOptions())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mock.ThingProfileFunc = func(token, id string) (aiot.Thing, error) {
+		close(started)
+		<-release
+		return aiot.Thing{ID: id, Name: "old"}, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.ThingProfile("tok", "t1")
+	}()
+
+	<-started
+	require.NoError(c.UpdateThing("tok", aiot.UpdateThingInput{ID: "t1", Name: "new"}))
+	close(release)
+	<-done
+
+	mock.ThingProfileFunc = func(token, id string) (aiot.Thing, error) {
+		return aiot.Thing{ID: id, Name: "new"}, nil
+	}
+
+	thing, err := c.ThingProfile("tok", "t1")
+	require.NoError(err)
+	require.Equal("new", thing.Name)
+}
@@ -0,0 +1,121 @@
+package cache
+
+import "github.com/mobifone-aiot/aiot-go"
+
+func (c *Client) ThingProfile(token, thingID string) (aiot.Thing, error) {
+	v, err := c.get(key{kindThing, thingID, token}, c.opts.thingTTL, func() (interface{}, error) {
+		return c.API.ThingProfile(token, thingID)
+	})
+	if err != nil {
+		return aiot.Thing{}, err
+	}
+
+	return cloneThing(v.(aiot.Thing)), nil
+}
+
+func (c *Client) ChannelProfile(token, channelID string) (aiot.Channel, error) {
+	v, err := c.get(key{kindChannel, channelID, token}, c.opts.channelTTL, func() (interface{}, error) {
+		return c.API.ChannelProfile(token, channelID)
+	})
+	if err != nil {
+		return aiot.Channel{}, err
+	}
+
+	ch := v.(aiot.Channel)
+	ch.Metadata = ch.Metadata.Clone()
+	return ch, nil
+}
+
+func (c *Client) GatewayProfile(token, id string) (aiot.Gateway, error) {
+	v, err := c.get(key{kindGateway, id, token}, c.opts.gatewayTTL, func() (interface{}, error) {
+		return c.API.GatewayProfile(token, id)
+	})
+	if err != nil {
+		return aiot.Gateway{}, err
+	}
+
+	g := v.(aiot.Gateway)
+	g.UnderlayThing = cloneThing(g.UnderlayThing)
+	return g, nil
+}
+
+// Gateway chứa thông tin thing của gateway nên sửa hoặc xóa thing cũng xóa
+// các gateway khỏi cache.
+func (c *Client) UpdateThing(token string, in aiot.UpdateThingInput) error {
+	defer c.invalidateKind(kindGateway)
+	defer c.invalidate(kindThing, in.ID)
+
+	return c.API.UpdateThing(token, in)
+}
+
+func (c *Client) RotateThingKey(token, thingID string) (string, error) {
+	defer c.invalidateKind(kindGateway)
+	defer c.invalidate(kindThing, thingID)
+
+	return c.API.RotateThingKey(token, thingID)
+}
+
+func (c *Client) PatchThingMetadata(token, thingID string, patch aiot.Metadata) (aiot.Thing, error) {
+	defer c.invalidateKind(kindGateway)
+	defer c.invalidate(kindThing, thingID)
+
+	return c.API.PatchThingMetadata(token, thingID, patch)
+}
+
+func (c *Client) DeleteThing(token, thingID string) error {
+	defer c.invalidateKind(kindGateway)
+	defer c.invalidate(kindThing, thingID)
+
+	return c.API.DeleteThing(token, thingID)
+}
+
+func (c *Client) Connect(token string, channelIDs, thingIDs []string) error {
+	defer c.invalidate(kindChannel, channelIDs...)
+	defer c.invalidate(kindThing, thingIDs...)
+
+	return c.API.Connect(token, channelIDs, thingIDs)
+}
+
+func (c *Client) Disconnect(token string, channelID, thingID string) error {
+	defer c.invalidate(kindChannel, channelID)
+	defer c.invalidate(kindThing, thingID)
+
+	return c.API.Disconnect(token, channelID, thingID)
+}
+
+func (c *Client) UpdateChannel(token string, in aiot.UpdateChannelInput) error {
+	defer c.invalidate(kindChannel, in.ID)
+
+	return c.API.UpdateChannel(token, in)
+}
+
+func (c *Client) PatchChannelMetadata(token, channelID string, patch aiot.Metadata) (aiot.Channel, error) {
+	defer c.invalidate(kindChannel, channelID)
+
+	return c.API.PatchChannelMetadata(token, channelID, patch)
+}
+
+func (c *Client) DeleteChannel(token, channelID string) error {
+	defer c.invalidate(kindChannel, channelID)
+
+	return c.API.DeleteChannel(token, channelID)
+}
+
+func (c *Client) UpdateGateway(token string, in aiot.UpdateGatewayInput) error {
+	defer c.invalidate(kindGateway, in.ID)
+
+	return c.API.UpdateGateway(token, in)
+}
+
+func (c *Client) DeleteGateway(token, id string) error {
+	defer c.invalidate(kindGateway, id)
+
+	return c.API.DeleteGateway(token, id)
+}
+
+// Giá trị trong cache dùng chung giữa các lời gọi, vì vậy metadata được sao
+// chép trước khi trả về.
+func cloneThing(t aiot.Thing) aiot.Thing {
+	t.Metadata = t.Metadata.Clone()
+	return t
+}
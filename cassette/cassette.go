@@ -0,0 +1,114 @@
+// Package cassette ghi lại các request/response giữa aiot.Client và gateway
+// vào một file cassette, và phát lại chúng để chạy test không cần gateway.
+//
+// Các trường aiot.SensitiveFields (token, mật khẩu, key của thing/channel)
+// được che bằng aiot.Redact trước khi ghi vào file. Body không phải JSON không
+// thể che từng trường nên được thay toàn bộ bằng aiot.Redacted.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+const Version = 1
+
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  string          `json:"query,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+type Response struct {
+	Status int             `json:"status"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// Đọc cassette từ file
+func Load(path string) (*Cassette, error) {
+	const op = "cassette.Load"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	if c.Version != Version {
+		return nil, fmt.Errorf("%s -> unsupported cassette version %d", op, c.Version)
+	}
+
+	return &c, nil
+}
+
+// Ghi cassette ra file
+func (c *Cassette) Save(path string) error {
+	const op = "cassette.Save"
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return nil
+}
+
+// encodeBody che các trường nhạy cảm và chuẩn hóa body. Body JSON được trả
+// về dưới dạng JSON với key đã sắp xếp, các body khác được thay bằng
+// aiot.Redacted vì có thể chứa token hoặc mật khẩu.
+func encodeBody(data []byte) (json.RawMessage, string) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ""
+	}
+
+	if !json.Valid(data) {
+		return nil, aiot.Redacted
+	}
+
+	return aiot.Redact(data), ""
+}
+
+// key là khóa dùng để so khớp request khi phát lại: method, path, query và
+// body JSON đã chuẩn hóa (file cassette có thể đã được sửa bằng tay nên body
+// được chuẩn hóa lại).
+func (r Request) key() string {
+	body := r.Text
+	if len(r.Body) > 0 {
+		b, _ := encodeBody(r.Body)
+		body = string(b)
+	}
+
+	return r.Method + " " + r.Path + "?" + r.Query + " " + body
+}
@@ -0,0 +1,171 @@
+package cassette_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/mobifone-aiot/aiot-go/cassette"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	email    = "test@aiot.vn"
+	password = "secret-password"
+)
+
+func record(t *testing.T, path string) {
+	require := require.New(t)
+
+	srv := aiottest.NewServer()
+	defer srv.Close()
+	srv.AddUser(aiottest.User{Email: email, Password: password})
+
+	rec := cassette.NewRecorder(path, nil)
+	client := aiot.NewClientWithOptions(srv.URL, aiot.NewClientOptions().
+		SetHTTPClient(&http.Client{Transport: rec}))
+
+	token, err := client.Token(email, password)
+	require.NoError(err)
+
+	_, total, err := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Equal(0, total)
+
+	err = client.CreateThing(token, aiot.CreateThingInput{
+		Name:     "demo-1",
+		Metadata: aiot.Metadata{"b": "2", "a": "1"},
+	})
+	require.NoError(err)
+
+	_, total, err = client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Equal(1, total)
+
+	require.Len(rec.Interactions(), 4)
+}
+
+func Test_Record_Redacts(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	record(t, path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(err)
+
+	require.NotContains(string(data), password)
+
+	c, err := cassette.Load(path)
+	require.NoError(err)
+	require.Len(c.Interactions, 4)
+
+	login := c.Interactions[0]
+	require.Equal("/api-gw/v1/user/login", login.Request.Path)
+	require.Equal(http.StatusCreated, login.Response.Status)
+	require.JSONEq(`{"email": "test@aiot.vn", "password": "[REDACTED]"}`, string(login.Request.Body))
+	require.JSONEq(`{"token": "Bearer [REDACTED]"}`, string(login.Response.Body))
+
+	list := c.Interactions[3]
+	require.Contains(string(list.Response.Body), `"key": "[REDACTED]"`)
+}
+
+func Test_Record_RedactsWholeValue(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("token=abc123 password=hunter2"))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: cassette.NewRecorder(path, nil)}
+
+	resp, err := client.Post(srv.URL+"/json", "application/json", strings.NewReader(`{"password": "hunter2 secret", "token": "Bearer abc123"}`))
+	require.NoError(err)
+	resp.Body.Close()
+
+	resp, err = client.Post(srv.URL+"/form", "application/x-www-form-urlencoded", strings.NewReader("password=hunter2"))
+	require.NoError(err)
+	resp.Body.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(err)
+	require.NotContains(string(data), "hunter2")
+	require.NotContains(string(data), "secret")
+	require.NotContains(string(data), "abc123")
+
+	c, err := cassette.Load(path)
+	require.NoError(err)
+	require.Len(c.Interactions, 2)
+
+	// chỉ tiền tố Bearer được giữ lại
+	require.JSONEq(`{"password": "[REDACTED]", "token": "Bearer [REDACTED]"}`, string(c.Interactions[0].Request.Body))
+	require.Equal(aiot.Redacted, c.Interactions[0].Response.Text)
+
+	// body không phải JSON bị che toàn bộ
+	require.Equal(aiot.Redacted, c.Interactions[1].Request.Text)
+	require.Equal(aiot.Redacted, c.Interactions[1].Response.Text)
+}
+
+func Test_Replay(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	record(t, path)
+
+	rep, err := cassette.NewReplayer(path)
+	require.NoError(err)
+
+	client := aiot.NewClientWithOptions("http://replay.invalid", aiot.NewClientOptions().
+		SetHTTPClient(&http.Client{Transport: rep}))
+
+	token, err := client.Token(email, "another-password-is-redacted-too")
+	require.NoError(err)
+	require.Equal(aiot.Redacted, token)
+
+	things, total, err := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Equal(0, total)
+	require.Empty(things)
+
+	// body JSON được so khớp sau khi chuẩn hóa nên thứ tự key không quan trọng
+	err = client.CreateThing(token, aiot.CreateThingInput{
+		Name:     "demo-1",
+		Metadata: aiot.Metadata{"a": "1", "b": "2"},
+	})
+	require.NoError(err)
+
+	require.Error(rep.Done())
+
+	things, total, err = client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Equal(1, total)
+	require.Equal("demo-1", things[0].Name)
+	require.Equal(aiot.Redacted, things[0].Key)
+
+	require.NoError(rep.Done())
+}
+
+func Test_Replay_Unmatched(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	record(t, path)
+
+	rep, err := cassette.NewReplayer(path)
+	require.NoError(err)
+
+	client := aiot.NewClientWithOptions("http://replay.invalid", aiot.NewClientOptions().
+		SetHTTPClient(&http.Client{Transport: rep}))
+
+	err = client.CreateThing("token", aiot.CreateThingInput{Name: "demo-2"})
+	require.Error(err)
+	require.True(strings.Contains(err.Error(), "cassette: unmatched request POST /api-gw/v1/thing"))
+}
@@ -0,0 +1,109 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Các header của response được ghi lại
+var recordedHeaders = []string{"Content-Type", "Retry-After"}
+
+// Recorder là http.RoundTripper chuyển request đến transport thật và ghi
+// lại mỗi cặp request/response vào file cassette
+type Recorder struct {
+	path  string
+	inner http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// Tạo mới Recorder ghi vào file path. Nếu inner là nil thì dùng
+// http.DefaultTransport.
+func NewRecorder(path string, inner http.RoundTripper) *Recorder {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+
+	return &Recorder{
+		path:     path,
+		inner:    inner,
+		cassette: Cassette{Version: Version, Interactions: []Interaction{}},
+	}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	in := Interaction{
+		Request: Request{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Query:  req.URL.RawQuery,
+		},
+		Response: Response{
+			Status: resp.StatusCode,
+			Header: http.Header{},
+		},
+	}
+	in.Request.Body, in.Request.Text = encodeBody(reqBody)
+	in.Response.Body, in.Response.Text = encodeBody(respBody)
+
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			in.Response.Header[h] = v
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	if err := r.cassette.Save(r.path); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Các cặp request/response đã ghi
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// readBody đọc hết body và thay bằng một bản sao để vẫn đọc lại được.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
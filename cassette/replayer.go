@@ -0,0 +1,119 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Replayer là http.RoundTripper trả lời request bằng các response đã ghi
+// trong cassette mà không cần kết nối đến gateway.
+//
+// Request được so khớp theo method, path, query và body JSON (sau khi chuẩn
+// hóa và che các trường nhạy cảm giống như lúc ghi). Mỗi interaction chỉ được
+// dùng một lần, theo đúng thứ tự đã ghi. Request không khớp với interaction
+// nào sẽ trả về lỗi.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Tạo mới Replayer từ file cassette
+func NewReplayer(path string) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Replayer{
+		interactions: c.Interactions,
+		used:         make([]bool, len(c.Interactions)),
+	}, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	got := Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+	}
+	got.Body, got.Text = encodeBody(data)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := got.key()
+	for i, in := range r.interactions {
+		if r.used[i] || in.Request.key() != key {
+			continue
+		}
+
+		r.used[i] = true
+		return makeResponse(req, in.Response), nil
+	}
+
+	return nil, fmt.Errorf("cassette: unmatched request %s", key)
+}
+
+// Các interaction chưa được phát lại
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []Interaction
+	for i, in := range r.interactions {
+		if !r.used[i] {
+			out = append(out, in)
+		}
+	}
+
+	return out
+}
+
+// Trả về lỗi liệt kê các interaction chưa được phát lại, nếu có
+func (r *Replayer) Done() error {
+	unused := r.Unused()
+	if len(unused) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(unused))
+	for i, in := range unused {
+		keys[i] = in.Request.key()
+	}
+
+	return fmt.Errorf("cassette: %d interactions not replayed:\n%s", len(unused), strings.Join(keys, "\n"))
+}
+
+func makeResponse(req *http.Request, r Response) *http.Response {
+	body := []byte(r.Body)
+	if len(body) == 0 {
+		body = []byte(r.Text)
+	}
+
+	header := http.Header{}
+	for k, v := range r.Header {
+		header[k] = append([]string(nil), v...)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -1,6 +1,9 @@
 package aiot
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,12 +11,82 @@ import (
 )
 
 type Client struct {
-	gatewayAddr string
+	gatewayAddr  string
+	httpClient   *http.Client
+	limiter      *limiter
+	breaker      *breaker
+	endpoints    *endpoints
+	interceptors []Interceptor
+	metrics      Metrics
+	patchRetries int
+	patches      *patchLocks
+	ctx          context.Context
 }
 
 // Tạo mới một đối tượng aiot Client
 func NewClient(gatewayAddr string) Client {
-	return Client{gatewayAddr: gatewayAddr}
+	return NewClientWithOptions(gatewayAddr, NewClientOptions())
+}
+
+// Tạo mới một đối tượng aiot Client với các tùy chọn
+func NewClientWithOptions(gatewayAddr string, opts *ClientOptions) Client {
+	var interceptors []Interceptor
+	if opts.tracer != nil {
+		interceptors = append(interceptors, TracingInterceptor(opts.tracer))
+	}
+	interceptors = append(interceptors, opts.interceptors...)
+	if opts.metrics != nil {
+		interceptors = append(interceptors, MetricsInterceptor(opts.metrics))
+	}
+	if opts.logger != nil {
+		interceptors = append(interceptors, LoggingInterceptor(opts.logger))
+	}
+
+	return Client{
+		gatewayAddr:  gatewayAddr,
+		httpClient:   opts.httpClient,
+		limiter:      newLimiter(opts),
+		breaker:      newBreaker(opts.breaker),
+		endpoints:    newEndpoints(gatewayAddr, opts),
+		interceptors: interceptors,
+		metrics:      opts.metrics,
+		patchRetries: opts.patchRetries,
+		patches:      newPatchLocks(),
+	}
+}
+
+// WithContext trả về bản sao của Client dùng ctx cho các lời gọi API: request
+// bị hủy khi ctx bị hủy, và span của Tracer là con của span trong ctx.
+func (c Client) WithContext(ctx context.Context) Client {
+	c.ctx = ctx
+	return c
+}
+
+// context trả về context của Client, mặc định context.Background()
+func (c Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
+
+// Trạng thái giới hạn của route (ví dụ "/thing/connect"), hoặc của giới hạn
+// chung nếu route rỗng
+func (c Client) LimitStats(route string) LimitStats {
+	return c.limiter.stats(route)
+}
+
+// Trạng thái hiện tại của circuit breaker, luôn là CIRCUIT_CLOSED nếu không
+// dùng circuit breaker
+func (c Client) CircuitState() CircuitState {
+	return c.breaker.currentState()
+}
+
+// Trạng thái của địa chỉ gateway chính và các địa chỉ dự phòng, rỗng nếu
+// không dùng địa chỉ dự phòng
+func (c Client) Endpoints() []EndpointStatus {
+	return c.endpoints.status()
 }
 
 // Tạo mới một token bằng username và password
@@ -21,6 +94,7 @@ func (c Client) Token(email, password string) (string, error) {
 	const op operation = "aiot.Token"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/user/login",
 		Method: http.MethodPost,
 		Body: map[string]string{
@@ -53,6 +127,7 @@ func (c Client) TokenVerify(token string) (bool, error) {
 	const op operation = "aiot.TokenVerify"
 
 	_, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/user/verify",
 		Method: http.MethodGet,
 		Token:  token,
@@ -70,6 +145,7 @@ func (c Client) ResetPassword(token, newPW, oldPW string) error {
 	const op operation = "aiot.ResetPassword"
 
 	_, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/user/reset-password",
 		Method: http.MethodPost,
 		Token:  token,
@@ -91,6 +167,7 @@ func (c Client) UserProfile(token string) (User, error) {
 	const op operation = "aiot.UserProfile"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/user/profile",
 		Method: http.MethodGet,
 		Token:  token,
@@ -135,6 +212,7 @@ func (c Client) ListThingsByUser(token string, opts *ListThingsByUserOptions) ([
 	const op operation = "aiot.ListThingsByUser"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/thing/list",
 		Method: http.MethodGet,
 		Token:  token,
@@ -159,13 +237,18 @@ func (c Client) ListThingsByUser(token string, opts *ListThingsByUserOptions) ([
 		return nil, 0, makeE(op, err)
 	}
 
-	return body.Data, body.Total, nil
+	return withThingVersions(body.Data), body.Total, nil
 }
 
 func (c Client) CreateThing(token string, in CreateThingInput) error {
 	const op operation = "aiot.CreateThing"
 
+	if err := in.Validate(); err != nil {
+		return makeE(op, KIND_INVALID, err)
+	}
+
 	_, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/thing",
 		Method: http.MethodPost,
 		Token:  token,
@@ -186,6 +269,8 @@ func (c Client) DeleteThing(token, thingID string) error {
 	const op operation = "aiot.DeleteThing"
 
 	_, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_THING_ID, thingID}},
 		Path:   "/api-gw/v1/thing/" + thingID,
 		Method: http.MethodDelete,
 		Token:  token,
@@ -202,6 +287,8 @@ func (c Client) ThingProfile(token, thingID string) (Thing, error) {
 	const op operation = "aiot.ThingProfile"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_THING_ID, thingID}},
 		Path:   "/api-gw/v1/thing/" + thingID,
 		Method: http.MethodGet,
 		Token:  token,
@@ -212,36 +299,59 @@ func (c Client) ThingProfile(token, thingID string) (Thing, error) {
 	}
 
 	var body struct {
-		ID       string            `json:"id"`
-		Key      string            `json:"key"`
-		Name     string            `json:"name"`
-		Metadata map[string]string `json:"metadata"`
+		ID       string   `json:"id"`
+		Key      string   `json:"key"`
+		Name     string   `json:"name"`
+		Metadata Metadata `json:"metadata"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
 		return Thing{}, makeE(op, err)
 	}
 
-	return Thing{
+	t := Thing{
 		ID:       body.ID,
 		Key:      body.Key,
 		Name:     body.Name,
 		Metadata: body.Metadata,
-	}, nil
+	}
+	t.Version = profileVersion(resp, thingVersion(t))
+
+	return t, nil
 }
 
 func (c Client) UpdateThing(token string, in UpdateThingInput) error {
 	const op operation = "aiot.UpdateThing"
 
-	_, err := c.httpDo(request{
+	if err := in.Validate(); err != nil {
+		return makeE(op, KIND_INVALID, err)
+	}
+
+	header, err := precondition(in.Version, func() (string, error) {
+		t, err := c.ThingProfile(token, in.ID)
+		return thingVersion(t), err
+	})
+	if err != nil {
+		return makeE(op, err)
+	}
+
+	body := map[string]interface{}{
+		"id":       in.ID,
+		"name":     in.Name,
+		"metadata": in.Metadata,
+	}
+	if in.Key != "" {
+		body["key"] = in.Key
+	}
+
+	_, err = c.httpDo(request{
+		Op:     op,
+		Header: header,
+		Attrs:  []Field{{ATTR_THING_ID, in.ID}},
 		Path:   "/api-gw/v1/thing",
 		Method: http.MethodPut,
 		Token:  token,
-		Body: map[string]interface{}{
-			"id":       in.ID,
-			"name":     in.Name,
-			"metadata": in.Metadata,
-		},
+		Body:   body,
 	})
 
 	if err != nil {
@@ -251,6 +361,50 @@ func (c Client) UpdateThing(token string, in UpdateThingInput) error {
 	return nil
 }
 
+// RotateThingKey sinh key ngẫu nhiên mới cho thing bằng UpdateThing và trả
+// về key đó. Tên và metadata của thing được giữ nguyên; nếu thing bị sửa
+// trong lúc đổi key thì trả về lỗi KIND_CONFLICT.
+func (c Client) RotateThingKey(token, thingID string) (string, error) {
+	const op operation = "aiot.RotateThingKey"
+
+	key, err := newThingKey()
+	if err != nil {
+		return "", makeE(op, err)
+	}
+
+	t, err := c.ThingProfile(token, thingID)
+	if err != nil {
+		return "", makeE(op, err)
+	}
+
+	err = c.UpdateThing(token, UpdateThingInput{
+		ID:       t.ID,
+		Name:     t.Name,
+		Metadata: t.Metadata,
+		Key:      key,
+		Version:  t.Version,
+	})
+	if err != nil {
+		return "", makeE(op, err)
+	}
+
+	return key, nil
+}
+
+// newThingKey sinh key dạng UUID v4 giống key do gateway cấp
+func newThingKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32], nil
+}
+
 func (c Client) ListChannelByThing(token, thingID string, opts *ListChannelByThingOptions) ([]Channel, int, error) {
 	const op operation = "client.ListChannelByThing"
 
@@ -260,6 +414,8 @@ func (c Client) ListChannelByThing(token, thingID string, opts *ListChannelByThi
 	}
 
 	resp, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_THING_ID, thingID}},
 		Path:   fmt.Sprintf("/api-gw/v1/thing/%s/channels", thingID),
 		Method: http.MethodGet,
 		Token:  token,
@@ -285,13 +441,15 @@ func (c Client) ListChannelByThing(token, thingID string, opts *ListChannelByThi
 		return nil, 0, makeE(op, err)
 	}
 
-	return body.Data, body.Total, nil
+	return withChannelVersions(body.Data), body.Total, nil
 }
 
 func (c Client) Connect(token string, channelIDs, thingIDs []string) error {
 	const op operation = "client.Connect"
 
 	_, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_CHANNEL_ID, channelIDs}, {ATTR_THING_ID, thingIDs}},
 		Path:   "/api-gw/v1/thing/connect",
 		Method: http.MethodPost,
 		Token:  token,
@@ -312,6 +470,8 @@ func (c Client) Disconnect(token string, channelID, thingID string) error {
 	const op operation = "client.Disconnect"
 
 	_, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_CHANNEL_ID, channelID}, {ATTR_THING_ID, thingID}},
 		Path:   fmt.Sprintf("/api-gw/v1/thing/%s/channel/%s", thingID, channelID),
 		Method: http.MethodDelete,
 		Token:  token,
@@ -326,7 +486,12 @@ func (c Client) Disconnect(token string, channelID, thingID string) error {
 func (c Client) CreateChannel(token string, in CreateChannelInput) error {
 	const op operation = "aiot.CreateChannel"
 
+	if err := in.Validate(); err != nil {
+		return makeE(op, KIND_INVALID, err)
+	}
+
 	_, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/channel",
 		Method: http.MethodPost,
 		Token:  token,
@@ -346,7 +511,22 @@ func (c Client) CreateChannel(token string, in CreateChannelInput) error {
 func (c Client) UpdateChannel(token string, in UpdateChannelInput) error {
 	const op operation = "aiot.UpdateChannel"
 
-	_, err := c.httpDo(request{
+	if err := in.Validate(); err != nil {
+		return makeE(op, KIND_INVALID, err)
+	}
+
+	header, err := precondition(in.Version, func() (string, error) {
+		ch, err := c.ChannelProfile(token, in.ID)
+		return channelVersion(ch), err
+	})
+	if err != nil {
+		return makeE(op, err)
+	}
+
+	_, err = c.httpDo(request{
+		Op:     op,
+		Header: header,
+		Attrs:  []Field{{ATTR_CHANNEL_ID, in.ID}},
 		Path:   "/api-gw/v1/channel",
 		Method: http.MethodPut,
 		Token:  token,
@@ -368,6 +548,8 @@ func (c Client) DeleteChannel(token, channelID string) error {
 	const op operation = "aiot.DeleteChannel"
 
 	_, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_CHANNEL_ID, channelID}},
 		Path:   "/api-gw/v1/channel/" + channelID,
 		Method: http.MethodDelete,
 		Token:  token,
@@ -384,6 +566,8 @@ func (c Client) ChannelProfile(token, channelID string) (Channel, error) {
 	const op operation = "aiot.ChannelProfile"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_CHANNEL_ID, channelID}},
 		Path:   "/api-gw/v1/channel/" + channelID,
 		Method: http.MethodGet,
 		Token:  token,
@@ -394,28 +578,32 @@ func (c Client) ChannelProfile(token, channelID string) (Channel, error) {
 	}
 
 	var body struct {
-		ID       string            `json:"id"`
-		Key      string            `json:"key"`
-		Name     string            `json:"name"`
-		Metadata map[string]string `json:"metadata"`
+		ID       string   `json:"id"`
+		Key      string   `json:"key"`
+		Name     string   `json:"name"`
+		Metadata Metadata `json:"metadata"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
 		return Channel{}, makeE(op, err)
 	}
 
-	return Channel{
+	ch := Channel{
 		ID:       body.ID,
 		Key:      body.Key,
 		Name:     body.Name,
 		Metadata: body.Metadata,
-	}, nil
+	}
+	ch.Version = profileVersion(resp, channelVersion(ch))
+
+	return ch, nil
 }
 
 func (c Client) ListAllChannel(token string, opts *ListAllChannelOptions) ([]Channel, int, error) {
 	const op operation = "aiot.ListAllChannel"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/thing/getall",
 		Method: http.MethodGet,
 		Token:  token,
@@ -440,13 +628,14 @@ func (c Client) ListAllChannel(token string, opts *ListAllChannelOptions) ([]Cha
 		return nil, 0, makeE(op, err)
 	}
 
-	return body.Data, body.Total, nil
+	return withChannelVersions(body.Data), body.Total, nil
 }
 
 func (c Client) ListChannelByUser(token string, opts *ListChannelByUserOptions) ([]Channel, int, error) {
 	const op operation = "aiot.ListChannelByUser"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/channel/list",
 		Method: http.MethodGet,
 		Token:  token,
@@ -471,13 +660,19 @@ func (c Client) ListChannelByUser(token string, opts *ListChannelByUserOptions)
 		return nil, 0, makeE(op, err)
 	}
 
-	return body.Data, body.Total, nil
+	return withChannelVersions(body.Data), body.Total, nil
 }
 
 func (c Client) CreateGateway(token string, in CreateGatewayInput) error {
 	const op operation = "aiot.CreateGateway"
 
+	if err := in.Validate(); err != nil {
+		return makeE(op, KIND_INVALID, err)
+	}
+
 	_, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_THING_ID, in.ThingID}},
 		Path:   "/api-gw/v1/gateway/create",
 		Method: http.MethodPost,
 		Token:  token,
@@ -498,7 +693,22 @@ func (c Client) CreateGateway(token string, in CreateGatewayInput) error {
 func (c Client) UpdateGateway(token string, in UpdateGatewayInput) error {
 	const op operation = "aiot.UpdateGateway"
 
-	_, err := c.httpDo(request{
+	if err := in.Validate(); err != nil {
+		return makeE(op, KIND_INVALID, err)
+	}
+
+	header, err := precondition(in.Version, func() (string, error) {
+		g, err := c.GatewayProfile(token, in.ID)
+		return gatewayVersion(g), err
+	})
+	if err != nil {
+		return makeE(op, err)
+	}
+
+	_, err = c.httpDo(request{
+		Op:     op,
+		Header: header,
+		Attrs:  []Field{{ATTR_GATEWAY_ID, in.ID}},
 		Path:   "/api-gw/v1/gateway/edit",
 		Method: http.MethodPut,
 		Token:  token,
@@ -520,6 +730,8 @@ func (c Client) DeleteGateway(token, id string) error {
 	const op operation = "aiot.DeleteGateway"
 
 	_, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_GATEWAY_ID, id}},
 		Path:   "/api-gw/v1/gateway/" + id,
 		Method: http.MethodDelete,
 		Token:  token,
@@ -536,6 +748,8 @@ func (c Client) GatewayProfile(token, id string) (Gateway, error) {
 	const op operation = "aiot.GatewayProfile"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_GATEWAY_ID, id}},
 		Path:   "/api-gw/v1/gateway/" + id,
 		Method: http.MethodGet,
 		Token:  token,
@@ -546,25 +760,22 @@ func (c Client) GatewayProfile(token, id string) (Gateway, error) {
 	}
 
 	var body struct {
-		GatewayID          string `json:"gatewayId"`
-		GatewayName        string `json:"gatewayName"`
-		GatewayDescription string `json:"gatewayDes"`
-		GatewayOwner       string `json:"gatewayOwner"`
-		ThingID            string `json:"thingId"`
-		ThingName          string `json:"thingName"`
-		ThingKey           string `json:"thingKey"`
-		ThingOwner         string `json:"thingOwner"`
-		Metadata           string `json:"metadata"`
+		GatewayID          string   `json:"gatewayId"`
+		GatewayName        string   `json:"gatewayName"`
+		GatewayDescription string   `json:"gatewayDes"`
+		GatewayOwner       string   `json:"gatewayOwner"`
+		ThingID            string   `json:"thingId"`
+		ThingName          string   `json:"thingName"`
+		ThingKey           string   `json:"thingKey"`
+		ThingOwner         string   `json:"thingOwner"`
+		Metadata           Metadata `json:"metadata"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
 		return Gateway{}, makeE(op, err)
 	}
 
-	metadata := make(map[string]string)
-	json.Unmarshal([]byte(body.Metadata), &metadata)
-
-	return Gateway{
+	g := Gateway{
 		ID:          body.GatewayID,
 		Name:        body.GatewayName,
 		Description: body.GatewayDescription,
@@ -573,16 +784,21 @@ func (c Client) GatewayProfile(token, id string) (Gateway, error) {
 			ID:       body.ThingID,
 			Name:     body.ThingName,
 			Key:      body.ThingKey,
-			Metadata: metadata,
+			Metadata: body.Metadata,
 		},
 		UnderlayThingOwner: body.ThingOwner,
-	}, nil
+	}
+	g.UnderlayThing.Version = thingVersion(g.UnderlayThing)
+	g.Version = profileVersion(resp, gatewayVersion(g))
+
+	return g, nil
 }
 
 func (c Client) ListGateway(token string) ([]Gateway, error) {
 	const op operation = "aiot.ListGateway"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/gateway/list",
 		Method: http.MethodGet,
 		Token:  token,
@@ -593,15 +809,15 @@ func (c Client) ListGateway(token string) ([]Gateway, error) {
 	}
 
 	var body []struct {
-		GatewayID          string `json:"gatewayId"`
-		GatewayName        string `json:"gatewayName"`
-		GatewayDescription string `json:"gatewayDes"`
-		GatewayOwner       string `json:"gatewayOwner"`
-		ThingID            string `json:"thingId"`
-		ThingName          string `json:"thingName"`
-		ThingKey           string `json:"thingKey"`
-		ThingOwner         string `json:"thingOwner"`
-		Metadata           string `json:"metadata"`
+		GatewayID          string   `json:"gatewayId"`
+		GatewayName        string   `json:"gatewayName"`
+		GatewayDescription string   `json:"gatewayDes"`
+		GatewayOwner       string   `json:"gatewayOwner"`
+		ThingID            string   `json:"thingId"`
+		ThingName          string   `json:"thingName"`
+		ThingKey           string   `json:"thingKey"`
+		ThingOwner         string   `json:"thingOwner"`
+		Metadata           Metadata `json:"metadata"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
@@ -610,10 +826,7 @@ func (c Client) ListGateway(token string) ([]Gateway, error) {
 
 	gateways := []Gateway{}
 	for _, g := range body {
-		metadata := make(map[string]string)
-		json.Unmarshal([]byte(g.Metadata), &metadata)
-
-		gateways = append(gateways, Gateway{
+		gw := Gateway{
 			ID:          g.GatewayID,
 			Name:        g.GatewayName,
 			Description: g.GatewayDescription,
@@ -622,10 +835,14 @@ func (c Client) ListGateway(token string) ([]Gateway, error) {
 				ID:       g.ThingID,
 				Name:     g.ThingName,
 				Key:      g.ThingKey,
-				Metadata: metadata,
+				Metadata: g.Metadata,
 			},
 			UnderlayThingOwner: g.ThingOwner,
-		})
+		}
+		gw.UnderlayThing.Version = thingVersion(gw.UnderlayThing)
+		gw.Version = gatewayVersion(gw)
+
+		gateways = append(gateways, gw)
 	}
 
 	return gateways, nil
@@ -635,6 +852,7 @@ func (c Client) GatewayStatus(token string) (map[string]bool, error) {
 	const op operation = "aiot.GatewayStatus"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
 		Path:   "/api-gw/v1/gateway/status",
 		Method: http.MethodGet,
 		Token:  token,
@@ -656,6 +874,8 @@ func (c Client) GatewayActiveDeviceCount(token, gateID string) (int, error) {
 	const op operation = "aiot.GatewayActiveDeviceCount"
 
 	resp, err := c.httpDo(request{
+		Op:     op,
+		Attrs:  []Field{{ATTR_GATEWAY_ID, gateID}},
 		Path:   "/api-gw/v1/gateway/active-device-count/" + gateID,
 		Method: http.MethodGet,
 		Token:  token,
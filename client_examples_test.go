@@ -3,10 +3,28 @@ package aiot_test
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"time"
 
 	"github.com/mobifone-aiot/aiot-go"
 )
 
+func ExampleNewClientWithOptions() {
+	// Tạo một aiot client dùng http.Client có timeout
+
+	opts := aiot.NewClientOptions().
+		SetHTTPClient(&http.Client{Timeout: 10 * time.Second})
+
+	client := aiot.NewClientWithOptions("http://localhost", opts)
+
+	token, err := client.Token("email@demo.com", "password")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("Token: %s", token)
+}
+
 func ExampleClient_Token() {
 	// Tạo một aiot client và thực hiện lệnh lấy token cho một user
 
@@ -105,7 +123,7 @@ func ExampleClient_CreateThing() {
 
 	err = client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -127,9 +145,9 @@ func ExampleClient_UpdateThing() {
 	}
 
 	err = client.UpdateThing(token, aiot.UpdateThingInput{
-		ID:   "thing-id",
+		ID:   "8f14e45f-ceea-4e6b-9a3d-2c1b7e5d4f60",
 		Name: "demo-2",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-2": "meta-2",
 		},
 	})
@@ -150,7 +168,7 @@ func ExampleClient_DeleteThing() {
 		log.Fatalln(err)
 	}
 
-	if err := client.DeleteThing(token, "thing-id"); err != nil {
+	if err := client.DeleteThing(token, "8f14e45f-ceea-4e6b-9a3d-2c1b7e5d4f60"); err != nil {
 		log.Fatalln(err)
 	}
 
@@ -167,7 +185,7 @@ func ExampleClient_ThingProfile() {
 		log.Fatalln(err)
 	}
 
-	tp, err := client.ThingProfile(token, "thing-id")
+	tp, err := client.ThingProfile(token, "8f14e45f-ceea-4e6b-9a3d-2c1b7e5d4f60")
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -189,7 +207,7 @@ func ExampleClient_ListChannelByThing() {
 		SetDirection(aiot.DIRECTION_ASC).
 		SetDisconnected(true)
 
-	channels, total, err := client.ListChannelByThing(token, "thing-id", opts)
+	channels, total, err := client.ListChannelByThing(token, "8f14e45f-ceea-4e6b-9a3d-2c1b7e5d4f60", opts)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -208,7 +226,7 @@ func ExampleClient_Connect() {
 		log.Fatalln(err)
 	}
 
-	err = client.Connect(token, []string{"channel-id"}, []string{"thing-id"})
+	err = client.Connect(token, []string{"c9f0f895-fb98-4b91-8c2e-6a7d3e1f5b24"}, []string{"8f14e45f-ceea-4e6b-9a3d-2c1b7e5d4f60"})
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -226,7 +244,7 @@ func ExampleClient_Disconnect() {
 		log.Fatalln(err)
 	}
 
-	err = client.Disconnect(token, "channel-id", "thing-id")
+	err = client.Disconnect(token, "c9f0f895-fb98-4b91-8c2e-6a7d3e1f5b24", "8f14e45f-ceea-4e6b-9a3d-2c1b7e5d4f60")
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -246,7 +264,7 @@ func ExampleClient_CreateGateway() {
 
 	err = client.CreateGateway(token, aiot.CreateGatewayInput{
 		Name:        "demo-1",
-		ThingID:     "thing-id-1",
+		ThingID:     "8f14e45f-ceea-4e6b-9a3d-2c1b7e5d4f60",
 		Description: "demo-1",
 	})
 
@@ -268,7 +286,7 @@ func ExampleClient_UpdateGateway() {
 	}
 
 	err = client.UpdateGateway(token, aiot.UpdateGatewayInput{
-		ID:          "gateway-id-1",
+		ID:          "45c48cce-2e2d-4fbd-b7a1-93e0d6f8a215",
 		Name:        "demo-2",
 		Description: "demo-2",
 	})
@@ -290,7 +308,7 @@ func ExampleClient_GatewayProfile() {
 		log.Fatalln(err)
 	}
 
-	gateway, err := client.GatewayProfile(token, "gateway-id-1")
+	gateway, err := client.GatewayProfile(token, "45c48cce-2e2d-4fbd-b7a1-93e0d6f8a215")
 
 	if err != nil {
 		log.Fatalln(err)
@@ -309,7 +327,7 @@ func ExampleClient_DeleteGateway() {
 		log.Fatalln(err)
 	}
 
-	if err := client.DeleteGateway(token, "gateway-id-1"); err != nil {
+	if err := client.DeleteGateway(token, "45c48cce-2e2d-4fbd-b7a1-93e0d6f8a215"); err != nil {
 		log.Fatalln(err)
 	}
 }
@@ -342,7 +360,7 @@ func ExampleClient_GatewayActiveDeviceCount() {
 		log.Fatalln(err)
 	}
 
-	count, err := client.GatewayActiveDeviceCount(token, "gateway-id-1")
+	count, err := client.GatewayActiveDeviceCount(token, "45c48cce-2e2d-4fbd-b7a1-93e0d6f8a215")
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -85,7 +85,7 @@ func Test_ListThingsByUser(t *testing.T) {
 
 	err = client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 			"meta-2": "meta-2",
 		},
@@ -118,7 +118,7 @@ func Test_CreateThing(t *testing.T) {
 
 	err := client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 			"meta-2": "meta-2",
 		},
@@ -146,7 +146,7 @@ func Test_DeleteThing(t *testing.T) {
 
 	client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 			"meta-2": "meta-2",
 		},
@@ -170,7 +170,7 @@ func Test_ThingProfile(t *testing.T) {
 
 	client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 			"meta-2": "meta-2",
 		},
@@ -197,7 +197,7 @@ func Test_UpdateThing(t *testing.T) {
 
 	client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 			"meta-2": "meta-2",
 		},
@@ -223,6 +223,45 @@ func Test_UpdateThing(t *testing.T) {
 	client.DeleteThing(token, things[0].ID)
 }
 
+func Test_RotateThingKey(t *testing.T) {
+	require := require.New(t)
+
+	client := aiot.NewClient(gatewayAddr)
+	token, _ := client.Token(validEmail, validPassword)
+
+	client.CreateThing(token, aiot.CreateThingInput{Name: "demo-1"})
+	client.CreateChannel(token, aiot.CreateChannelInput{Name: "demo-1"})
+
+	things, _, _ := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	channels, _, _ := client.ListChannelByUser(token, aiot.NewListChannelByUserOptions())
+	require.NoError(client.Connect(token, []string{channels[0].ID}, []string{things[0].ID}))
+
+	key, err := client.RotateThingKey(token, things[0].ID)
+	require.NoError(err)
+	require.NotEqual(things[0].Key, key)
+
+	thing, err := client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.Equal(key, thing.Key)
+
+	connected, _, err := client.ListChannelByThing(token, things[0].ID, aiot.NewListChannelByThingOptions().SetDisconnected(true))
+	require.NoError(err)
+	require.Len(connected, 1)
+
+	// key của channel khác không được dùng lại
+	require.NotEmpty(channels[0].Key)
+	require.Error(client.UpdateThing(token, aiot.UpdateThingInput{ID: thing.ID, Name: thing.Name, Key: channels[0].Key}))
+
+	// key rỗng là giữ nguyên key
+	require.NoError(client.UpdateThing(token, aiot.UpdateThingInput{ID: thing.ID, Name: "demo-2"}))
+	thing, err = client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.Equal(key, thing.Key)
+
+	client.DeleteThing(token, things[0].ID)
+	client.DeleteChannel(token, channels[0].ID)
+}
+
 func Test_CreateChannel(t *testing.T) {
 	require := require.New(t)
 
@@ -231,7 +270,7 @@ func Test_CreateChannel(t *testing.T) {
 
 	err := client.CreateChannel(token, aiot.CreateChannelInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -255,7 +294,7 @@ func Test_UpdateChannel(t *testing.T) {
 
 	err := client.CreateChannel(token, aiot.CreateChannelInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -267,7 +306,7 @@ func Test_UpdateChannel(t *testing.T) {
 	err = client.UpdateChannel(token, aiot.UpdateChannelInput{
 		ID:   channels[0].ID,
 		Name: "demo-2",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-2": "meta-2",
 		},
 	})
@@ -290,7 +329,7 @@ func Test_DeleteChannel(t *testing.T) {
 
 	err := client.CreateChannel(token, aiot.CreateChannelInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -319,7 +358,7 @@ func Test_ChannelProfile(t *testing.T) {
 
 	err := client.CreateChannel(token, aiot.CreateChannelInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -356,7 +395,7 @@ func Test_ListChannelByUser(t *testing.T) {
 
 	err = client.CreateChannel(token, aiot.CreateChannelInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -380,7 +419,7 @@ func Test_ListChannelByThing(t *testing.T) {
 
 	err := client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -388,7 +427,7 @@ func Test_ListChannelByThing(t *testing.T) {
 
 	err = client.CreateChannel(token, aiot.CreateChannelInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -539,6 +578,7 @@ func Test_Disconnect(t *testing.T) {
 
 func Test_CreateGateway(t *testing.T) {
 	cleanup()
+	t.Cleanup(cleanup)
 
 	require := require.New(t)
 
@@ -547,7 +587,7 @@ func Test_CreateGateway(t *testing.T) {
 
 	err := client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -584,7 +624,7 @@ func Test_UpdateGateway(t *testing.T) {
 
 	err := client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -622,6 +662,7 @@ func Test_UpdateGateway(t *testing.T) {
 
 func Test_GatewayProfile(t *testing.T) {
 	cleanup()
+	t.Cleanup(cleanup)
 
 	require := require.New(t)
 
@@ -630,7 +671,7 @@ func Test_GatewayProfile(t *testing.T) {
 
 	err := client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -670,7 +711,7 @@ func Test_DeleteGateway(t *testing.T) {
 
 	err := client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -710,7 +751,7 @@ func Test_GatewayStatus(t *testing.T) {
 
 	err := client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -744,7 +785,7 @@ func Test_GatewayActiveDeviceCount(t *testing.T) {
 
 	err := client.CreateThing(token, aiot.CreateThingInput{
 		Name: "demo-1",
-		Metadata: map[string]string{
+		Metadata: aiot.Metadata{
 			"meta-1": "meta-1",
 		},
 	})
@@ -812,7 +853,7 @@ func createThings(token string, count int) ([]aiot.Thing, error) {
 	for i := 1; i <= count; i++ {
 		err := client.CreateThing(token, aiot.CreateThingInput{
 			Name: fmt.Sprintf("demo-%d", i),
-			Metadata: map[string]string{
+			Metadata: aiot.Metadata{
 				fmt.Sprintf("meta-%d", i): fmt.Sprintf("meta-%d", i),
 			},
 		})
@@ -836,7 +877,7 @@ func createChannels(token string, count int) ([]aiot.Channel, error) {
 	for i := 1; i <= count; i++ {
 		err := client.CreateChannel(token, aiot.CreateChannelInput{
 			Name: fmt.Sprintf("demo-%d", i),
-			Metadata: map[string]string{
+			Metadata: aiot.Metadata{
 				fmt.Sprintf("meta-%d", i): fmt.Sprintf("meta-%d", i),
 			},
 		})
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func cmdLogin(e *env, args []string) error {
+	e.newFlags()
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	client, p, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	password, _, err := p.Credentials()
+	if err != nil {
+		return err
+	}
+
+	if p.Email == "" || password == "" {
+		return errors.New("missing credentials, use --email and --password")
+	}
+
+	token, err := client.Token(p.Email, password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, token)
+	return nil
+}
+
+func cmdTokenVerify(e *env, args []string) error {
+	e.newFlags()
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	ok, err := client.TokenVerify(token)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, ok)
+	return nil
+}
+
+func cmdPasswordReset(e *env, args []string) error {
+	fs := e.newFlags()
+	newPW := fs.String("new", "", "password mới")
+	oldPW := fs.String("old", "", "password hiện tại, mặc định là --password")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	if *newPW == "" {
+		return errors.New("missing --new")
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	if *oldPW == "" {
+		p, err := e.settings()
+		if err != nil {
+			return err
+		}
+		if *oldPW, _, err = p.Credentials(); err != nil {
+			return err
+		}
+	}
+
+	if err := client.ResetPassword(token, *newPW, *oldPW); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "password changed")
+	return nil
+}
+
+func cmdUserProfile(e *env, args []string) error {
+	var out outputFlags
+	out.register(e.newFlags())
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	u, err := client.UserProfile(token)
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, u)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mobifone-aiot/aiot-go/backup"
+)
+
+func cmdExport(e *env, args []string) error {
+	fs := e.newFlags()
+	file := fs.String("f", "", "file archive, mặc định in ra stdout")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	a, err := backup.Export(client, token)
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return a.Write(e.stdout)
+	}
+
+	f, err := os.OpenFile(*file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := a.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "exported %d things, %d channels, %d connections, %d gateways\n",
+		len(a.Things), len(a.Channels), len(a.Connections), len(a.Gateways))
+	return nil
+}
+
+func cmdImport(e *env, args []string) error {
+	fs := e.newFlags()
+	file := fs.String("f", "", "file archive")
+	conflict := fs.String("on-conflict", string(backup.CONFLICT_FAIL), "khi trùng tên: fail, reuse, create")
+	reportFile := fs.String("report", "", "file ghi bảng ánh xạ id, mặc định in ra stdout")
+	newKeys := fs.Bool("new-keys", false, "giữ key mới của thing thay vì đặt lại key trong archive")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return errors.New("missing -f")
+	}
+
+	policy := backup.ConflictPolicy(*conflict)
+	switch policy {
+	case backup.CONFLICT_FAIL, backup.CONFLICT_REUSE, backup.CONFLICT_CREATE:
+	default:
+		return fmt.Errorf("unknown --on-conflict %q", *conflict)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	a, err := backup.Read(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	report, importErr := backup.Import(client, token, a, backup.NewOptions().
+		SetConflictPolicy(policy).
+		SetRestoreKeys(!*newKeys))
+
+	// report được ghi cả khi lỗi để biết những gì đã được nhập
+	if *reportFile == "" {
+		if err := report.Write(e.stdout); err != nil {
+			return err
+		}
+	} else if err := writeReport(*reportFile, report); err != nil {
+		return err
+	}
+
+	return importErr
+}
+
+func writeReport(path string, report backup.Report) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/render"
+)
+
+func cmdChannelList(e *env, args []string) error {
+	fs := e.newFlags()
+	var out outputFlags
+	out.register(fs)
+	var p pageFlags
+	p.register(fs)
+	all := fs.Bool("all", false, "liệt kê toàn bộ channel của nền tảng")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	var channels []aiot.Channel
+	var total int
+
+	if *all {
+		opts := aiot.NewListAllChannelOptions().
+			SetOffset(p.offset).
+			SetLimit(p.limit).
+			SetOrder(aiot.ThingOrder(p.order)).
+			SetDirection(aiot.Direction(p.dir))
+		channels, total, err = client.ListAllChannel(token, opts)
+	} else {
+		opts := aiot.NewListChannelByUserOptions().
+			SetOffset(p.offset).
+			SetLimit(p.limit).
+			SetOrder(aiot.ThingOrder(p.order)).
+			SetDirection(aiot.Direction(p.dir))
+		channels, total, err = client.ListChannelByUser(token, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, render.List{Items: channels, Total: total})
+}
+
+func cmdChannelGet(e *env, args []string) error {
+	var out outputFlags
+	out.register(e.newFlags())
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	c, err := client.ChannelProfile(token, ids[0])
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, c)
+}
+
+func cmdChannelCreate(e *env, args []string) error {
+	fs := e.newFlags()
+	name := fs.String("name", "", "tên channel")
+	meta := metaFlag{}
+	fs.Var(meta, "meta", "metadata dạng key=value hoặc key:=<json>, có thể lặp lại")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return errors.New("missing --name")
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	err = client.CreateChannel(token, aiot.CreateChannelInput{
+		Name:     *name,
+		Metadata: aiot.Metadata(meta),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "channel created")
+	return nil
+}
+
+// cmdChannelUpdate giữ nguyên tên và metadata hiện tại nếu không có --name
+// hoặc --meta.
+func cmdChannelUpdate(e *env, args []string) error {
+	fs := e.newFlags()
+	name := fs.String("name", "", "tên mới")
+	meta := metaFlag{}
+	fs.Var(meta, "meta", "metadata mới dạng key=value hoặc key:=<json>, có thể lặp lại")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	c, err := client.ChannelProfile(token, ids[0])
+	if err != nil {
+		return err
+	}
+
+	in := aiot.UpdateChannelInput{ID: c.ID, Name: c.Name, Metadata: c.Metadata, Version: c.Version}
+	if *name != "" {
+		in.Name = *name
+	}
+	if len(meta) > 0 {
+		in.Metadata = aiot.Metadata(meta)
+	}
+
+	if err := client.UpdateChannel(token, in); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "channel updated")
+	return nil
+}
+
+func cmdChannelDelete(e *env, args []string) error {
+	e.newFlags()
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, -1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := client.DeleteChannel(token, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "channel %s deleted\n", id)
+	}
+
+	return nil
+}
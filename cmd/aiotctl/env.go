@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+var (
+	errUsage = errors.New("usage")
+	errHelp  = errors.New("help")
+)
+
+// config là thông tin kết nối lấy từ flag hoặc biến môi trường, ghi đè lên
+// profile trong file cấu hình
+type config struct {
+	Gateway  string
+	Email    string
+	Password string
+	Token    string
+}
+
+type env struct {
+	name   string
+	getenv func(string) string
+	stdout io.Writer
+	stderr io.Writer
+
+	flags   *flag.FlagSet
+	global  config
+	config  string
+	profile string
+}
+
+// newFlags tạo FlagSet cho lệnh hiện tại, kèm các flag kết nối dùng chung.
+func (e *env) newFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("aiotctl "+e.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+
+	fs.StringVar(&e.global.Gateway, "gateway", "", "địa chỉ AIOT gateway (AIOT_GATEWAY)")
+	fs.StringVar(&e.global.Email, "email", "", "email đăng nhập (AIOT_EMAIL)")
+	fs.StringVar(&e.global.Password, "password", "", "password đăng nhập (AIOT_PASSWORD)")
+	fs.StringVar(&e.global.Token, "token", "", "token đã có, bỏ qua bước đăng nhập (AIOT_TOKEN)")
+	fs.StringVar(&e.config, "config", "", "file cấu hình (AIOT_CONFIG)")
+	fs.StringVar(&e.profile, "profile", "", "profile trong file cấu hình (AIOT_PROFILE)")
+
+	e.flags = fs
+	return fs
+}
+
+func (e *env) parse(args []string) error {
+	err := e.flags.Parse(args)
+	if err == flag.ErrHelp {
+		return errHelp
+	}
+	if err != nil {
+		return errUsage
+	}
+
+	return nil
+}
+
+// settings trả về profile từ file cấu hình, đã ghi đè bằng flag và biến môi
+// trường. Profile chọn bằng --profile được ưu tiên hơn biến môi trường, chỉ
+// flag kết nối mới ghi đè được nó.
+func (e *env) settings() (aiot.Profile, error) {
+	p, err := e.loadProfile()
+	if err != nil {
+		return aiot.Profile{}, err
+	}
+
+	s := e.global
+	if e.profile == "" {
+		merge(&s, config{
+			Gateway:  e.getenv("AIOT_GATEWAY"),
+			Email:    e.getenv("AIOT_EMAIL"),
+			Password: e.getenv("AIOT_PASSWORD"),
+			Token:    e.getenv("AIOT_TOKEN"),
+		})
+	}
+
+	if s.Gateway != "" {
+		p.Gateway = s.Gateway
+	}
+	if s.Email != "" {
+		p.Email = s.Email
+	}
+	if s.Password != "" {
+		p.Password = s.Password
+	}
+	if s.Token != "" {
+		p.Token = s.Token
+	}
+
+	if p.Gateway == "" {
+		return aiot.Profile{}, errors.New("missing gateway address, use --gateway, AIOT_GATEWAY or --profile")
+	}
+
+	return p, nil
+}
+
+func merge(dst *config, src config) {
+	if dst.Gateway == "" {
+		dst.Gateway = src.Gateway
+	}
+	if dst.Email == "" {
+		dst.Email = src.Email
+	}
+	if dst.Password == "" {
+		dst.Password = src.Password
+	}
+	if dst.Token == "" {
+		dst.Token = src.Token
+	}
+}
+
+// loadProfile đọc profile được chọn bằng --profile hoặc AIOT_PROFILE, hoặc
+// profile current của file cấu hình. Không có file cấu hình mặc định thì trả
+// về profile rỗng.
+func (e *env) loadProfile() (aiot.Profile, error) {
+	name := e.profile
+	if name == "" {
+		name = e.getenv("AIOT_PROFILE")
+	}
+
+	path := e.config
+	explicit := path != "" || name != "" || e.getenv("AIOT_CONFIG") != ""
+	if path == "" {
+		path = aiot.ConfigPathEnv(e.getenv)
+	}
+	if path == "" {
+		return aiot.Profile{}, nil
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && !explicit {
+		return aiot.Profile{}, nil
+	}
+
+	c, err := aiot.LoadConfig(path)
+	if err != nil {
+		return aiot.Profile{}, err
+	}
+
+	if name == "" && c.Current == "" {
+		if _, ok := c.Profiles[aiot.DefaultProfile]; !ok {
+			return aiot.Profile{}, nil
+		}
+	}
+
+	return c.Profile(name)
+}
+
+func (e *env) client() (aiot.Client, aiot.Profile, error) {
+	p, err := e.settings()
+	if err != nil {
+		return aiot.Client{}, aiot.Profile{}, err
+	}
+
+	client, err := p.NewClient()
+	if err != nil {
+		return aiot.Client{}, aiot.Profile{}, err
+	}
+
+	return client, p, nil
+}
+
+// session trả về client và token, đăng nhập bằng email/password nếu chưa có
+// token.
+func (e *env) session() (aiot.Client, string, error) {
+	p, err := e.settings()
+	if err != nil {
+		return aiot.Client{}, "", err
+	}
+
+	s, err := aiot.NewSession(p)
+	if err != nil {
+		return aiot.Client{}, "", err
+	}
+
+	token, err := s.Token()
+	if errors.Is(err, aiot.ErrMissingOrInvalidCredentials) {
+		return aiot.Client{}, "", errors.New("missing credentials, use --token or --email and --password")
+	}
+	if err != nil {
+		return aiot.Client{}, "", err
+	}
+
+	return s.Client, token, nil
+}
+
+// args kiểm tra số tham số vị trí sau khi đã parse flag.
+func (e *env) args(min, max int) ([]string, error) {
+	args := e.flags.Args()
+	if len(args) < min || max >= 0 && len(args) > max {
+		fmt.Fprintf(e.stderr, "aiotctl %s: wrong number of arguments\n", e.name)
+		e.flags.Usage()
+		return nil, errUsage
+	}
+
+	return args, nil
+}
+
+// metaFlag là flag --meta key=value với giá trị chuỗi, hoặc key:=<json> với
+// giá trị JSON (số, bool, object, ...), có thể lặp lại
+type metaFlag aiot.Metadata
+
+func (m metaFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			pairs = append(pairs, k+"="+s)
+			continue
+		}
+		data, _ := json.Marshal(v)
+		pairs = append(pairs, k+":="+string(data))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (m metaFlag) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || kv[0] == "" || kv[0] == ":" {
+		return fmt.Errorf("metadata must be key=value or key:=json: %q", s)
+	}
+
+	if strings.HasSuffix(kv[0], ":") {
+		var v interface{}
+		if err := json.Unmarshal([]byte(kv[1]), &v); err != nil {
+			return fmt.Errorf("metadata %q: invalid JSON value: %w", s, err)
+		}
+		m[strings.TrimSuffix(kv[0], ":")] = v
+		return nil
+	}
+
+	m[kv[0]] = kv[1]
+	return nil
+}
+
+// listFlag là flag nhận danh sách phân tách bằng dấu phẩy, có thể lặp lại
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+
+	return nil
+}
+
+type pageFlags struct {
+	offset int
+	limit  int
+	order  string
+	dir    string
+}
+
+func (p *pageFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&p.offset, "offset", 0, "vị trí bắt đầu")
+	fs.IntVar(&p.limit, "limit", 10, "số phần tử tối đa")
+	fs.StringVar(&p.order, "order", "name", "sắp xếp theo: name, key, id")
+	fs.StringVar(&p.dir, "dir", "desc", "chiều sắp xếp: asc, desc")
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+func cmdGatewayList(e *env, args []string) error {
+	var out outputFlags
+	out.register(e.newFlags())
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	gateways, err := client.ListGateway(token)
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, gateways)
+}
+
+func cmdGatewayGet(e *env, args []string) error {
+	var out outputFlags
+	out.register(e.newFlags())
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	g, err := client.GatewayProfile(token, ids[0])
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, g)
+}
+
+func cmdGatewayCreate(e *env, args []string) error {
+	fs := e.newFlags()
+	name := fs.String("name", "", "tên gateway")
+	desc := fs.String("desc", "", "mô tả")
+	thing := fs.String("thing", "", "id thing của gateway")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	if *name == "" || *thing == "" {
+		return errors.New("missing --name or --thing")
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	err = client.CreateGateway(token, aiot.CreateGatewayInput{
+		Name:        *name,
+		Description: *desc,
+		ThingID:     *thing,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "gateway created")
+	return nil
+}
+
+// cmdGatewayUpdate giữ nguyên tên và mô tả hiện tại nếu không có flag tương
+// ứng.
+func cmdGatewayUpdate(e *env, args []string) error {
+	fs := e.newFlags()
+	name := fs.String("name", "", "tên mới")
+	desc := fs.String("desc", "", "mô tả mới")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	g, err := client.GatewayProfile(token, ids[0])
+	if err != nil {
+		return err
+	}
+
+	in := aiot.UpdateGatewayInput{ID: g.ID, Name: g.Name, Description: g.Description, Version: g.Version}
+	if *name != "" {
+		in.Name = *name
+	}
+	if *desc != "" {
+		in.Description = *desc
+	}
+
+	if err := client.UpdateGateway(token, in); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "gateway updated")
+	return nil
+}
+
+func cmdGatewayDelete(e *env, args []string) error {
+	e.newFlags()
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, -1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := client.DeleteGateway(token, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "gateway %s deleted\n", id)
+	}
+
+	return nil
+}
+
+func cmdGatewayStatus(e *env, args []string) error {
+	var out outputFlags
+	out.register(e.newFlags())
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	status, err := client.GatewayStatus(token)
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, status)
+}
+
+func cmdGatewayDevices(e *env, args []string) error {
+	e.newFlags()
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	count, err := client.GatewayActiveDeviceCount(token, ids[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, count)
+	return nil
+}
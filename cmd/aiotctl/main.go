@@ -0,0 +1,136 @@
+// Command aiotctl thực hiện các thao tác với nền tảng AIOT từ dòng lệnh.
+//
+//	aiotctl <nhóm lệnh> <lệnh> [flags] [tham số]
+//
+// Thông tin kết nối được lấy theo thứ tự ưu tiên: flag, biến môi trường
+// (AIOT_GATEWAY, AIOT_EMAIL, AIOT_PASSWORD, AIOT_TOKEN) và profile trong file
+// cấu hình (mặc định ~/.config/aiot/config.yaml, đổi bằng --config hoặc
+// AIOT_CONFIG). Profile được chọn bằng --profile hoặc AIOT_PROFILE, mặc định
+// là profile current của file cấu hình; xem aiot.Config.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(e *env, args []string) error
+}
+
+var commands = []command{
+	{"login", "đăng nhập và in ra token", cmdLogin},
+	{"token verify", "kiểm tra tính hợp lệ của token", cmdTokenVerify},
+	{"password reset", "thay đổi password", cmdPasswordReset},
+	{"user profile", "xem thông tin người dùng", cmdUserProfile},
+
+	{"thing list", "liệt kê thing", cmdThingList},
+	{"thing get", "xem thông tin thing: thing get <id>", cmdThingGet},
+	{"thing create", "tạo thing", cmdThingCreate},
+	{"thing update", "sửa thing: thing update <id>", cmdThingUpdate},
+	{"thing delete", "xóa thing: thing delete <id>...", cmdThingDelete},
+	{"thing channels", "liệt kê channel theo trạng thái kết nối: thing channels <id>", cmdThingChannels},
+
+	{"channel list", "liệt kê channel", cmdChannelList},
+	{"channel get", "xem thông tin channel: channel get <id>", cmdChannelGet},
+	{"channel create", "tạo channel", cmdChannelCreate},
+	{"channel update", "sửa channel: channel update <id>", cmdChannelUpdate},
+	{"channel delete", "xóa channel: channel delete <id>...", cmdChannelDelete},
+
+	{"connect", "kết nối thing với channel", cmdConnect},
+	{"disconnect", "ngắt kết nối thing với channel", cmdDisconnect},
+
+	{"gateway list", "liệt kê gateway", cmdGatewayList},
+	{"gateway get", "xem thông tin gateway: gateway get <id>", cmdGatewayGet},
+	{"gateway create", "tạo gateway", cmdGatewayCreate},
+	{"gateway update", "sửa gateway: gateway update <id>", cmdGatewayUpdate},
+	{"gateway delete", "xóa gateway: gateway delete <id>...", cmdGatewayDelete},
+	{"gateway status", "xem trạng thái các gateway", cmdGatewayStatus},
+	{"gateway devices", "số thiết bị đang online: gateway devices <id>", cmdGatewayDevices},
+
+	{"plan", "so sánh manifest với trạng thái hiện tại: plan -f <file>", cmdPlan},
+	{"apply", "đưa trạng thái hiện tại về manifest: apply -f <file>", cmdApply},
+
+	{"export", "xuất toàn bộ dữ liệu ra archive JSON", cmdExport},
+	{"import", "nhập archive vào tài khoản: import -f <file>", cmdImport},
+
+	{"topology", "đồ thị thing, channel và gateway", cmdTopology},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stdout, os.Stderr))
+}
+
+func run(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	cmd, rest, ok := findCommand(args)
+	if !ok {
+		usage(stderr)
+		return 2
+	}
+
+	e := &env{name: cmd.name, getenv: getenv, stdout: stdout, stderr: stderr}
+
+	if err := cmd.run(e, rest); err != nil {
+		switch err {
+		case errHelp:
+			return 0
+		case errUsage:
+			return 2
+		}
+
+		fmt.Fprintf(stderr, "aiotctl %s: %v\n", cmd.name, err)
+		return 1
+	}
+
+	return 0
+}
+
+// findCommand tìm lệnh dài nhất khớp với các tham số đầu tiên.
+func findCommand(args []string) (command, []string, bool) {
+	var best command
+	n := 0
+
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(words) <= n || len(words) > len(args) {
+			continue
+		}
+
+		match := true
+		for i, w := range words {
+			if args[i] != w {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			best, n = cmd, len(words)
+		}
+	}
+
+	return best, args[n:], n > 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Cách dùng: aiotctl <lệnh> [flags] [tham số]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Các lệnh:")
+
+	cmds := append([]command(nil), commands...)
+	sort.SliceStable(cmds, func(i, j int) bool {
+		return strings.Fields(cmds[i].name)[0] < strings.Fields(cmds[j].name)[0]
+	})
+
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.name, cmd.usage)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Dùng \"aiotctl <lệnh> -h\" để xem các flag của một lệnh.")
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	email    = "ops@aiot.vn"
+	password = "secret"
+)
+
+type harness struct {
+	t      *testing.T
+	srv    *aiottest.Server
+	client aiot.Client
+	token  string
+	env    map[string]string
+}
+
+func newHarness(t *testing.T) *harness {
+	srv := aiottest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddUser(aiottest.User{Email: email, Password: password, Fullname: "Ops"})
+
+	client := aiot.NewClient(srv.URL)
+	token, err := client.Token(email, password)
+	require.NoError(t, err)
+
+	return &harness{
+		t:      t,
+		srv:    srv,
+		client: client,
+		token:  token,
+		env: map[string]string{
+			"AIOT_GATEWAY":    srv.URL,
+			"AIOT_EMAIL":      email,
+			"AIOT_PASSWORD":   password,
+			"XDG_CONFIG_HOME": t.TempDir(),
+		},
+	}
+}
+
+func (h *harness) run(args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, func(k string) string { return h.env[k] }, &stdout, &stderr)
+
+	return stdout.String(), stderr.String(), code
+}
+
+func (h *harness) mustRun(args ...string) string {
+	stdout, stderr, code := h.run(args...)
+	require.Equal(h.t, 0, code, stderr)
+
+	return stdout
+}
+
+func Test_Login(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	token := strings.TrimSpace(h.mustRun("login"))
+	require.NotEmpty(token)
+
+	h.env = map[string]string{"AIOT_GATEWAY": h.srv.URL}
+	require.Equal("true\n", h.mustRun("token", "verify", "--token", token))
+
+	_, stderr, code := h.run("user", "profile")
+	require.Equal(1, code)
+	require.Contains(stderr, "missing credentials")
+}
+
+func Test_ConfigFile(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "aiot", "config.yaml")
+	require.NoError(os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(os.WriteFile(path, []byte(`
+current: dev
+profiles:
+  dev:
+    gateway: `+h.srv.URL+`
+    email: `+email+`
+    password: `+password+`
+  broken:
+    gateway: `+h.srv.URL+`
+    email: `+email+`
+    password: wrong
+`), 0o600))
+
+	h.env = map[string]string{"XDG_CONFIG_HOME": dir}
+
+	out := h.mustRun("user", "profile")
+	require.Contains(out, email)
+
+	// flag được ưu tiên hơn file cấu hình
+	_, stderr, code := h.run("user", "profile", "--password", "wrong")
+	require.Equal(1, code)
+	require.Contains(stderr, "invalid email or password")
+
+	_, stderr, code = h.run("user", "profile", "--profile", "broken")
+	require.Equal(1, code)
+	require.Contains(stderr, "invalid email or password")
+
+	h.env["AIOT_PROFILE"] = "broken"
+	require.Contains(h.mustRun("user", "profile", "--password", password), email)
+
+	// --profile được ưu tiên hơn biến môi trường
+	h.env = map[string]string{"XDG_CONFIG_HOME": dir, "AIOT_GATEWAY": "http://127.0.0.1:1", "AIOT_PASSWORD": "wrong"}
+	require.Contains(h.mustRun("user", "profile", "--profile", "dev"), email)
+
+	_, _, code = h.run("user", "profile")
+	require.Equal(1, code)
+
+	_, stderr, code = h.run("user", "profile", "--profile", "prod")
+	require.Equal(1, code)
+	require.Contains(stderr, `profile "prod" not found`)
+}
+
+func Test_ThingChannelLifecycle(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	h.mustRun("thing", "create", "--name", "sensor-1", "--meta", "floor=1", "--meta", `location:={"lat":10.75}`)
+	h.mustRun("channel", "create", "--name", "telemetry")
+
+	things, _, err := h.client.ListThingsByUser(h.token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Len(things, 1)
+	require.Equal(aiot.Metadata{"floor": "1", "location": map[string]interface{}{"lat": 10.75}}, things[0].Metadata)
+
+	channels, _, err := h.client.ListChannelByUser(h.token, aiot.NewListChannelByUserOptions())
+	require.NoError(err)
+	require.Len(channels, 1)
+
+	out := h.mustRun("thing", "list")
+	require.Contains(out, "sensor-1")
+	require.Contains(out, "METADATA.FLOOR")
+
+	h.mustRun("thing", "update", "--name", "sensor-2", things[0].ID)
+	out = h.mustRun("thing", "get", things[0].ID)
+	require.Contains(out, "sensor-2")
+	require.Regexp(`metadata.floor:\s+1`, out)
+
+	h.mustRun("connect", "--channel", channels[0].ID, "--thing", things[0].ID)
+	out = h.mustRun("thing", "channels", things[0].ID)
+	require.Contains(out, "telemetry")
+
+	h.mustRun("disconnect", "--channel", channels[0].ID, "--thing", things[0].ID)
+	out = h.mustRun("thing", "channels", things[0].ID)
+	require.NotContains(out, "telemetry")
+
+	h.mustRun("channel", "delete", channels[0].ID)
+	h.mustRun("thing", "delete", things[0].ID)
+
+	_, total, err := h.client.ListThingsByUser(h.token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Equal(0, total)
+}
+
+func Test_Output(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	require.NoError(h.client.CreateThing(h.token, aiot.CreateThingInput{Name: "a", Metadata: aiot.Metadata{"floor": "1"}}))
+	require.NoError(h.client.CreateThing(h.token, aiot.CreateThingInput{Name: "b"}))
+
+	out := h.mustRun("thing", "list", "-o", "json", "--columns", "name,metadata.floor", "--dir", "asc")
+	require.JSONEq(`{"total": 2, "items": [{"name": "a", "metadata.floor": "1"}, {"name": "b", "metadata.floor": null}]}`, out)
+
+	out = h.mustRun("thing", "list", "--output", "csv", "--columns", "name,metadata.floor", "--dir", "asc")
+	require.Equal("name,metadata.floor\na,1\nb,\n", out)
+
+	out = h.mustRun("thing", "list", "-o", "template={{.Name}}", "--dir", "asc")
+	require.Equal("a\nb\n", out)
+
+	_, stderr, code := h.run("thing", "list", "-o", "xml")
+	require.Equal(2, code)
+	require.Contains(stderr, "unknown output format")
+}
+
+func Test_Gateway(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	require.NoError(h.client.CreateThing(h.token, aiot.CreateThingInput{Name: "gw-thing"}))
+	things, _, err := h.client.ListThingsByUser(h.token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+
+	h.mustRun("gateway", "create", "--name", "gw-1", "--desc", "floor 1", "--thing", things[0].ID)
+
+	gateways, err := h.client.ListGateway(h.token)
+	require.NoError(err)
+	require.Len(gateways, 1)
+
+	h.srv.SetGatewayOnline(gateways[0].ID, true)
+	h.srv.SetActiveDeviceCount(gateways[0].ID, 3)
+
+	require.Contains(h.mustRun("gateway", "list"), "gw-1")
+	require.Equal(gateways[0].ID+",true\n", h.mustRun("gateway", "status", "-o", "csv", "--no-header"))
+	require.Equal("3\n", h.mustRun("gateway", "devices", gateways[0].ID))
+
+	h.mustRun("gateway", "update", "--desc", "floor 2", gateways[0].ID)
+	out := h.mustRun("gateway", "get", gateways[0].ID)
+	require.Contains(out, "gw-1")
+	require.Contains(out, "floor 2")
+
+	h.mustRun("gateway", "delete", gateways[0].ID)
+	gateways, err = h.client.ListGateway(h.token)
+	require.NoError(err)
+	require.Empty(gateways)
+}
+
+func Test_PlanApply(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	require.NoError(os.WriteFile(path, []byte(`
+things:
+  - name: sensor-1
+    channels: [telemetry]
+channels:
+  - name: telemetry
+`), 0o600))
+
+	out := h.mustRun("plan", "-f", path)
+	require.Contains(out, "+ thing sensor-1")
+	require.Contains(out, "2 to create")
+
+	out = h.mustRun("apply", "-f", path)
+	require.Contains(out, "+ connect thing sensor-1 -> channel telemetry")
+	require.Contains(out, "3 changes applied")
+
+	require.Equal("no changes\n", h.mustRun("plan", "-f", path))
+
+	_, stderr, code := h.run("plan")
+	require.Equal(1, code)
+	require.Contains(stderr, "missing -f")
+}
+
+func Test_ExportImport(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	require.NoError(h.client.CreateThing(h.token, aiot.CreateThingInput{Name: "sensor-1"}))
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.json")
+	report := filepath.Join(dir, "report.json")
+
+	require.Contains(h.mustRun("export", "-f", archive), "exported 1 things")
+
+	target := newHarness(t)
+	target.mustRun("import", "-f", archive, "--report", report)
+
+	things, _, err := target.client.ListThingsByUser(target.token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Len(things, 1)
+
+	data, err := os.ReadFile(report)
+	require.NoError(err)
+	require.Contains(string(data), things[0].ID)
+
+	_, stderr, code := target.run("import", "-f", archive)
+	require.Equal(1, code)
+	require.Contains(stderr, `thing "sensor-1" already exists`)
+
+	target.mustRun("import", "-f", archive, "--on-conflict", "reuse")
+}
+
+func Test_Topology(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	require.NoError(h.client.CreateThing(h.token, aiot.CreateThingInput{Name: "sensor-1"}))
+	require.NoError(h.client.CreateChannel(h.token, aiot.CreateChannelInput{Name: "telemetry"}))
+
+	out := h.mustRun("topology")
+	require.Contains(out, "things: 1, channels: 1, gateways: 0, connections: 0")
+	require.Contains(out, "telemetry")
+
+	require.Contains(h.mustRun("topology", "--format", "dot"), "digraph aiot {")
+	require.Contains(h.mustRun("topology", "--format", "mermaid"), `t0("sensor-1")`)
+}
+
+func Test_Usage(t *testing.T) {
+	require := require.New(t)
+	h := newHarness(t)
+
+	_, stderr, code := h.run("thing")
+	require.Equal(2, code)
+	require.Contains(stderr, "thing list")
+
+	_, _, code = h.run("thing", "get")
+	require.Equal(2, code)
+
+	_, _, code = h.run("thing", "list", "-h")
+	require.Equal(0, code)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"github.com/mobifone-aiot/aiot-go/render"
+)
+
+// formatFlag là flag --output, được kiểm tra ngay khi parse
+type formatFlag struct {
+	value string
+	opts  *render.Options
+}
+
+func (f *formatFlag) String() string {
+	return f.value
+}
+
+func (f *formatFlag) Set(s string) error {
+	opts, err := render.ParseOptions(s)
+	if err != nil {
+		return err
+	}
+
+	f.value, f.opts = s, opts
+	return nil
+}
+
+// outputFlags là các flag chọn định dạng đầu ra của những lệnh in dữ liệu
+type outputFlags struct {
+	format   formatFlag
+	columns  listFlag
+	noHeader bool
+}
+
+func (o *outputFlags) register(fs *flag.FlagSet) {
+	o.format = formatFlag{value: "table", opts: render.NewOptions()}
+
+	fs.Var(&o.format, "output", "định dạng đầu ra: table, json, yaml, csv, template=<go template>")
+	fs.Var(&o.format, "o", "viết tắt của --output")
+	fs.Var(&o.columns, "columns", "các cột cần in, ví dụ id,name,metadata.floor")
+	fs.BoolVar(&o.noHeader, "no-header", false, "không in dòng tiêu đề (table, csv)")
+}
+
+func (o *outputFlags) render(w io.Writer, v interface{}) error {
+	opts := o.format.opts.SetColumns(o.columns...).SetNoHeader(o.noHeader)
+
+	return render.Render(w, v, opts)
+}
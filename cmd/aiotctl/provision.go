@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/mobifone-aiot/aiot-go/provision"
+)
+
+type manifestFlags struct {
+	file  string
+	prune bool
+}
+
+func (m *manifestFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&m.file, "f", "", "file manifest (YAML hoặc JSON)")
+	fs.BoolVar(&m.prune, "prune", false, "xóa thing, channel, gateway không có trong manifest")
+}
+
+func (m *manifestFlags) load() (provision.Manifest, error) {
+	if m.file == "" {
+		return provision.Manifest{}, errors.New("missing -f")
+	}
+
+	return provision.LoadManifest(m.file)
+}
+
+func cmdPlan(e *env, args []string) error {
+	fs := e.newFlags()
+	var m manifestFlags
+	m.register(fs)
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	manifest, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	r := provision.NewReconciler(client, token, provision.NewOptions().SetPrune(m.prune))
+	plan, err := r.Plan(manifest)
+	if err != nil {
+		return err
+	}
+
+	printPlan(e, plan)
+	return nil
+}
+
+func cmdApply(e *env, args []string) error {
+	fs := e.newFlags()
+	var m manifestFlags
+	m.register(fs)
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	manifest, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	opts := provision.NewOptions().
+		SetPrune(m.prune).
+		SetOnApply(func(a provision.Action) {
+			fmt.Fprintln(e.stdout, a)
+		})
+
+	r := provision.NewReconciler(client, token, opts)
+	plan, err := r.Plan(manifest)
+	if err != nil {
+		return err
+	}
+
+	if plan.Empty() {
+		fmt.Fprintln(e.stdout, "no changes")
+		return nil
+	}
+
+	if err := r.Apply(plan); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "\n%d changes applied\n", len(plan.Actions))
+	return nil
+}
+
+func printPlan(e *env, plan provision.Plan) {
+	if plan.Empty() {
+		fmt.Fprintln(e.stdout, "no changes")
+		return
+	}
+
+	fmt.Fprint(e.stdout, plan)
+	fmt.Fprintf(e.stdout, "\n%d to create, %d to update, %d to delete, %d to connect, %d to disconnect\n",
+		plan.Count(provision.OP_CREATE),
+		plan.Count(provision.OP_UPDATE),
+		plan.Count(provision.OP_DELETE),
+		plan.Count(provision.OP_CONNECT),
+		plan.Count(provision.OP_DISCONNECT),
+	)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/render"
+)
+
+func cmdThingList(e *env, args []string) error {
+	fs := e.newFlags()
+	var out outputFlags
+	out.register(fs)
+	var p pageFlags
+	p.register(fs)
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	opts := aiot.NewListThingsByUserOptions().
+		SetOffset(p.offset).
+		SetLimit(p.limit).
+		SetOrder(aiot.ThingOrder(p.order)).
+		SetDirection(aiot.Direction(p.dir))
+
+	things, total, err := client.ListThingsByUser(token, opts)
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, render.List{Items: things, Total: total})
+}
+
+func cmdThingGet(e *env, args []string) error {
+	var out outputFlags
+	out.register(e.newFlags())
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	t, err := client.ThingProfile(token, ids[0])
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, t)
+}
+
+func cmdThingCreate(e *env, args []string) error {
+	fs := e.newFlags()
+	name := fs.String("name", "", "tên thing")
+	meta := metaFlag{}
+	fs.Var(meta, "meta", "metadata dạng key=value hoặc key:=<json>, có thể lặp lại")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return errors.New("missing --name")
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	err = client.CreateThing(token, aiot.CreateThingInput{
+		Name:     *name,
+		Metadata: aiot.Metadata(meta),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "thing created")
+	return nil
+}
+
+// cmdThingUpdate giữ nguyên tên và metadata hiện tại nếu không có --name
+// hoặc --meta.
+func cmdThingUpdate(e *env, args []string) error {
+	fs := e.newFlags()
+	name := fs.String("name", "", "tên mới")
+	meta := metaFlag{}
+	fs.Var(meta, "meta", "metadata mới dạng key=value hoặc key:=<json>, có thể lặp lại")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	t, err := client.ThingProfile(token, ids[0])
+	if err != nil {
+		return err
+	}
+
+	in := aiot.UpdateThingInput{ID: t.ID, Name: t.Name, Metadata: t.Metadata, Version: t.Version}
+	if *name != "" {
+		in.Name = *name
+	}
+	if len(meta) > 0 {
+		in.Metadata = aiot.Metadata(meta)
+	}
+
+	if err := client.UpdateThing(token, in); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "thing updated")
+	return nil
+}
+
+func cmdThingDelete(e *env, args []string) error {
+	e.newFlags()
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, -1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := client.DeleteThing(token, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "thing %s deleted\n", id)
+	}
+
+	return nil
+}
+
+func cmdThingChannels(e *env, args []string) error {
+	fs := e.newFlags()
+	var out outputFlags
+	out.register(fs)
+	var p pageFlags
+	p.register(fs)
+	disconnected := fs.Bool("disconnected", true, "giá trị tham số disconnected gửi lên gateway")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	ids, err := e.args(1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	opts := aiot.NewListChannelByThingOptions().
+		SetOffset(p.offset).
+		SetLimit(p.limit).
+		SetOrder(aiot.ThingOrder(p.order)).
+		SetDirection(aiot.Direction(p.dir)).
+		SetDisconnected(*disconnected)
+
+	channels, total, err := client.ListChannelByThing(token, ids[0], opts)
+	if err != nil {
+		return err
+	}
+
+	return out.render(e.stdout, render.List{Items: channels, Total: total})
+}
+
+func cmdConnect(e *env, args []string) error {
+	fs := e.newFlags()
+	var channels, things listFlag
+	fs.Var(&channels, "channel", "id channel, phân tách bằng dấu phẩy hoặc lặp lại")
+	fs.Var(&things, "thing", "id thing, phân tách bằng dấu phẩy hoặc lặp lại")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	if len(channels) == 0 || len(things) == 0 {
+		return errors.New("missing --channel or --thing")
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Connect(token, channels, things); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "connected")
+	return nil
+}
+
+func cmdDisconnect(e *env, args []string) error {
+	fs := e.newFlags()
+	channel := fs.String("channel", "", "id channel")
+	thing := fs.String("thing", "", "id thing")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	if *channel == "" || *thing == "" {
+		return errors.New("missing --channel or --thing")
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Disconnect(token, *channel, *thing); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.stdout, "disconnected")
+	return nil
+}
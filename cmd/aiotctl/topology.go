@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/mobifone-aiot/aiot-go/topology"
+)
+
+func cmdTopology(e *env, args []string) error {
+	fs := e.newFlags()
+	format := fs.String("format", "summary", "định dạng: summary, dot, mermaid, json")
+	if err := e.parse(args); err != nil {
+		return err
+	}
+	if _, err := e.args(0, 0); err != nil {
+		return err
+	}
+
+	var write func(*topology.Topology, io.Writer) error
+	switch *format {
+	case "summary":
+		write = writeSummary
+	case "dot":
+		write = (*topology.Topology).WriteDOT
+	case "mermaid":
+		write = (*topology.Topology).WriteMermaid
+	case "json":
+		write = (*topology.Topology).WriteJSON
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	client, token, err := e.session()
+	if err != nil {
+		return err
+	}
+
+	topo, err := topology.Build(client, token)
+	if err != nil {
+		return err
+	}
+
+	return write(topo, e.stdout)
+}
+
+func writeSummary(topo *topology.Topology, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "things: %d, channels: %d, gateways: %d, connections: %d\n",
+		len(topo.Things()), len(topo.Channels()), len(topo.Gateways()), len(topo.Edges())-len(topo.Gateways()))
+
+	fmt.Fprintln(tw, "\norphan channels:")
+	for _, n := range topo.OrphanChannels() {
+		fmt.Fprintf(tw, "  %s\t%s\n", n.Name, n.ID)
+	}
+
+	fmt.Fprintln(tw, "\nthings without channels:")
+	for _, n := range topo.ThingsWithoutChannels() {
+		fmt.Fprintf(tw, "  %s\t%s\n", n.Name, n.ID)
+	}
+
+	fmt.Fprintln(tw, "\nfan-out:")
+	for _, f := range topo.FanOut() {
+		fmt.Fprintf(tw, "  %s\t%s\t%d\n", f.Channel.Name, f.Channel.ID, f.Things)
+	}
+
+	return tw.Flush()
+}
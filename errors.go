@@ -12,12 +12,42 @@ var (
 )
 
 type operation string
-type kind uint8
+
+// Kind phân loại lỗi trả về từ Client, kiểm tra bằng Is
+type Kind uint8
+
+const (
+	// Lỗi không được phân loại
+	KIND_OTHER Kind = iota
+	// Request bị từ chối bởi giới hạn tốc độ phía client
+	KIND_RATE_LIMITED
+	// Request bị từ chối vì circuit breaker đang mở
+	KIND_CIRCUIT_OPEN
+	// Đối tượng đã bị thay đổi so với Version của lời gọi cập nhật
+	KIND_CONFLICT
+	// Input không hợp lệ, request không được gửi
+	KIND_INVALID
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KIND_RATE_LIMITED:
+		return "rate limited"
+	case KIND_CIRCUIT_OPEN:
+		return "circuit open"
+	case KIND_CONFLICT:
+		return "conflict"
+	case KIND_INVALID:
+		return "invalid"
+	}
+
+	return "other"
+}
 
 type aiotError struct {
 	Op   operation
 	Err  error
-	Kind kind
+	Kind Kind
 }
 
 func (e *aiotError) Error() string {
@@ -36,6 +66,10 @@ func (e *aiotError) Error() string {
 	return buf.String()
 }
 
+func (e *aiotError) Unwrap() error {
+	return e.Err
+}
+
 func makeE(args ...interface{}) error {
 	e := &aiotError{}
 	for _, arg := range args {
@@ -44,7 +78,7 @@ func makeE(args ...interface{}) error {
 			e.Op = arg
 		case error:
 			e.Err = arg
-		case kind:
+		case Kind:
 			e.Kind = arg
 		default:
 			panic("bad call to E")
@@ -52,3 +86,24 @@ func makeE(args ...interface{}) error {
 	}
 	return e
 }
+
+// Is báo err có thuộc loại kind hay không. Lỗi được bọc nhiều lớp mang loại
+// của lớp trong cùng có loại khác KIND_OTHER.
+func Is(kind Kind, err error) bool {
+	return kindOf(err) == kind
+}
+
+func kindOf(err error) Kind {
+	var e *aiotError
+	if !errors.As(err, &e) {
+		return KIND_OTHER
+	}
+	if e.Kind != KIND_OTHER {
+		return e.Kind
+	}
+	if e.Err != nil {
+		return kindOf(e.Err)
+	}
+
+	return KIND_OTHER
+}
@@ -0,0 +1,9 @@
+package aiot
+
+import "time"
+
+// SetNow thay đồng hồ của client, chỉ dùng trong test
+func (opts *ClientOptions) SetNow(now func() time.Time) *ClientOptions {
+	opts.now = now
+	return opts
+}
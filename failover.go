@@ -0,0 +1,144 @@
+package aiot
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EndpointStatus là trạng thái của một địa chỉ gateway
+type EndpointStatus struct {
+	Addr    string
+	Healthy bool
+	// Thời điểm địa chỉ bị đánh dấu lỗi, rỗng nếu Healthy
+	DownSince time.Time
+}
+
+// endpoints chọn địa chỉ gateway theo thứ tự ưu tiên: địa chỉ đầu tiên còn
+// hoạt động luôn được dùng trước. Địa chỉ bị lỗi chỉ được thử lại sau
+// probeInterval bằng một request idempotent, hoặc khi không còn địa chỉ nào
+// khác.
+type endpoints struct {
+	addrs         []string
+	probeInterval time.Duration
+	now           func() time.Time
+
+	mu     sync.Mutex
+	down   []time.Time
+	probed []time.Time
+}
+
+func newEndpoints(primary string, opts *ClientOptions) *endpoints {
+	if len(opts.failoverAddrs) == 0 {
+		return nil
+	}
+
+	addrs := append([]string{primary}, opts.failoverAddrs...)
+
+	return &endpoints{
+		addrs:         addrs,
+		probeInterval: opts.probeInterval,
+		now:           opts.now,
+		down:          make([]time.Time, len(addrs)),
+		probed:        make([]time.Time, len(addrs)),
+	}
+}
+
+// order trả về các địa chỉ theo thứ tự sẽ thử cho một request: địa chỉ còn
+// hoạt động và địa chỉ lỗi đã đến lúc thử lại theo thứ tự ưu tiên, sau đó
+// là các địa chỉ lỗi còn lại. Chỉ request idempotent được dùng để thử lại
+// địa chỉ lỗi, vì request khác gặp 5xx sẽ không được gửi sang địa chỉ dự
+// phòng.
+func (e *endpoints) order(method string) []string {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	probe := idempotent(method)
+	out := make([]string, 0, len(e.addrs))
+	var rest []string
+
+	for i, addr := range e.addrs {
+		switch {
+		case e.down[i].IsZero():
+			out = append(out, addr)
+		case probe && now.Sub(e.probed[i]) >= e.probeInterval:
+			// chỉ một request thử lại địa chỉ lỗi trong mỗi chu kỳ
+			e.probed[i] = now
+			out = append(out, addr)
+		default:
+			rest = append(rest, addr)
+		}
+	}
+
+	return append(out, rest...)
+}
+
+// observe đánh dấu địa chỉ lỗi hoặc hoạt động lại theo kết quả request
+func (e *endpoints) observe(addr string, r result) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range e.addrs {
+		if e.addrs[i] != addr {
+			continue
+		}
+
+		switch {
+		case r == resultSuccess:
+			e.down[i] = time.Time{}
+		case e.down[i].IsZero():
+			now := e.now()
+			e.down[i] = now
+			e.probed[i] = now
+		}
+		return
+	}
+}
+
+func (e *endpoints) status() []EndpointStatus {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]EndpointStatus, len(e.addrs))
+	for i, addr := range e.addrs {
+		out[i] = EndpointStatus{Addr: addr, Healthy: e.down[i].IsZero(), DownSince: e.down[i]}
+	}
+
+	return out
+}
+
+// failover báo có nên thử địa chỉ tiếp theo không. Request chưa đến được
+// gateway (lỗi kết nối) luôn được thử lại; lỗi khác và 5xx chỉ được thử lại
+// với method idempotent.
+func failover(method string, resp *http.Response, err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	return idempotent(method) && outcome(resp, err) == resultFailure
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+package aiot_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/stretchr/testify/require"
+)
+
+// regional là gateway chuyển tiếp request đến srv, hoặc trả về 503 khi down
+type regional struct {
+	*httptest.Server
+	down int32
+	hits int32
+}
+
+func newRegional(t *testing.T, srv *aiottest.Server) *regional {
+	target, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	r := &regional{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&r.hits, 1)
+		if atomic.LoadInt32(&r.down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"errorCode": "503", "errorMessage": "unavailable"}`))
+			return
+		}
+		proxy.ServeHTTP(w, req)
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func (r *regional) setDown(down bool) {
+	var v int32
+	if down {
+		v = 1
+	}
+	atomic.StoreInt32(&r.down, v)
+}
+
+func (r *regional) Hits() int {
+	return int(atomic.LoadInt32(&r.hits))
+}
+
+func Test_FailoverOnConnectionError(t *testing.T) {
+	require := require.New(t)
+
+	srv, _ := aiottest.NewAccountServer(t)
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	client := aiot.NewClientWithOptions(dead.URL, aiot.NewClientOptions().
+		SetFailoverAddrs(srv.URL))
+
+	// request chưa đến được gateway thì chuyển địa chỉ kể cả với POST
+	token, err := client.Token(aiottest.AccountEmail, aiottest.AccountPassword)
+	require.NoError(err)
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+
+	status := client.Endpoints()
+	require.Len(status, 2)
+	require.False(status[0].Healthy)
+	require.False(status[0].DownSince.IsZero())
+	require.True(status[1].Healthy)
+}
+
+// clock là đồng hồ giả cho các test phụ thuộc thời gian
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func Test_FailoverOn5xx(t *testing.T) {
+	require := require.New(t)
+
+	srv, token := aiottest.NewAccountServer(t)
+
+	clk := &clock{now: time.Unix(0, 0)}
+	primary := newRegional(t, srv)
+	client := aiot.NewClientWithOptions(primary.URL, aiot.NewClientOptions().
+		SetFailoverAddrs(srv.URL).
+		SetProbeInterval(time.Minute).
+		SetNow(clk.Now))
+
+	// POST không idempotent nên không gửi lại sang địa chỉ khác
+	primary.setDown(true)
+	err := client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"})
+	require.Error(err)
+	require.Equal(1, primary.Hits())
+
+	// địa chỉ chính bị lỗi, dùng địa chỉ dự phòng cho đến lần thử lại
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	require.Equal(1, primary.Hits())
+
+	// đến lúc thử lại nhưng POST không được dùng để thử địa chỉ lỗi
+	clk.Add(time.Minute)
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	require.Equal(1, primary.Hits())
+
+	// lần thử lại bằng GET vẫn lỗi, GET được gửi lại sang địa chỉ dự phòng
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+	require.Equal(2, primary.Hits())
+	require.False(client.Endpoints()[0].Healthy)
+
+	// chỉ một request thử lại trong mỗi chu kỳ
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+	require.Equal(2, primary.Hits())
+
+	// địa chỉ chính hoạt động lại thì được dùng lại
+	primary.setDown(false)
+	clk.Add(time.Minute)
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+	require.True(client.Endpoints()[0].Healthy)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	require.Equal(4, primary.Hits())
+}
@@ -3,52 +3,140 @@ package aiot
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 )
 
 func (c Client) httpDo(r request) (*http.Response, error) {
 	const op operation = "aiot.httpDo"
 
-	body, err := json.Marshal(r.Body)
-	if err != nil {
-		return nil, makeE(op, err)
-	}
-
-	req, err := http.NewRequest(r.Method, c.makeUrl(r.Path), bytes.NewBuffer(body))
-	if err != nil {
-		return nil, makeE(op, err)
+	call := &Call{
+		Context:    c.context(),
+		Op:         string(r.Op),
+		Method:     r.Method,
+		Path:       r.Path,
+		Token:      r.Token,
+		Body:       r.Body,
+		Header:     make(http.Header),
+		Attributes: r.Attrs,
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	if r.Token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.Token))
+	for k, v := range r.Header {
+		call.Header[k] = append([]string(nil), v...)
 	}
 
-	client := http.Client{}
-
-	resp, err := client.Do(req)
+	res, err := intercept(c.interceptors, c.do)(call)
 	if err != nil {
 		return nil, makeE(op, err)
 	}
 
-	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+	if res.StatusCode != 200 && res.StatusCode != 201 {
 		var e struct {
 			ErrorCode    string `json:"errorCode"`
 			ErrorMessage string `json:"errorMessage"`
 		}
 
-		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		if err := json.Unmarshal(res.Body, &e); err != nil {
 			return nil, makeE(op, err)
 		}
 
+		if res.StatusCode == http.StatusPreconditionFailed {
+			return nil, makeE(op, KIND_CONFLICT, fmt.Errorf("%w: [code] %s [message] %s", ErrConflict, e.ErrorCode, e.ErrorMessage))
+		}
+
 		return nil, makeE(op, fmt.Errorf("[code] %s [message] %s", e.ErrorCode, e.ErrorMessage))
 	}
 
-	return resp, nil
+	return &http.Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       io.NopCloser(bytes.NewReader(res.Body)),
+	}, nil
+}
+
+// do là Handler cuối cùng của chuỗi interceptor, gửi call đến gateway
+func (c Client) do(call *Call) (*Response, error) {
+	body, err := json.Marshal(call.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := c.breaker.allow()
+	if err != nil {
+		return nil, makeE(KIND_CIRCUIT_OPEN, err)
+	}
+
+	release, err := c.limiter.acquire(call.Context, call.Path)
+	if errors.Is(err, ErrRateLimited) {
+		done(resultSkipped)
+		return nil, makeE(KIND_RATE_LIMITED, err)
+	}
+	if err != nil {
+		done(resultSkipped)
+		return nil, err
+	}
+	defer release()
+
+	resp, err := c.send(call, body)
+	done(outcome(resp, err))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	c.limiter.observe(call.Path, resp)
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data}, nil
 }
 
-func (c Client) makeUrl(path string) string {
-	return c.gatewayAddr + path
+// send gửi request đến gateway, lần lượt thử các địa chỉ dự phòng (nếu có)
+// khi không kết nối được hoặc khi request idempotent gặp lỗi 5xx.
+func (c Client) send(call *Call, body []byte) (*http.Response, error) {
+	client := c.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	addrs := c.endpoints.order(call.Method)
+	if len(addrs) == 0 {
+		addrs = []string{c.gatewayAddr}
+	}
+
+	for i, addr := range addrs {
+		req, err := http.NewRequestWithContext(call.Context, call.Method, addr+call.Path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range call.Header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		if call.Token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", call.Token))
+		}
+
+		resp, err := client.Do(req)
+		c.endpoints.observe(addr, outcome(resp, err))
+
+		if i == len(addrs)-1 || !failover(call.Method, resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if c.metrics != nil {
+			c.metrics.Retried(call.Op)
+		}
+	}
+
+	panic("unreachable")
 }
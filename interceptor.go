@@ -0,0 +1,54 @@
+package aiot
+
+import (
+	"context"
+	"net/http"
+)
+
+// Call là một lời gọi API đi qua chuỗi interceptor. Interceptor có thể sửa
+// các trường trước khi gọi tiếp.
+type Call struct {
+	// Context truyền vào Client.WithContext, mặc định context.Background()
+	Context context.Context
+	// Tên thao tác của Client, ví dụ "aiot.CreateThing"
+	Op     string
+	Method string
+	// Path của request, ví dụ "/api-gw/v1/thing"
+	Path  string
+	Token string
+	// Body trước khi mã hóa JSON
+	Body interface{}
+	// Header được thêm vào request, ví dụ correlation id
+	Header http.Header
+	// Id của các đối tượng trong lời gọi, với khóa ATTR_THING_ID,
+	// ATTR_CHANNEL_ID hoặc ATTR_GATEWAY_ID
+	Attributes []Field
+}
+
+// Response là response của gateway với body đã được đọc hết. Các response
+// không phải 200/201 vẫn được trả về qua chuỗi interceptor và chỉ trở thành
+// lỗi sau khi ra khỏi chuỗi.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Handler thực hiện một lời gọi API
+type Handler func(call *Call) (*Response, error)
+
+// Interceptor bao quanh một lời gọi API. Interceptor gọi next để tiếp tục,
+// hoặc tự trả về Response hay lỗi để dừng lời gọi mà không gửi đến gateway.
+type Interceptor func(call *Call, next Handler) (*Response, error)
+
+// intercept ghép các interceptor quanh h, interceptor đầu tiên ở ngoài cùng
+func intercept(interceptors []Interceptor, h Handler) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], h
+		h = func(call *Call) (*Response, error) {
+			return ic(call, next)
+		}
+	}
+
+	return h
+}
@@ -0,0 +1,120 @@
+package aiot_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InterceptorOrder(t *testing.T) {
+	require := require.New(t)
+
+	var trace []string
+	named := func(name string) aiot.Interceptor {
+		return func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			trace = append(trace, name+" "+call.Op)
+			res, err := next(call)
+			trace = append(trace, name+" done")
+			return res, err
+		}
+	}
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions().
+		AddInterceptor(named("a")).
+		AddInterceptor(named("b")))
+
+	_, err := client.UserProfile(token)
+	require.NoError(err)
+	require.Equal([]string{"a aiot.UserProfile", "b aiot.UserProfile", "b done", "a done"}, trace)
+}
+
+func Test_InterceptorModifyAndObserve(t *testing.T) {
+	require := require.New(t)
+
+	srv, _ := aiottest.NewAccountServer(t)
+
+	// gateway ghi lại header correlation id của các request
+	var ids []string
+	target, err := url.Parse(srv.URL)
+	require.NoError(err)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Correlation-ID"))
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(gw.Close)
+
+	type audit struct {
+		Op     string
+		Method string
+		Status int
+	}
+	var audits []audit
+
+	client := aiot.NewClientWithOptions(gw.URL, aiot.NewClientOptions().
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			call.Header.Set("X-Correlation-ID", "req-1")
+			return next(call)
+		}).
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			res, err := next(call)
+			if call.Method != http.MethodGet && err == nil {
+				audits = append(audits, audit{call.Op, call.Method, res.StatusCode})
+			}
+			return res, err
+		}).
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			if body, ok := call.Body.(map[string]interface{}); ok && call.Op == "aiot.CreateThing" {
+				body["name"] = "prefix-" + body["name"].(string)
+			}
+			return next(call)
+		}))
+
+	token, err := client.Token(aiottest.AccountEmail, aiottest.AccountPassword)
+	require.NoError(err)
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+
+	things, _, err := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.Equal("prefix-sensor", things[0].Name)
+
+	// response lỗi vẫn đi qua interceptor
+	require.Error(client.DeleteThing(token, "missing"))
+
+	require.Equal([]string{"req-1", "req-1", "req-1", "req-1"}, ids)
+	require.Len(audits, 3)
+	require.Equal(audit{"aiot.Token", http.MethodPost, http.StatusCreated}, audits[0])
+	require.Equal("aiot.CreateThing", audits[1].Op)
+	require.Equal(audit{"aiot.DeleteThing", http.MethodDelete, http.StatusNotFound}, audits[2])
+}
+
+func Test_InterceptorShortCircuit(t *testing.T) {
+	require := require.New(t)
+
+	denied := errors.New("denied")
+	client := aiot.NewClientWithOptions("http://127.0.0.1:0", aiot.NewClientOptions().
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			switch call.Op {
+			case "aiot.UserProfile":
+				return &aiot.Response{StatusCode: http.StatusOK, Body: []byte(`{"email": "cached@aiot.vn"}`)}, nil
+			case "aiot.DeleteThing":
+				return nil, denied
+			}
+			return next(call)
+		}))
+
+	user, err := client.UserProfile("token")
+	require.NoError(err)
+	require.Equal("cached@aiot.vn", user.Email)
+
+	err = client.DeleteThing("token", "t1")
+	require.True(errors.Is(err, denied))
+	require.EqualError(err, "aiot.DeleteThing -> aiot.httpDo -> denied")
+}
@@ -0,0 +1,192 @@
+// Package inventory đọc toàn bộ trạng thái hiện tại của một tài khoản AIOT
+// (thing, channel, kết nối và gateway), duyệt qua tất cả các trang kết quả.
+package inventory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+// Số phần tử mỗi trang khi liệt kê
+const pageSize = 100
+
+// Connection là kết nối giữa một thing và một channel
+type Connection struct {
+	ThingID   string `json:"thingId"`
+	ChannelID string `json:"channelId"`
+}
+
+// Snapshot là trạng thái của tài khoản tại một thời điểm. Các danh sách được
+// sắp xếp theo tên rồi theo id, kết nối sắp xếp theo thing rồi channel.
+type Snapshot struct {
+	Things      []aiot.Thing   `json:"things"`
+	Channels    []aiot.Channel `json:"channels"`
+	Connections []Connection   `json:"connections"`
+	Gateways    []aiot.Gateway `json:"gateways"`
+}
+
+// Take đọc trạng thái hiện tại của tài khoản sở hữu token.
+func Take(api aiot.API, token string) (Snapshot, error) {
+	const op = "inventory.Take"
+
+	var s Snapshot
+	var err error
+
+	if s.Things, err = Things(api, token); err != nil {
+		return Snapshot{}, fmt.Errorf("%s -> %w", op, err)
+	}
+	if s.Channels, err = Channels(api, token); err != nil {
+		return Snapshot{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	for _, t := range s.Things {
+		channels, err := Connected(api, token, t.ID)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("%s -> %w", op, err)
+		}
+		for _, c := range channels {
+			s.Connections = append(s.Connections, Connection{ThingID: t.ID, ChannelID: c.ID})
+		}
+	}
+
+	if s.Gateways, err = api.ListGateway(token); err != nil {
+		return Snapshot{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	s.sort()
+	return s, nil
+}
+
+// paginate gọi list với offset tăng dần cho tới khi đọc đủ total phần tử.
+func paginate(list func(offset int) (int, int, error)) error {
+	offset := 0
+	for {
+		n, total, err := list(offset)
+		if err != nil {
+			return err
+		}
+
+		offset += n
+		if n == 0 || offset >= total {
+			return nil
+		}
+	}
+}
+
+// Things liệt kê toàn bộ thing của tài khoản
+func Things(api aiot.API, token string) ([]aiot.Thing, error) {
+	var all []aiot.Thing
+
+	err := paginate(func(offset int) (int, int, error) {
+		opts := aiot.NewListThingsByUserOptions().
+			SetOffset(offset).
+			SetLimit(pageSize).
+			SetOrder(aiot.THING_ORDER_ID).
+			SetDirection(aiot.DIRECTION_ASC)
+
+		page, total, err := api.ListThingsByUser(token, opts)
+		all = append(all, page...)
+		return len(page), total, err
+	})
+
+	return all, err
+}
+
+// Channels liệt kê toàn bộ channel của tài khoản
+func Channels(api aiot.API, token string) ([]aiot.Channel, error) {
+	var all []aiot.Channel
+
+	err := paginate(func(offset int) (int, int, error) {
+		opts := aiot.NewListChannelByUserOptions().
+			SetOffset(offset).
+			SetLimit(pageSize).
+			SetOrder(aiot.THING_ORDER_ID).
+			SetDirection(aiot.DIRECTION_ASC)
+
+		page, total, err := api.ListChannelByUser(token, opts)
+		all = append(all, page...)
+		return len(page), total, err
+	})
+
+	return all, err
+}
+
+// Connected trả về các channel đang kết nối với thing. Gateway trả về
+// channel đang kết nối khi disconnected là true.
+func Connected(api aiot.API, token, thingID string) ([]aiot.Channel, error) {
+	var all []aiot.Channel
+
+	err := paginate(func(offset int) (int, int, error) {
+		opts := aiot.NewListChannelByThingOptions().
+			SetOffset(offset).
+			SetLimit(pageSize).
+			SetOrder(aiot.THING_ORDER_ID).
+			SetDirection(aiot.DIRECTION_ASC).
+			SetDisconnected(true)
+
+		page, total, err := api.ListChannelByThing(token, thingID, opts)
+		all = append(all, page...)
+		return len(page), total, err
+	})
+
+	return all, err
+}
+
+func (s *Snapshot) sort() {
+	sort.Slice(s.Things, func(i, j int) bool {
+		return less(s.Things[i].Name, s.Things[i].ID, s.Things[j].Name, s.Things[j].ID)
+	})
+	sort.Slice(s.Channels, func(i, j int) bool {
+		return less(s.Channels[i].Name, s.Channels[i].ID, s.Channels[j].Name, s.Channels[j].ID)
+	})
+	sort.Slice(s.Gateways, func(i, j int) bool {
+		return less(s.Gateways[i].Name, s.Gateways[i].ID, s.Gateways[j].Name, s.Gateways[j].ID)
+	})
+	sort.Slice(s.Connections, func(i, j int) bool {
+		return less(s.Connections[i].ThingID, s.Connections[i].ChannelID, s.Connections[j].ThingID, s.Connections[j].ChannelID)
+	})
+}
+
+func less(name1, id1, name2, id2 string) bool {
+	if name1 != name2 {
+		return name1 < name2
+	}
+
+	return id1 < id2
+}
+
+// Thing tìm thing theo id
+func (s Snapshot) Thing(id string) (aiot.Thing, bool) {
+	for _, t := range s.Things {
+		if t.ID == id {
+			return t, true
+		}
+	}
+
+	return aiot.Thing{}, false
+}
+
+// Channel tìm channel theo id
+func (s Snapshot) Channel(id string) (aiot.Channel, bool) {
+	for _, c := range s.Channels {
+		if c.ID == id {
+			return c, true
+		}
+	}
+
+	return aiot.Channel{}, false
+}
+
+// ChannelsOf trả về id các channel đang kết nối với thing
+func (s Snapshot) ChannelsOf(thingID string) []string {
+	var ids []string
+	for _, c := range s.Connections {
+		if c.ThingID == thingID {
+			ids = append(ids, c.ChannelID)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,50 @@
+package inventory_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Take(t *testing.T) {
+	require := require.New(t)
+
+	client, token := aiottest.NewAccount(t)
+
+	// nhiều hơn một trang
+	for i := 0; i < 150; i++ {
+		require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: fmt.Sprintf("thing-%03d", i)}))
+	}
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "a"}))
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "b"}))
+
+	s, err := inventory.Take(client, token)
+	require.NoError(err)
+	require.Len(s.Things, 150)
+	require.Equal("thing-000", s.Things[0].Name)
+	require.Equal("thing-149", s.Things[149].Name)
+	require.Len(s.Channels, 2)
+	require.Empty(s.Connections)
+
+	thing, chA := s.Things[0], s.Channels[0]
+	require.NoError(client.Connect(token, []string{chA.ID}, []string{thing.ID}))
+	require.NoError(client.CreateGateway(token, aiot.CreateGatewayInput{Name: "gw", ThingID: thing.ID}))
+
+	s, err = inventory.Take(client, token)
+	require.NoError(err)
+	require.Equal([]inventory.Connection{{ThingID: thing.ID, ChannelID: chA.ID}}, s.Connections)
+	require.Equal([]string{chA.ID}, s.ChannelsOf(thing.ID))
+	require.Len(s.Gateways, 1)
+	require.Equal(thing.ID, s.Gateways[0].UnderlayThing.ID)
+
+	got, ok := s.Thing(thing.ID)
+	require.True(ok)
+	require.Equal(thing.Name, got.Name)
+
+	_, ok = s.Channel("missing")
+	require.False(ok)
+}
@@ -0,0 +1,345 @@
+package aiot
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LimitPolicy string
+
+var (
+	// Chờ đến khi có lượt gửi request
+	LIMIT_POLICY_WAIT LimitPolicy = "wait"
+	// Trả về ErrRateLimited ngay khi phải chờ
+	LIMIT_POLICY_FAIL LimitPolicy = "fail"
+)
+
+// Lỗi trả về với LIMIT_POLICY_FAIL khi request vượt giới hạn phía client
+var ErrRateLimited = errors.New("client-side rate limit exceeded")
+
+// Tiền tố chung của các route, được bỏ đi khi so khớp giới hạn theo route
+const routePrefix = "/api-gw/v1"
+
+// RateLimit giới hạn tốc độ (token bucket) và số request đồng thời
+type RateLimit struct {
+	// Số request mỗi giây, 0 là không giới hạn
+	Rate float64
+	// Số request được gửi dồn một lúc, tối thiểu là 1
+	Burst int
+	// Số request đang thực hiện tối đa, 0 là không giới hạn
+	MaxInFlight int
+}
+
+// LimitStats là trạng thái hiện tại của một giới hạn
+type LimitStats struct {
+	// Thời gian một request mới phải chờ trước khi được gửi
+	Wait time.Duration
+	// Tốc độ hiện tại, có thể thấp hơn RateLimit.Rate sau khi gặp 429
+	Rate     float64
+	InFlight int
+	Waiting  int
+}
+
+// Khi gặp 429, tốc độ giảm một nửa nhưng không thấp hơn Rate/minRateDivisor,
+// và tăng lại rateStep*Rate sau mỗi request thành công.
+const (
+	minRateDivisor = 16
+	rateStep       = 0.1
+)
+
+type limiter struct {
+	policy LimitPolicy
+	global *bucket
+	routes map[string]*bucket
+	now    func() time.Time
+	sleep  func(context.Context, time.Duration) error
+}
+
+func newLimiter(opts *ClientOptions) *limiter {
+	if opts.limit == (RateLimit{}) && len(opts.routeLimits) == 0 {
+		return nil
+	}
+
+	l := &limiter{
+		policy: opts.limitPolicy,
+		global: newBucket(opts.limit),
+		routes: make(map[string]*bucket, len(opts.routeLimits)),
+		now:    time.Now,
+		sleep:  sleep,
+	}
+	for route, rl := range opts.routeLimits {
+		l.routes[route] = newBucket(rl)
+	}
+
+	return l
+}
+
+// buckets trả về giới hạn của route (nếu có) và giới hạn chung
+func (l *limiter) buckets(path string) []*bucket {
+	if b, ok := l.routes[strings.TrimPrefix(path, routePrefix)]; ok {
+		return []*bucket{b, l.global}
+	}
+
+	return []*bucket{l.global}
+}
+
+// acquire chờ (hoặc từ chối) cho đến khi request được gửi, trả về hàm giải
+// phóng lượt đồng thời phải gọi khi request kết thúc. Khi ctx bị hủy trong
+// lúc chờ, acquire trả về lỗi của ctx. Khi không được gửi, token và lượt
+// đồng thời đã lấy ở mọi giới hạn đều được trả lại.
+func (l *limiter) acquire(ctx context.Context, path string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	var acquired, reserved []*bucket
+	release := func() {
+		for _, b := range acquired {
+			b.release()
+		}
+	}
+	fail := func(err error) (func(), error) {
+		for _, b := range reserved {
+			b.refund()
+		}
+		release()
+		return nil, err
+	}
+
+	for _, b := range l.buckets(path) {
+		if err := b.acquireSlot(ctx, l.policy == LIMIT_POLICY_WAIT); err != nil {
+			return fail(err)
+		}
+		acquired = append(acquired, b)
+
+		wait, ok := b.reserve(l.now(), l.policy == LIMIT_POLICY_WAIT)
+		if !ok {
+			return fail(ErrRateLimited)
+		}
+		reserved = append(reserved, b)
+
+		if wait > 0 {
+			b.waiting(1)
+			err := l.sleep(ctx, wait)
+			b.waiting(-1)
+
+			if err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	return release, nil
+}
+
+// sleep chờ d hoặc đến khi ctx bị hủy
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// observe điều chỉnh tốc độ theo response: giảm khi gặp 429 (và chờ theo
+// Retry-After), tăng dần khi thành công. Route có giới hạn riêng chỉ ảnh
+// hưởng đến giới hạn của chính nó.
+func (l *limiter) observe(path string, resp *http.Response) {
+	if l == nil {
+		return
+	}
+
+	b := l.buckets(path)[0]
+	if resp.StatusCode == http.StatusTooManyRequests {
+		b.throttle(l.now(), retryAfter(resp))
+	} else {
+		b.recover(l.now())
+	}
+}
+
+func (l *limiter) stats(route string) LimitStats {
+	if l == nil {
+		return LimitStats{}
+	}
+
+	b := l.global
+	if route != "" {
+		var ok bool
+		if b, ok = l.routes[strings.TrimPrefix(route, routePrefix)]; !ok {
+			return LimitStats{}
+		}
+	}
+
+	return b.stats(l.now())
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+
+	return time.Duration(secs) * time.Second
+}
+
+type bucket struct {
+	base  float64
+	burst float64
+	sem   chan struct{}
+
+	mu      sync.Mutex
+	rate    float64
+	tokens  float64
+	last    time.Time
+	blocked time.Time
+	wait    int
+}
+
+func newBucket(rl RateLimit) *bucket {
+	b := &bucket{
+		base:   rl.Rate,
+		rate:   rl.Rate,
+		burst:  math.Max(float64(rl.Burst), 1),
+		tokens: math.Max(float64(rl.Burst), 1),
+	}
+	if rl.MaxInFlight > 0 {
+		b.sem = make(chan struct{}, rl.MaxInFlight)
+	}
+
+	return b
+}
+
+func (b *bucket) acquireSlot(ctx context.Context, wait bool) error {
+	if b.sem == nil {
+		return nil
+	}
+
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if !wait {
+		return ErrRateLimited
+	}
+
+	b.waiting(1)
+	defer b.waiting(-1)
+
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *bucket) release() {
+	if b.sem != nil {
+		<-b.sem
+	}
+}
+
+func (b *bucket) waiting(delta int) {
+	b.mu.Lock()
+	b.wait += delta
+	b.mu.Unlock()
+}
+
+// refill cộng thêm token theo thời gian trôi qua, b.mu phải được giữ
+func (b *bucket) refill(now time.Time) {
+	if !b.last.IsZero() && b.rate > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
+
+// delay là thời gian đến khi có đủ n token, b.mu phải được giữ
+func (b *bucket) delay(now time.Time, n float64) time.Duration {
+	var d time.Duration
+	if b.rate > 0 && b.tokens < n {
+		d = time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+	}
+	if blocked := b.blocked.Sub(now); blocked > d {
+		d = blocked
+	}
+
+	return d
+}
+
+// reserve lấy một token và trả về thời gian phải chờ. Khi không được chờ mà
+// thời gian chờ lớn hơn 0, token không bị lấy.
+func (b *bucket) reserve(now time.Time, wait bool) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	d := b.delay(now, 1)
+	if d > 0 && !wait {
+		return 0, false
+	}
+	if b.rate > 0 {
+		b.tokens--
+	}
+
+	return d, true
+}
+
+// refund trả lại token đã lấy bằng reserve khi request không được gửi
+func (b *bucket) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+1)
+	}
+}
+
+func (b *bucket) throttle(now time.Time, retryAfter time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until := now.Add(retryAfter); until.After(b.blocked) {
+		b.blocked = until
+	}
+
+	if b.base > 0 {
+		b.refill(now)
+		b.rate = math.Max(b.rate/2, b.base/minRateDivisor)
+	}
+}
+
+func (b *bucket) recover(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate < b.base {
+		b.refill(now)
+		b.rate = math.Min(b.base, b.rate+b.base*rateStep)
+	}
+}
+
+func (b *bucket) stats(now time.Time) LimitStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+
+	return LimitStats{
+		Wait:     b.delay(now, 1),
+		Rate:     b.rate,
+		InFlight: len(b.sem),
+		Waiting:  b.wait,
+	}
+}
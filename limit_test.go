@@ -0,0 +1,186 @@
+package aiot_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/stretchr/testify/require"
+)
+
+func limitedClient(t *testing.T, opts *aiot.ClientOptions) (*aiottest.Server, aiot.Client, string) {
+	srv, token := aiottest.NewAccountServer(t)
+
+	return srv, aiot.NewClientWithOptions(srv.URL, opts), token
+}
+
+func Test_RateLimit(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetRateLimit(aiot.RateLimit{Rate: 20, Burst: 2}))
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := client.UserProfile(token)
+		require.NoError(err)
+	}
+	// 2 request đầu dùng burst, 2 request sau chờ 50ms mỗi request
+	require.GreaterOrEqual(time.Since(start), 90*time.Millisecond)
+
+	_, client, token = limitedClient(t, aiot.NewClientOptions().
+		SetRateLimit(aiot.RateLimit{Rate: 1, Burst: 2}).
+		SetLimitPolicy(aiot.LIMIT_POLICY_FAIL))
+
+	for i := 0; i < 2; i++ {
+		_, err := client.UserProfile(token)
+		require.NoError(err)
+	}
+	_, err := client.UserProfile(token)
+	require.True(errors.Is(err, aiot.ErrRateLimited), err)
+	require.True(aiot.Is(aiot.KIND_RATE_LIMITED, err))
+	require.Greater(client.LimitStats("").Wait, time.Duration(0))
+}
+
+func Test_MaxInFlight(t *testing.T) {
+	require := require.New(t)
+
+	srv, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetRateLimit(aiot.RateLimit{MaxInFlight: 2}))
+	srv.InjectFault(aiottest.AnyRoute, aiottest.Fault{Latency: 100 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.UserProfile(token)
+			require.NoError(err)
+		}()
+	}
+
+	require.Eventually(func() bool {
+		s := client.LimitStats("")
+		return s.InFlight == 2 && s.Waiting == 1
+	}, time.Second, time.Millisecond)
+	wg.Wait()
+	require.Equal(aiot.LimitStats{}, client.LimitStats(""))
+
+	// fail fast khi đã đủ số request đồng thời
+	srv, client, token = limitedClient(t, aiot.NewClientOptions().
+		SetRateLimit(aiot.RateLimit{MaxInFlight: 1}).
+		SetLimitPolicy(aiot.LIMIT_POLICY_FAIL))
+	srv.InjectFault(aiottest.AnyRoute, aiottest.Fault{Latency: 100 * time.Millisecond})
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := client.UserProfile(token)
+			errs <- err
+		}()
+	}
+	failed := 0
+	for i := 0; i < 2; i++ {
+		if errors.Is(<-errs, aiot.ErrRateLimited) {
+			failed++
+		}
+	}
+	require.Equal(1, failed)
+}
+
+func Test_RouteLimitAdapts(t *testing.T) {
+	require := require.New(t)
+
+	srv, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetRouteRateLimit("/thing/connect", aiot.RateLimit{Rate: 100, Burst: 10}).
+		SetLimitPolicy(aiot.LIMIT_POLICY_FAIL))
+	srv.InjectFault("POST /api-gw/v1/thing/connect", aiottest.Fault{
+		Status:     http.StatusTooManyRequests,
+		RetryAfter: time.Second,
+		Times:      1,
+	})
+
+	err := client.Connect(token, []string{"c1"}, []string{"t1"})
+	require.Error(err)
+	require.False(errors.Is(err, aiot.ErrRateLimited))
+
+	stats := client.LimitStats("/thing/connect")
+	require.Equal(50.0, stats.Rate)
+	require.Greater(stats.Wait, 900*time.Millisecond)
+
+	// chờ Retry-After, các route khác không bị ảnh hưởng
+	err = client.Connect(token, []string{"c1"}, []string{"t1"})
+	require.True(errors.Is(err, aiot.ErrRateLimited), err)
+
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+	require.Equal(aiot.LimitStats{}, client.LimitStats(""))
+}
+
+func Test_LimitWaitCancelled(t *testing.T) {
+	require := require.New(t)
+
+	// chờ lượt đồng thời
+	srv, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetRateLimit(aiot.RateLimit{MaxInFlight: 1}))
+	srv.InjectFault(aiottest.AnyRoute, aiottest.Fault{Latency: 200 * time.Millisecond, Times: 1})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.UserProfile(token)
+	}()
+	require.Eventually(func() bool {
+		return client.LimitStats("").InFlight == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.WithContext(ctx).UserProfile(token)
+	require.True(errors.Is(err, context.DeadlineExceeded), err)
+	require.False(aiot.Is(aiot.KIND_RATE_LIMITED, err))
+	require.Less(int64(time.Since(start)), int64(150*time.Millisecond))
+	require.Equal(0, client.LimitStats("").Waiting)
+	<-done
+
+	// chờ token của rate limit
+	_, client, token = limitedClient(t, aiot.NewClientOptions().
+		SetRateLimit(aiot.RateLimit{Rate: 0.5, Burst: 1}))
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start = time.Now()
+	_, err = client.WithContext(ctx).UserProfile(token)
+	require.True(errors.Is(err, context.Canceled), err)
+	require.Less(int64(time.Since(start)), int64(time.Second))
+	require.Equal(0, client.LimitStats("").Waiting)
+}
+
+func Test_RouteLimitRefund(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetRateLimit(aiot.RateLimit{Rate: 1, Burst: 1}).
+		SetRouteRateLimit("/thing/connect", aiot.RateLimit{Rate: 1, Burst: 2}).
+		SetLimitPolicy(aiot.LIMIT_POLICY_FAIL))
+
+	_, err := client.UserProfile(token)
+	require.NoError(err)
+
+	// giới hạn chung từ chối, token của route được trả lại
+	for i := 0; i < 3; i++ {
+		err = client.Connect(token, []string{"c1"}, []string{"t1"})
+		require.True(errors.Is(err, aiot.ErrRateLimited), err)
+	}
+	require.Equal(time.Duration(0), client.LimitStats("/thing/connect").Wait)
+}
@@ -0,0 +1,84 @@
+package aiot
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type LogLevel string
+
+var (
+	// Body của request và response (đã che thông tin nhạy cảm)
+	LOG_LEVEL_DEBUG LogLevel = "debug"
+	// Lời gọi thành công
+	LOG_LEVEL_INFO LogLevel = "info"
+	// Lời gọi lỗi hoặc response không phải 200/201
+	LOG_LEVEL_ERROR LogLevel = "error"
+)
+
+// Field là một cặp khóa/giá trị của bản ghi log
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger nhận các bản ghi log có cấu trúc của Client. NewSlogLogger chuyển
+// một *slog.Logger thành Logger.
+type Logger interface {
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, msg string, fields ...Field)
+}
+
+// LoggingInterceptor ghi một bản ghi cho mỗi lời gọi API gồm op, method,
+// path, status, latency và loại lỗi. Ở LOG_LEVEL_DEBUG, body của request và
+// response cũng được ghi sau khi che các trường nhạy cảm bằng Redact.
+func LoggingInterceptor(l Logger) Interceptor {
+	return func(call *Call, next Handler) (*Response, error) {
+		if l.Enabled(LOG_LEVEL_DEBUG) {
+			body, _ := json.Marshal(call.Body)
+			l.Log(LOG_LEVEL_DEBUG, "aiot request",
+				Field{"op", call.Op},
+				Field{"method", call.Method},
+				Field{"path", call.Path},
+				Field{"body", string(Redact(body))},
+			)
+		}
+
+		start := time.Now()
+		res, err := next(call)
+		latency := time.Since(start)
+
+		fields := []Field{
+			{"op", call.Op},
+			{"method", call.Method},
+			{"path", call.Path},
+		}
+		if res != nil {
+			fields = append(fields, Field{"status", res.StatusCode})
+		}
+		fields = append(fields, Field{"latency", latency})
+
+		level := LOG_LEVEL_INFO
+		switch {
+		case err != nil:
+			level = LOG_LEVEL_ERROR
+			fields = append(fields, Field{"kind", kindOf(err).String()}, Field{"error", err.Error()})
+		case res.StatusCode != 200 && res.StatusCode != 201:
+			level = LOG_LEVEL_ERROR
+		}
+
+		if l.Enabled(level) {
+			l.Log(level, "aiot call", fields...)
+		}
+
+		if res != nil && l.Enabled(LOG_LEVEL_DEBUG) {
+			l.Log(LOG_LEVEL_DEBUG, "aiot response",
+				Field{"op", call.Op},
+				Field{"status", res.StatusCode},
+				Field{"body", string(Redact(res.Body))},
+			)
+		}
+
+		return res, err
+	}
+}
@@ -0,0 +1,121 @@
+package aiot_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	level  aiot.LogLevel
+	msg    string
+	fields map[string]interface{}
+}
+
+type recorder struct {
+	mu      sync.Mutex
+	min     aiot.LogLevel
+	records []record
+}
+
+func (r *recorder) Enabled(level aiot.LogLevel) bool {
+	return r.min == aiot.LOG_LEVEL_DEBUG || level != aiot.LOG_LEVEL_DEBUG
+}
+
+func (r *recorder) Log(level aiot.LogLevel, msg string, fields ...aiot.Field) {
+	m := map[string]interface{}{}
+	for _, f := range fields {
+		m[f.Key] = f.Value
+	}
+
+	r.mu.Lock()
+	r.records = append(r.records, record{level, msg, m})
+	r.mu.Unlock()
+}
+
+func Test_Logging(t *testing.T) {
+	require := require.New(t)
+
+	log := &recorder{min: aiot.LOG_LEVEL_INFO}
+	srv, client, token := limitedClient(t, aiot.NewClientOptions().SetLogger(log))
+
+	_, err := client.UserProfile(token)
+	require.NoError(err)
+	require.Len(log.records, 1)
+
+	r := log.records[0]
+	require.Equal(aiot.LOG_LEVEL_INFO, r.level)
+	require.Equal("aiot.UserProfile", r.fields["op"])
+	require.Equal(http.MethodGet, r.fields["method"])
+	require.Equal("/api-gw/v1/user/profile", r.fields["path"])
+	require.Equal(http.StatusOK, r.fields["status"])
+	require.Contains(r.fields, "latency")
+
+	// gateway trả về lỗi
+	log.records = nil
+	srv.InjectFault("POST /api-gw/v1/gateway/create", aiottest.Fault{Status: http.StatusBadGateway, Times: 1})
+	require.Error(client.CreateGateway(token, aiot.CreateGatewayInput{Name: "gw", ThingID: "00000000-0000-4000-8000-000000000000"}))
+	require.Equal(aiot.LOG_LEVEL_ERROR, log.records[0].level)
+	require.Equal(http.StatusBadGateway, log.records[0].fields["status"])
+
+	// lỗi trước khi gửi request có loại lỗi
+	log.records = nil
+	limited := aiot.NewClientWithOptions(srv.URL, aiot.NewClientOptions().
+		SetLogger(log).
+		SetRateLimit(aiot.RateLimit{Rate: 0.001}).
+		SetLimitPolicy(aiot.LIMIT_POLICY_FAIL))
+	limited.UserProfile(token)
+	limited.UserProfile(token)
+	require.Len(log.records, 2)
+	require.Equal("rate limited", log.records[1].fields["kind"])
+	require.NotContains(log.records[1].fields, "status")
+}
+
+func Test_LoggingRedactsBodies(t *testing.T) {
+	require := require.New(t)
+
+	log := &recorder{min: aiot.LOG_LEVEL_DEBUG}
+	_, client, _ := limitedClient(t, aiot.NewClientOptions().SetLogger(log))
+
+	token, err := client.Token(aiottest.AccountEmail, aiottest.AccountPassword)
+	require.NoError(err)
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	_, _, err = client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+
+	var bodies []string
+	for _, r := range log.records {
+		if r.level == aiot.LOG_LEVEL_DEBUG {
+			bodies = append(bodies, r.fields["body"].(string))
+		}
+	}
+	require.Len(bodies, 6)
+	require.Equal(`{"email":"ops@aiot.vn","password":"[REDACTED]"}`, bodies[0])
+	require.Equal(`{"token":"Bearer [REDACTED]"}`, bodies[1])
+	require.Contains(bodies[5], `"key":"[REDACTED]"`)
+	require.Contains(bodies[5], `"name":"sensor"`)
+
+	for _, b := range bodies {
+		require.NotContains(b, "secret")
+		require.NotContains(b, token)
+	}
+}
+
+func Test_Redact(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(`{"items":[{"channelKey":"[REDACTED]","id":"1"}],"newPassword":"[REDACTED]"}`,
+		string(aiot.Redact([]byte(`{"newPassword": "x", "items": [{"id": "1", "channelKey": "k"}]}`))))
+	require.Equal("[REDACTED]", string(aiot.Redact([]byte("not json"))))
+	require.Empty(aiot.Redact(nil))
+
+	// chỉ các trường trong SensitiveFields bị che, không so khớp một phần tên
+	require.Equal(`{"Token":"Bearer [REDACTED]","key":"","monkey":"m","secret":"[REDACTED]","thingKey":null}`,
+		string(aiot.Redact([]byte(`{"Token": "Bearer abc", "key": "", "monkey": "m", "secret": 1, "thingKey": null}`))))
+	require.True(aiot.Sensitive("ChannelKey"))
+	require.False(aiot.Sensitive("keyboard"))
+}
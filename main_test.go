@@ -0,0 +1,39 @@
+package aiot_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+)
+
+const (
+	validEmail      = "test@aiot.vn"
+	validPassword   = "valid-password"
+	invalidPassword = "invalid-password"
+)
+
+var gatewayAddr string
+
+// Mặc định các test chạy với gateway giả lập của package aiottest. Đặt biến
+// môi trường AIOT_TEST_GATEWAY để chạy với một gateway thật, khi đó user
+// test@aiot.vn phải tồn tại với mật khẩu validPassword.
+func TestMain(m *testing.M) {
+	if addr := os.Getenv("AIOT_TEST_GATEWAY"); addr != "" {
+		gatewayAddr = addr
+		os.Exit(m.Run())
+	}
+
+	srv := aiottest.NewServer()
+	srv.AddUser(aiottest.User{
+		Email:    validEmail,
+		Password: validPassword,
+		Fullname: "Test User",
+	})
+	gatewayAddr = srv.URL
+
+	code := m.Run()
+	srv.Close()
+
+	os.Exit(code)
+}
@@ -0,0 +1,257 @@
+package aiot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Metadata là metadata của thing và channel, có thể là JSON tùy ý: chuỗi,
+// số, bool, mảng hoặc object lồng nhau. Số được giải mã thành float64.
+type Metadata map[string]interface{}
+
+// MetadataOf chuyển v (thường là struct có tag json) thành Metadata
+func MetadataOf(v interface{}) (Metadata, error) {
+	const op operation = "aiot.MetadataOf"
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, makeE(op, err)
+	}
+
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, makeE(op, err)
+	}
+
+	return m, nil
+}
+
+// Bind giải mã metadata vào v (con trỏ tới struct có tag json hoặc map)
+func (m Metadata) Bind(v interface{}) error {
+	const op operation = "aiot.Metadata.Bind"
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return makeE(op, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return makeE(op, err)
+	}
+
+	return nil
+}
+
+// Giá trị của key và có tồn tại hay không
+func (m Metadata) Get(key string) (interface{}, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+// Giá trị chuỗi của key, false nếu không có hoặc không phải chuỗi
+func (m Metadata) String(key string) (string, bool) {
+	s, ok := m[key].(string)
+	return s, ok
+}
+
+// Text trả về giá trị của key dạng chuỗi: chuỗi được giữ nguyên, giá trị
+// khác được mã hóa JSON, rỗng nếu không có key
+func (m Metadata) Text(key string) string {
+	switch v := m[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+
+	data, err := json.Marshal(m[key])
+	if err != nil {
+		return fmt.Sprint(m[key])
+	}
+
+	return string(data)
+}
+
+// Giá trị số của key, false nếu không có hoặc không phải số
+func (m Metadata) Float(key string) (float64, bool) {
+	switch v := m[key].(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// Giá trị số nguyên của key, false nếu không có hoặc không phải số nguyên
+func (m Metadata) Int(key string) (int64, bool) {
+	switch v := m[key].(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case json.Number:
+		i, err := strconv.ParseInt(string(v), 10, 64)
+		return i, err == nil
+	}
+
+	f, ok := m.Float(key)
+	if !ok || f != float64(int64(f)) {
+		return 0, false
+	}
+
+	return int64(f), true
+}
+
+// Giá trị bool của key, false nếu không có hoặc không phải bool
+func (m Metadata) Bool(key string) (bool, bool) {
+	b, ok := m[key].(bool)
+	return b, ok
+}
+
+// Object lồng nhau của key, false nếu không có hoặc không phải object
+func (m Metadata) Map(key string) (Metadata, bool) {
+	switch v := m[key].(type) {
+	case Metadata:
+		return v, true
+	case map[string]interface{}:
+		return Metadata(v), true
+	}
+
+	return nil, false
+}
+
+// Clone trả về bản sao sâu của metadata
+func (m Metadata) Clone() Metadata {
+	if m == nil {
+		return nil
+	}
+
+	return cloneValue(m).(Metadata)
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case Metadata:
+		out := make(Metadata, len(v))
+		for k, e := range v {
+			out[k] = cloneValue(e)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = cloneValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = cloneValue(e)
+		}
+		return out
+	}
+
+	return v
+}
+
+// Merge trả về metadata mới sau khi áp dụng patch theo JSON Merge Patch
+// (RFC 7396): khóa có giá trị nil bị xóa, object được gộp theo từng khóa
+// con, giá trị khác thay thế giá trị cũ. m không bị thay đổi.
+func (m Metadata) Merge(patch Metadata) Metadata {
+	out := m.Clone()
+	if out == nil {
+		out = Metadata{}
+	}
+
+	for k, v := range patch {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+
+		if p, ok := patch.Map(k); ok {
+			cur, _ := out.Map(k)
+			out[k] = map[string]interface{}(cur.Merge(p))
+			continue
+		}
+
+		out[k] = cloneValue(v)
+	}
+
+	return out
+}
+
+// Equal so sánh hai metadata theo dạng JSON, nên 1 và 1.0 là bằng nhau.
+// Metadata rỗng và nil là bằng nhau.
+func (m Metadata) Equal(o Metadata) bool {
+	if len(m) == 0 || len(o) == 0 {
+		return len(m) == len(o)
+	}
+
+	a, errA := normalize(m)
+	b, errB := normalize(o)
+
+	return errA == nil && errB == nil && reflect.DeepEqual(a, b)
+}
+
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// UnmarshalJSON nhận metadata là object JSON, hoặc chuỗi chứa object JSON
+// như trong response của gateway. Metadata không phải object là lỗi.
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = bytes.TrimSpace([]byte(s))
+		if len(data) == 0 {
+			*m = nil
+			return nil
+		}
+	}
+
+	if bytes.Equal(data, []byte("null")) {
+		*m = nil
+		return nil
+	}
+
+	var v map[string]interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid metadata %s: %w", truncate(data, 64), err)
+	}
+
+	*m = Metadata(v)
+	return nil
+}
+
+func truncate(data []byte, n int) string {
+	if len(data) <= n {
+		return string(data)
+	}
+
+	return string(data[:n]) + "..."
+}
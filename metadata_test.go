@@ -0,0 +1,172 @@
+package aiot_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/stretchr/testify/require"
+)
+
+type location struct {
+	Building string  `json:"building"`
+	Floor    int     `json:"floor"`
+	Lat      float64 `json:"lat"`
+}
+
+type sensorMeta struct {
+	Model    string   `json:"model"`
+	Enabled  bool     `json:"enabled"`
+	Location location `json:"location"`
+	Tags     []string `json:"tags"`
+}
+
+func Test_MetadataGetters(t *testing.T) {
+	require := require.New(t)
+
+	var m aiot.Metadata
+	require.NoError(json.Unmarshal([]byte(`{"model":"x1","floor":3,"ratio":0.5,"enabled":true,"location":{"building":"A"}}`), &m))
+
+	s, ok := m.String("model")
+	require.True(ok)
+	require.Equal("x1", s)
+
+	n, ok := m.Int("floor")
+	require.True(ok)
+	require.Equal(int64(3), n)
+
+	_, ok = m.Int("ratio")
+	require.False(ok)
+
+	f, ok := m.Float("ratio")
+	require.True(ok)
+	require.Equal(0.5, f)
+
+	b, ok := m.Bool("enabled")
+	require.True(ok)
+	require.True(b)
+
+	loc, ok := m.Map("location")
+	require.True(ok)
+	require.Equal("A", loc.Text("building"))
+
+	_, ok = m.String("floor")
+	require.False(ok)
+	require.Equal("3", m.Text("floor"))
+	require.Equal("", m.Text("missing"))
+
+	// metadata tạo trong code có thể chứa int
+	n, ok = aiot.Metadata{"floor": 2}.Int("floor")
+	require.True(ok)
+	require.Equal(int64(2), n)
+}
+
+func Test_MetadataBind(t *testing.T) {
+	require := require.New(t)
+
+	in := sensorMeta{Model: "x1", Enabled: true, Location: location{Building: "A", Floor: 3, Lat: 10.75}, Tags: []string{"hcm"}}
+	m, err := aiot.MetadataOf(in)
+	require.NoError(err)
+	require.Equal(aiot.Metadata{
+		"model":    "x1",
+		"enabled":  true,
+		"location": map[string]interface{}{"building": "A", "floor": float64(3), "lat": 10.75},
+		"tags":     []interface{}{"hcm"},
+	}, m)
+
+	var out sensorMeta
+	require.NoError(m.Bind(&out))
+	require.Equal(in, out)
+
+	err = aiot.Metadata{"location": "A"}.Bind(&out)
+	require.Error(err)
+
+	_, err = aiot.MetadataOf("not an object")
+	require.Error(err)
+}
+
+func Test_MetadataCloneEqual(t *testing.T) {
+	require := require.New(t)
+
+	m := aiot.Metadata{"location": map[string]interface{}{"floor": 1}, "tags": []interface{}{"a"}}
+	c := m.Clone()
+	c["location"].(map[string]interface{})["floor"] = 2
+	c["tags"].([]interface{})[0] = "b"
+	require.Equal(1, m["location"].(map[string]interface{})["floor"])
+	require.Equal("a", m["tags"].([]interface{})[0])
+
+	require.True(aiot.Metadata{"floor": 1}.Equal(aiot.Metadata{"floor": 1.0}))
+	require.False(aiot.Metadata{"floor": 1}.Equal(aiot.Metadata{"floor": "1"}))
+	require.True(aiot.Metadata(nil).Equal(aiot.Metadata{}))
+}
+
+func Test_MetadataUnmarshal(t *testing.T) {
+	require := require.New(t)
+
+	for _, s := range []string{`{"floor":1}`, `"{\"floor\":1}"`} {
+		var m aiot.Metadata
+		require.NoError(json.Unmarshal([]byte(s), &m), s)
+		require.Equal(aiot.Metadata{"floor": float64(1)}, m)
+	}
+
+	for _, s := range []string{`null`, `""`, `"null"`} {
+		m := aiot.Metadata{"x": 1}
+		require.NoError(json.Unmarshal([]byte(s), &m), s)
+		require.Nil(m, s)
+	}
+
+	for _, s := range []string{`[1]`, `"not json"`, `3`, `"[1]"`} {
+		var m aiot.Metadata
+		require.Error(json.Unmarshal([]byte(s), &m), s)
+	}
+}
+
+func Test_RichMetadata(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions())
+
+	meta := aiot.Metadata{
+		"floor":    3,
+		"enabled":  true,
+		"location": map[string]interface{}{"building": "A", "lat": 10.75},
+	}
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor", Metadata: meta}))
+
+	things, _, err := client.ListThingsByUser(token, aiot.NewListThingsByUserOptions())
+	require.NoError(err)
+	require.True(meta.Equal(things[0].Metadata))
+
+	thing, err := client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.True(meta.Equal(thing.Metadata))
+
+	// gateway trả về metadata dạng chuỗi JSON, object lồng nhau và số được giữ nguyên
+	require.NoError(client.CreateGateway(token, aiot.CreateGatewayInput{Name: "gw", ThingID: thing.ID}))
+	gateways, err := client.ListGateway(token)
+	require.NoError(err)
+	require.True(meta.Equal(gateways[0].UnderlayThing.Metadata))
+
+	gw, err := client.GatewayProfile(token, gateways[0].ID)
+	require.NoError(err)
+	loc, ok := gw.UnderlayThing.Metadata.Map("location")
+	require.True(ok)
+	lat, _ := loc.Float("lat")
+	require.Equal(10.75, lat)
+}
+
+func Test_MalformedMetadata(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions().
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			return &aiot.Response{
+				StatusCode: 200,
+				Body:       []byte(`{"gatewayId":"g1","metadata":"{broken"}`),
+			}, nil
+		}))
+
+	_, err := client.GatewayProfile(token, "g1")
+	require.Error(err)
+	require.Contains(err.Error(), "invalid metadata")
+}
@@ -0,0 +1,56 @@
+package aiot
+
+import "time"
+
+// CallMetric là kết quả của một lời gọi API
+type CallMetric struct {
+	Op     string
+	Method string
+	// Mã HTTP của response, 0 nếu không có response
+	Status  int
+	Latency time.Duration
+	// Lỗi trước khi có response (lỗi kết nối, giới hạn tốc độ...) và loại của
+	// nó. Response không phải 200/201 có Err bằng nil.
+	Err  error
+	Kind Kind
+}
+
+// Failed báo lời gọi có lỗi hoặc response không phải 200/201
+func (m CallMetric) Failed() bool {
+	return m.Err != nil || m.Status != 200 && m.Status != 201
+}
+
+// Metrics nhận số liệu về các lời gọi API của Client, ví dụ
+// metrics.Collector xuất số liệu theo định dạng của Prometheus.
+type Metrics interface {
+	// Một lời gọi bắt đầu
+	Started(op string)
+	// Một lời gọi kết thúc
+	Finished(m CallMetric)
+	// Request được gửi lại sang địa chỉ gateway dự phòng
+	Retried(op string)
+}
+
+// MetricsInterceptor gửi số liệu của mỗi lời gọi API đến m
+func MetricsInterceptor(m Metrics) Interceptor {
+	return func(call *Call, next Handler) (*Response, error) {
+		m.Started(call.Op)
+
+		start := time.Now()
+		res, err := next(call)
+
+		cm := CallMetric{
+			Op:      call.Op,
+			Method:  call.Method,
+			Latency: time.Since(start),
+			Err:     err,
+			Kind:    kindOf(err),
+		}
+		if res != nil {
+			cm.Status = res.StatusCode
+		}
+		m.Finished(cm)
+
+		return res, err
+	}
+}
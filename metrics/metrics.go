@@ -0,0 +1,256 @@
+// Package metrics thu thập số liệu các lời gọi API của aiot.Client và xuất
+// ra theo định dạng text của Prometheus mà không cần thư viện Prometheus.
+//
+//	collector := metrics.NewCollector(nil)
+//	client := aiot.NewClientWithOptions(addr, aiot.NewClientOptions().SetMetrics(collector))
+//	http.Handle("/metrics", collector)
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+// Các bucket mặc định của histogram latency (giây), giống Prometheus
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type Options struct {
+	namespace string
+	buckets   []float64
+}
+
+func NewOptions() *Options {
+	return &Options{
+		namespace: "aiot",
+		buckets:   DefaultBuckets,
+	}
+}
+
+// Tiền tố tên các metric, mặc định "aiot"
+func (opts *Options) SetNamespace(ns string) *Options {
+	opts.namespace = ns
+	return opts
+}
+
+// Các bucket (giây, tăng dần) của histogram latency
+func (opts *Options) SetBuckets(buckets []float64) *Options {
+	opts.buckets = buckets
+	return opts
+}
+
+type requestKey struct {
+	op, method, status string
+}
+
+type errorKey struct {
+	op, kind, status string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Collector hiện thực aiot.Metrics và http.Handler trả về các metric:
+//
+//	aiot_requests_total{op,method,status}
+//	aiot_errors_total{op,kind,status}
+//	aiot_request_duration_seconds{op}
+//	aiot_retries_total{op}
+//	aiot_in_flight_requests{op}
+//
+// Với lỗi trước khi có response, status là rỗng và kind là aiot.Kind của lỗi
+// với khoảng trắng thay bằng "_", ví dụ "rate_limited"; với response không
+// phải 200/201, kind là "http".
+type Collector struct {
+	opts *Options
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	errors    map[errorKey]uint64
+	latencies map[string]*histogram
+	retries   map[string]uint64
+	inFlight  map[string]int64
+}
+
+func NewCollector(opts *Options) *Collector {
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	return &Collector{
+		opts:      opts,
+		requests:  map[requestKey]uint64{},
+		errors:    map[errorKey]uint64{},
+		latencies: map[string]*histogram{},
+		retries:   map[string]uint64{},
+		inFlight:  map[string]int64{},
+	}
+}
+
+func (c *Collector) Started(op string) {
+	c.mu.Lock()
+	c.inFlight[op]++
+	c.mu.Unlock()
+}
+
+func (c *Collector) Finished(m aiot.CallMetric) {
+	status := ""
+	if m.Status != 0 {
+		status = strconv.Itoa(m.Status)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight[m.Op]--
+	c.requests[requestKey{m.Op, m.Method, status}]++
+
+	if m.Failed() {
+		kind := "http"
+		if m.Err != nil {
+			kind = strings.ReplaceAll(m.Kind.String(), " ", "_")
+		}
+		c.errors[errorKey{m.Op, kind, status}]++
+	}
+
+	h, ok := c.latencies[m.Op]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.opts.buckets))}
+		c.latencies[m.Op] = h
+	}
+
+	secs := m.Latency.Seconds()
+	for i, b := range c.opts.buckets {
+		if secs <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+func (c *Collector) Retried(op string) {
+	c.mu.Lock()
+	c.retries[op]++
+	c.mu.Unlock()
+}
+
+// ServeHTTP trả về các metric theo định dạng text của Prometheus
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Write(w)
+}
+
+// Write ghi các metric theo định dạng text của Prometheus
+func (c *Collector) Write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	ns := c.opts.namespace
+
+	header(bw, ns+"_requests_total", "counter", "AIOT API calls by operation, method and HTTP status.")
+	requests := make([]requestKey, 0, len(c.requests))
+	for k := range c.requests {
+		requests = append(requests, k)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+		return a.op < b.op || a.op == b.op && (a.method < b.method || a.method == b.method && a.status < b.status)
+	})
+	for _, k := range requests {
+		sample(bw, ns+"_requests_total", labels("op", k.op, "method", k.method, "status", k.status), float64(c.requests[k]))
+	}
+
+	header(bw, ns+"_errors_total", "counter", "Failed AIOT API calls by operation, error kind and HTTP status.")
+	errors := make([]errorKey, 0, len(c.errors))
+	for k := range c.errors {
+		errors = append(errors, k)
+	}
+	sort.Slice(errors, func(i, j int) bool {
+		a, b := errors[i], errors[j]
+		return a.op < b.op || a.op == b.op && (a.kind < b.kind || a.kind == b.kind && a.status < b.status)
+	})
+	for _, k := range errors {
+		sample(bw, ns+"_errors_total", labels("op", k.op, "kind", k.kind, "status", k.status), float64(c.errors[k]))
+	}
+
+	name := ns + "_request_duration_seconds"
+	header(bw, name, "histogram", "Latency of AIOT API calls.")
+	var ops []string
+	for op := range c.latencies {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		h := c.latencies[op]
+		for i, b := range c.opts.buckets {
+			sample(bw, name+"_bucket", labels("op", op, "le", formatFloat(b)), float64(h.counts[i]))
+		}
+		sample(bw, name+"_bucket", labels("op", op, "le", "+Inf"), float64(h.count))
+		sample(bw, name+"_sum", labels("op", op), h.sum)
+		sample(bw, name+"_count", labels("op", op), float64(h.count))
+	}
+
+	header(bw, ns+"_retries_total", "counter", "AIOT API requests resent to a failover gateway.")
+	ops = ops[:0]
+	for op := range c.retries {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		sample(bw, ns+"_retries_total", labels("op", op), float64(c.retries[op]))
+	}
+
+	header(bw, ns+"_in_flight_requests", "gauge", "AIOT API calls in progress.")
+	ops = ops[:0]
+	for op := range c.inFlight {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		sample(bw, ns+"_in_flight_requests", labels("op", op), float64(c.inFlight[op]))
+	}
+
+	return bw.Flush()
+}
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sample(w io.Writer, name, labels string, v float64) {
+	fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(v))
+}
+
+// labels ghép các cặp tên/giá trị thành chuỗi label của Prometheus
+func labels(kv ...string) string {
+	var sb strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(kv[i])
+		sb.WriteString(`="`)
+		sb.WriteString(escaper.Replace(kv[i+1]))
+		sb.WriteByte('"')
+	}
+
+	return sb.String()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,73 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/mobifone-aiot/aiot-go/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Collector(t *testing.T) {
+	require := require.New(t)
+
+	srv, _ := aiottest.NewAccountServer(t)
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	collector := metrics.NewCollector(metrics.NewOptions().SetBuckets([]float64{0.5, 1}))
+	client := aiot.NewClientWithOptions(dead.URL, aiot.NewClientOptions().
+		SetMetrics(collector).
+		SetFailoverAddrs(srv.URL))
+
+	token, err := client.Token(aiottest.AccountEmail, aiottest.AccountPassword)
+	require.NoError(err)
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+	require.Error(client.DeleteThing(token, "missing"))
+
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal("text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	out := rec.Body.String()
+	for _, line := range []string{
+		`# TYPE aiot_requests_total counter`,
+		`aiot_requests_total{op="aiot.Token",method="POST",status="201"} 1`,
+		`aiot_requests_total{op="aiot.UserProfile",method="GET",status="200"} 2`,
+		`aiot_errors_total{op="aiot.DeleteThing",kind="http",status="404"} 1`,
+		`aiot_request_duration_seconds_bucket{op="aiot.UserProfile",le="0.5"} 2`,
+		`aiot_request_duration_seconds_bucket{op="aiot.UserProfile",le="+Inf"} 2`,
+		`aiot_request_duration_seconds_count{op="aiot.UserProfile"} 2`,
+		`aiot_retries_total{op="aiot.Token"} 1`,
+		`aiot_in_flight_requests{op="aiot.UserProfile"} 0`,
+	} {
+		require.Contains(out, line+"\n")
+	}
+	require.NotContains(out, `aiot_retries_total{op="aiot.UserProfile"}`)
+}
+
+func Test_ErrorKinds(t *testing.T) {
+	require := require.New(t)
+
+	collector := metrics.NewCollector(nil)
+	collector.Started(`op"x`)
+	collector.Finished(aiot.CallMetric{Op: `op"x`, Method: "GET", Latency: 20 * time.Second, Err: io.EOF, Kind: aiot.KIND_RATE_LIMITED})
+
+	var sb strings.Builder
+	require.NoError(collector.Write(&sb))
+	out := sb.String()
+
+	require.Contains(out, `aiot_errors_total{op="op\"x",kind="rate_limited",status=""} 1`)
+	require.Contains(out, `aiot_request_duration_seconds_bucket{op="op\"x",le="10"} 0`)
+	require.Contains(out, `aiot_request_duration_seconds_sum{op="op\"x"} 20`)
+}
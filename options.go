@@ -1,5 +1,10 @@
 package aiot
 
+import (
+	"net/http"
+	"time"
+)
+
 type Direction string
 type ThingOrder string
 
@@ -162,3 +167,110 @@ func (opts *ListChannelByUserOptions) SetDirection(dir Direction) *ListChannelBy
 	opts.direction = dir
 	return opts
 }
+
+type ClientOptions struct {
+	httpClient    *http.Client
+	limit         RateLimit
+	routeLimits   map[string]RateLimit
+	limitPolicy   LimitPolicy
+	breaker       *CircuitBreaker
+	failoverAddrs []string
+	probeInterval time.Duration
+	interceptors  []Interceptor
+	logger        Logger
+	metrics       Metrics
+	tracer        Tracer
+	patchRetries  int
+	now           func() time.Time
+}
+
+func NewClientOptions() *ClientOptions {
+	return &ClientOptions{
+		httpClient:    &http.Client{},
+		limitPolicy:   LIMIT_POLICY_WAIT,
+		probeInterval: 30 * time.Second,
+		patchRetries:  5,
+		now:           time.Now,
+	}
+}
+
+// Dùng http.Client riêng, ví dụ để cấu hình timeout hoặc RoundTripper
+func (opts *ClientOptions) SetHTTPClient(client *http.Client) *ClientOptions {
+	opts.httpClient = client
+	return opts
+}
+
+// Giới hạn chung cho mọi request của Client (và các bản sao của nó)
+func (opts *ClientOptions) SetRateLimit(limit RateLimit) *ClientOptions {
+	opts.limit = limit
+	return opts
+}
+
+// Giới hạn riêng cho một route, áp dụng thêm vào giới hạn chung. Route là
+// path sau /api-gw/v1, ví dụ "/thing/connect".
+func (opts *ClientOptions) SetRouteRateLimit(route string, limit RateLimit) *ClientOptions {
+	if opts.routeLimits == nil {
+		opts.routeLimits = make(map[string]RateLimit)
+	}
+	opts.routeLimits[route] = limit
+	return opts
+}
+
+// Cách xử lý request vượt giới hạn, mặc định là LIMIT_POLICY_WAIT
+func (opts *ClientOptions) SetLimitPolicy(policy LimitPolicy) *ClientOptions {
+	opts.limitPolicy = policy
+	return opts
+}
+
+// Dùng circuit breaker để từ chối request ngay khi gateway liên tục lỗi
+func (opts *ClientOptions) SetCircuitBreaker(cb CircuitBreaker) *ClientOptions {
+	opts.breaker = &cb
+	return opts
+}
+
+// Các địa chỉ gateway dự phòng theo thứ tự ưu tiên, được dùng khi địa chỉ
+// chính không kết nối được hoặc trả về lỗi 5xx
+func (opts *ClientOptions) SetFailoverAddrs(addrs ...string) *ClientOptions {
+	opts.failoverAddrs = addrs
+	return opts
+}
+
+// Khoảng thời gian trước khi thử lại một địa chỉ gateway bị lỗi, mặc định
+// 30 giây
+func (opts *ClientOptions) SetProbeInterval(d time.Duration) *ClientOptions {
+	opts.probeInterval = d
+	return opts
+}
+
+// Thêm interceptor vào chuỗi interceptor của Client. Interceptor được thêm
+// trước nằm ngoài interceptor được thêm sau.
+func (opts *ClientOptions) AddInterceptor(interceptors ...Interceptor) *ClientOptions {
+	opts.interceptors = append(opts.interceptors, interceptors...)
+	return opts
+}
+
+// Ghi log có cấu trúc cho mỗi lời gọi API, xem LoggingInterceptor. Log được
+// ghi sau các interceptor khác, với request thực sự được gửi đi.
+func (opts *ClientOptions) SetLogger(l Logger) *ClientOptions {
+	opts.logger = l
+	return opts
+}
+
+// Gửi số liệu của các lời gọi API đến m, xem MetricsInterceptor
+func (opts *ClientOptions) SetMetrics(m Metrics) *ClientOptions {
+	opts.metrics = m
+	return opts
+}
+
+// Tạo span cho mỗi lời gọi API, xem TracingInterceptor
+func (opts *ClientOptions) SetTracer(t Tracer) *ClientOptions {
+	opts.tracer = t
+	return opts
+}
+
+// Số lần thử lại của PatchThingMetadata/PatchChannelMetadata khi metadata bị
+// thay đổi đồng thời, mặc định 5
+func (opts *ClientOptions) SetPatchRetries(n int) *ClientOptions {
+	opts.patchRetries = n
+	return opts
+}
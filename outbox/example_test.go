@@ -0,0 +1,35 @@
+package outbox_test
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go/outbox"
+)
+
+func ExampleNewPublisher() {
+	// Gửi message qua hàng đợi trên đĩa, message được giữ lại khi mất kết nối
+
+	send := func(m outbox.Message) error {
+		// gửi m.Payload đến channel m.ChannelID
+		return nil
+	}
+
+	opts := outbox.NewOptions().
+		SetMaxBytes(16 << 20).
+		SetMaxAge(24 * time.Hour).
+		SetDropPolicy(outbox.DROP_OLDEST)
+
+	p, err := outbox.NewPublisher("/var/lib/aiot/outbox", send, opts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer p.Close()
+
+	if err := p.Publish("c9f0f895-fb98-4b91-8c2e-6a7d3e1f5b24", []byte(`{"temp": 25}`)); err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("Queue depth: %d", p.Stats().Depth)
+}
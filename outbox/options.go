@@ -0,0 +1,113 @@
+package outbox
+
+import "time"
+
+type DropPolicy string
+type DropReason string
+
+var (
+	// Bỏ message cũ nhất để nhường chỗ cho message mới
+	DROP_OLDEST DropPolicy = "oldest"
+	// Từ chối message mới khi hàng đợi đã đầy
+	DROP_NEWEST DropPolicy = "newest"
+
+	DROP_REASON_FULL     DropReason = "full"
+	DROP_REASON_EXPIRED  DropReason = "expired"
+	DROP_REASON_ATTEMPTS DropReason = "attempts"
+	DROP_REASON_REJECTED DropReason = "rejected"
+)
+
+// Thời gian chờ tối thiểu giữa hai lần gửi lại
+const MIN_BACKOFF = time.Millisecond
+
+type Options struct {
+	maxMessages int
+	maxBytes    int64
+	maxAge      time.Duration
+	dropPolicy  DropPolicy
+	sync        bool
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxAttempts int
+	onDrop      func(Message, DropReason)
+
+	skipCorrupted bool
+}
+
+func NewOptions() *Options {
+	return &Options{
+		maxMessages: 100000,
+		maxBytes:    64 << 20,
+		maxAge:      0,
+		dropPolicy:  DROP_OLDEST,
+		sync:        true,
+		minBackoff:  time.Second,
+		maxBackoff:  time.Minute,
+		maxAttempts: 0,
+	}
+}
+
+// Số message tối đa trong hàng đợi, 0 là không giới hạn
+func (opts *Options) SetMaxMessages(n int) *Options {
+	opts.maxMessages = n
+	return opts
+}
+
+// Dung lượng tối đa (byte) của các message trong hàng đợi, 0 là không giới hạn
+func (opts *Options) SetMaxBytes(n int64) *Options {
+	opts.maxBytes = n
+	return opts
+}
+
+// Thời gian sống tối đa của một message, 0 là không giới hạn
+func (opts *Options) SetMaxAge(d time.Duration) *Options {
+	opts.maxAge = d
+	return opts
+}
+
+func (opts *Options) SetDropPolicy(policy DropPolicy) *Options {
+	opts.dropPolicy = policy
+	return opts
+}
+
+// Gọi fsync sau mỗi lần ghi vào hàng đợi
+func (opts *Options) SetSync(sync bool) *Options {
+	opts.sync = sync
+	return opts
+}
+
+// Thời gian chờ giữa hai lần gửi lại, tăng gấp đôi từ min đến max. Giá trị
+// nhỏ hơn MIN_BACKOFF được nâng lên MIN_BACKOFF, max nhỏ hơn min được nâng
+// lên min.
+func (opts *Options) SetBackoff(min, max time.Duration) *Options {
+	if min < MIN_BACKOFF {
+		min = MIN_BACKOFF
+	}
+	if max < min {
+		max = min
+	}
+
+	opts.minBackoff = min
+	opts.maxBackoff = max
+	return opts
+}
+
+// Số lần gửi tối đa cho một message trước khi bỏ, 0 là không giới hạn
+func (opts *Options) SetMaxAttempts(n int) *Options {
+	opts.maxAttempts = n
+	return opts
+}
+
+// Bỏ qua record hỏng trong file log thay vì trả về lỗi ErrCorrupted. Phần
+// hỏng được chép vào file queue.corrupt trong thư mục của hàng đợi để kiểm
+// tra sau.
+func (opts *Options) SetSkipCorrupted(skip bool) *Options {
+	opts.skipCorrupted = skip
+	return opts
+}
+
+// Hàm được gọi mỗi khi một message bị bỏ
+func (opts *Options) SetOnDrop(fn func(Message, DropReason)) *Options {
+	opts.onDrop = fn
+	return opts
+}
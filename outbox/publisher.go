@@ -0,0 +1,245 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Hàm thực hiện gửi một message lên nền tảng AIOT. Trả về lỗi để message
+// được gửi lại sau, hoặc Permanent(err) nếu message không bao giờ gửi được.
+type SendFunc func(m Message) error
+
+type Stats struct {
+	Depth     int
+	Bytes     int64
+	OldestAge time.Duration
+	Enqueued  uint64
+	Sent      uint64
+	Dropped   uint64
+	Retries   uint64
+	// Số record hỏng đã bỏ qua, xem Options.SetSkipCorrupted
+	Corrupted uint64
+	LastError error
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Đánh dấu lỗi không thể gửi lại, message sẽ bị bỏ ngay
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Publisher ghi message vào hàng đợi trên đĩa và gửi chúng theo đúng thứ tự
+// khi gateway có thể kết nối được
+type Publisher struct {
+	q    *Queue
+	send SendFunc
+	opts *Options
+
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	enqueued uint64
+	sent     uint64
+	retries  uint64
+	lastErr  error
+}
+
+// Tạo mới một Publisher dùng hàng đợi trong thư mục dir và bắt đầu gửi các
+// message còn tồn đọng từ lần chạy trước
+func NewPublisher(dir string, send SendFunc, opts *Options) (*Publisher, error) {
+	const op = "outbox.NewPublisher"
+
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	q, err := Open(dir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	p := &Publisher{
+		q:       q,
+		send:    send,
+		opts:    opts,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p, nil
+}
+
+// Đưa payload vào hàng đợi để gửi đến channel
+func (p *Publisher) Publish(channelID string, payload []byte) error {
+	return p.PublishMessage(Message{ChannelID: channelID, Payload: payload})
+}
+
+func (p *Publisher) PublishMessage(m Message) error {
+	if err := p.q.Append(m); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.enqueued++
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Thông tin về hàng đợi và số lượng message đã gửi
+func (p *Publisher) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return Stats{
+		Depth:     p.q.Len(),
+		Bytes:     p.q.Bytes(),
+		OldestAge: p.q.OldestAge(),
+		Enqueued:  p.enqueued,
+		Sent:      p.sent,
+		Dropped:   p.q.Dropped(),
+		Retries:   p.retries,
+		Corrupted: p.q.Corrupted(),
+		LastError: p.lastErr,
+	}
+}
+
+// Dừng gửi và đóng hàng đợi. Các message chưa gửi vẫn được giữ trên đĩa.
+// Close chờ lần gửi đang thực hiện (nếu có) kết thúc.
+func (p *Publisher) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+	})
+	<-p.stopped
+
+	return p.q.Close()
+}
+
+func (p *Publisher) run() {
+	defer close(p.stopped)
+
+	attempts := 0
+	backoff := p.opts.minBackoff
+
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		if err := p.q.Expire(); err != nil {
+			p.setErr(err)
+		}
+
+		m, err := p.q.Peek()
+		if errors.Is(err, ErrEmpty) {
+			select {
+			case <-p.wake:
+			case <-p.done:
+				return
+			}
+			continue
+		}
+		if err != nil {
+			p.setErr(err)
+			if !p.sleep(backoff) {
+				return
+			}
+			continue
+		}
+
+		err = p.send(m)
+		if err == nil {
+			if err := p.q.Ack(); err != nil {
+				p.setErr(err)
+			}
+
+			p.mu.Lock()
+			p.sent++
+			p.mu.Unlock()
+
+			attempts = 0
+			backoff = p.opts.minBackoff
+			continue
+		}
+
+		p.setErr(err)
+		attempts++
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			p.q.Drop(DROP_REASON_REJECTED)
+			attempts = 0
+			continue
+		}
+
+		if p.opts.maxAttempts > 0 && attempts >= p.opts.maxAttempts {
+			p.q.Drop(DROP_REASON_ATTEMPTS)
+			attempts = 0
+			backoff = p.opts.minBackoff
+			continue
+		}
+
+		p.mu.Lock()
+		p.retries++
+		p.mu.Unlock()
+
+		if !p.sleep(backoff) {
+			return
+		}
+
+		backoff *= 2
+		if backoff > p.opts.maxBackoff {
+			backoff = p.opts.maxBackoff
+		}
+	}
+}
+
+// sleep chờ trong khoảng [d/2, d] và trả về false nếu Publisher bị đóng.
+func (p *Publisher) sleep(d time.Duration) bool {
+	if d > 1 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)))
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+func (p *Publisher) setErr(err error) {
+	p.mu.Lock()
+	p.lastErr = err
+	p.mu.Unlock()
+}
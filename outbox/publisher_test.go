@@ -0,0 +1,174 @@
+package outbox_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu       sync.Mutex
+	fail     int
+	attempts int
+	got      []string
+}
+
+func (r *recorder) send(m outbox.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts++
+	if r.fail > 0 {
+		r.fail--
+		return errors.New("gateway unreachable")
+	}
+
+	r.got = append(r.got, string(m.Payload))
+	return nil
+}
+
+func (r *recorder) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.got...)
+}
+
+func Test_Publisher_RetryInOrder(t *testing.T) {
+	require := require.New(t)
+
+	r := &recorder{fail: 3}
+	opts := outbox.NewOptions().SetBackoff(time.Millisecond, 5*time.Millisecond)
+
+	p, err := outbox.NewPublisher(t.TempDir(), r.send, opts)
+	require.NoError(err)
+	defer p.Close()
+
+	for _, m := range []string{"a", "b", "c"} {
+		require.NoError(p.Publish("ch-1", []byte(m)))
+	}
+
+	require.Eventually(func() bool {
+		return p.Stats().Sent == 3
+	}, 2*time.Second, time.Millisecond)
+
+	require.Equal([]string{"a", "b", "c"}, r.received())
+
+	stats := p.Stats()
+	require.Equal(0, stats.Depth)
+	require.Equal(uint64(3), stats.Enqueued)
+	require.Equal(uint64(3), stats.Sent)
+	require.Equal(uint64(3), stats.Retries)
+	require.Error(stats.LastError)
+}
+
+func Test_Publisher_ResumeAfterRestart(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	offline := func(outbox.Message) error { return errors.New("offline") }
+	opts := outbox.NewOptions().SetBackoff(time.Hour, time.Hour)
+
+	p, err := outbox.NewPublisher(dir, offline, opts)
+	require.NoError(err)
+
+	require.NoError(p.Publish("ch-1", []byte("a")))
+	require.NoError(p.Publish("ch-1", []byte("b")))
+	require.NoError(p.Close())
+
+	r := &recorder{}
+	p, err = outbox.NewPublisher(dir, r.send, outbox.NewOptions())
+	require.NoError(err)
+	defer p.Close()
+
+	require.Eventually(func() bool {
+		return len(r.received()) == 2
+	}, 2*time.Second, time.Millisecond)
+	require.Equal([]string{"a", "b"}, r.received())
+}
+
+func Test_Publisher_DropAfterAttempts(t *testing.T) {
+	require := require.New(t)
+
+	r := &recorder{fail: 2}
+
+	var mu sync.Mutex
+	var reasons []outbox.DropReason
+	opts := outbox.NewOptions().
+		SetBackoff(time.Millisecond, time.Millisecond).
+		SetMaxAttempts(2).
+		SetOnDrop(func(m outbox.Message, reason outbox.DropReason) {
+			mu.Lock()
+			reasons = append(reasons, reason)
+			mu.Unlock()
+		})
+
+	p, err := outbox.NewPublisher(t.TempDir(), r.send, opts)
+	require.NoError(err)
+	defer p.Close()
+
+	require.NoError(p.Publish("ch-1", []byte("a")))
+	require.NoError(p.Publish("ch-1", []byte("b")))
+
+	require.Eventually(func() bool {
+		return p.Stats().Sent == 1
+	}, 2*time.Second, time.Millisecond)
+
+	require.Equal([]string{"b"}, r.received())
+	require.Equal(uint64(1), p.Stats().Dropped)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal([]outbox.DropReason{outbox.DROP_REASON_ATTEMPTS}, reasons)
+}
+
+func Test_Publisher_Permanent(t *testing.T) {
+	require := require.New(t)
+
+	var mu sync.Mutex
+	var got []string
+	send := func(m outbox.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if string(m.Payload) == "bad" {
+			return outbox.Permanent(errors.New("rejected"))
+		}
+		got = append(got, string(m.Payload))
+		return nil
+	}
+
+	p, err := outbox.NewPublisher(t.TempDir(), send, outbox.NewOptions())
+	require.NoError(err)
+	defer p.Close()
+
+	require.NoError(p.Publish("ch-1", []byte("bad")))
+	require.NoError(p.Publish("ch-1", []byte("good")))
+
+	require.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 1
+	}, 2*time.Second, time.Millisecond)
+
+	require.Equal(uint64(1), p.Stats().Dropped)
+}
+
+func Test_Publisher_ZeroBackoff(t *testing.T) {
+	require := require.New(t)
+
+	r := &recorder{fail: 1 << 30}
+	p, err := outbox.NewPublisher(t.TempDir(), r.send, outbox.NewOptions().SetBackoff(0, 0))
+	require.NoError(err)
+
+	require.NoError(p.Publish("ch-1", []byte("a")))
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(p.Close())
+
+	// backoff được nâng lên MIN_BACKOFF nên vòng gửi không quay liên tục
+	require.Less(p.Stats().Retries, uint64(100))
+}
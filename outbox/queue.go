@@ -0,0 +1,733 @@
+package outbox
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull = errors.New("queue is full")
+	ErrTooLarge  = errors.New("message is larger than queue capacity")
+	ErrClosed    = errors.New("queue is closed")
+	ErrEmpty     = errors.New("queue is empty")
+	ErrCorrupted = errors.New("corrupted record")
+
+	// record không đọc được: ghi dở ở cuối file hoặc hỏng
+	errInvalid = errors.New("invalid record")
+)
+
+// CorruptError là record hỏng nằm giữa file log, sau nó vẫn còn record đọc
+// được. errors.Is(err, ErrCorrupted) trả về true với lỗi này.
+type CorruptError struct {
+	// Vị trí và độ dài (byte) của phần hỏng trong file log
+	Offset int64
+	Size   int64
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("corrupted record at offset %d (%d bytes)", e.Offset, e.Size)
+}
+
+func (e *CorruptError) Is(target error) bool {
+	return target == ErrCorrupted
+}
+
+const (
+	ackFile    = "queue.ack"
+	badFile    = "queue.corrupt"
+	logPrefix  = "queue-"
+	logSuffix  = ".log"
+	headerSize = 8
+
+	// Dung lượng phần đã ack tối thiểu trước khi ghi lại file log
+	compactThreshold = 1 << 20
+)
+
+// Message chờ gửi đến một channel của nền tảng AIOT
+type Message struct {
+	ChannelID string    `json:"channelId"`
+	Subtopic  string    `json:"subtopic,omitempty"`
+	Payload   []byte    `json:"payload"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type entry struct {
+	off     int64
+	size    int64
+	created time.Time
+}
+
+// Queue là hàng đợi append-only lưu trên đĩa.
+//
+// Các message được ghi nối tiếp vào một file log, vị trí của message đầu
+// tiên chưa được ack được lưu trong file queue.ack. Khi phần đã ack đủ lớn,
+// phần còn lại được chép sang một file log mới (generation mới).
+type Queue struct {
+	mu      sync.Mutex
+	dir     string
+	opts    *Options
+	gen     uint64
+	file    *os.File
+	head    int64
+	tail    int64
+	entries []entry
+	bytes   int64
+	dropped uint64
+	// số phần hỏng đã bỏ qua
+	corrupted uint64
+	closed    bool
+}
+
+// Mở (hoặc tạo mới) hàng đợi trong thư mục dir
+func Open(dir string, opts *Options) (*Queue, error) {
+	const op = "outbox.Open"
+
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	gen, head, err := readAck(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	if err := removeStaleLogs(dir, gen); err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	f, err := os.OpenFile(logPath(dir, gen), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	q := &Queue{dir: dir, opts: opts, gen: gen, file: f, head: head}
+	if err := q.load(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return q, nil
+}
+
+// load quét file log từ vị trí head để dựng lại danh sách message. Record
+// không đọc được mà sau nó không còn record nào hợp lệ là record bị ghi dở ở
+// cuối file (do mất điện giữa chừng) và bị cắt khỏi file. Record hỏng nằm
+// giữa file trả về *CorruptError để không âm thầm bỏ các message sau nó, hoặc
+// được chép sang file queue.corrupt rồi bỏ qua nếu bật SetSkipCorrupted.
+func (q *Queue) load() error {
+	info, err := q.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	if q.head > size {
+		q.head = size
+	}
+
+	off := q.head
+	for off < size {
+		m, n, err := q.readAt(off, size)
+		if errors.Is(err, errInvalid) {
+			next, err := q.resync(off+1, size)
+			if err != nil {
+				return err
+			}
+
+			if next < 0 {
+				if err := q.file.Truncate(off); err != nil {
+					return err
+				}
+				break
+			}
+
+			if err := q.skip(off, next-off); err != nil {
+				return err
+			}
+			off = next
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		q.entries = append(q.entries, entry{off: off, size: n, created: m.CreatedAt})
+		q.bytes += n
+		off += n
+	}
+
+	q.tail = off
+	return nil
+}
+
+// Thêm một message vào cuối hàng đợi
+func (q *Queue) Append(m Message) error {
+	const op = "outbox.Append"
+
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+
+	rec, err := encodeRecord(m)
+	if err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+	size := int64(len(rec))
+
+	q.mu.Lock()
+	var drops []drop
+	defer func() {
+		q.mu.Unlock()
+		q.notify(drops)
+	}()
+
+	if q.closed {
+		return fmt.Errorf("%s -> %w", op, ErrClosed)
+	}
+
+	if q.opts.maxBytes > 0 && size > q.opts.maxBytes {
+		q.dropped++
+		drops = append(drops, drop{m, DROP_REASON_FULL})
+		return fmt.Errorf("%s -> %w", op, ErrTooLarge)
+	}
+
+	if drops, err = q.expire(time.Now()); err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	for q.full(size) {
+		if q.opts.dropPolicy == DROP_NEWEST {
+			q.dropped++
+			drops = append(drops, drop{m, DROP_REASON_FULL})
+			return fmt.Errorf("%s -> %w", op, ErrQueueFull)
+		}
+
+		old, err := q.pop()
+		if errors.Is(err, ErrEmpty) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s -> %w", op, err)
+		}
+		q.dropped++
+		drops = append(drops, drop{old, DROP_REASON_FULL})
+	}
+
+	if _, err := q.file.WriteAt(rec, q.tail); err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	if q.opts.sync {
+		if err := q.file.Sync(); err != nil {
+			return fmt.Errorf("%s -> %w", op, err)
+		}
+	}
+
+	q.entries = append(q.entries, entry{off: q.tail, size: size, created: m.CreatedAt})
+	q.bytes += size
+	q.tail += size
+
+	return nil
+}
+
+// Đọc message đầu hàng đợi mà không xóa nó. Trả về ErrEmpty nếu hàng đợi rỗng
+func (q *Queue) Peek() (Message, error) {
+	const op = "outbox.Peek"
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return Message{}, fmt.Errorf("%s -> %w", op, ErrClosed)
+	}
+
+	if len(q.entries) == 0 {
+		return Message{}, fmt.Errorf("%s -> %w", op, ErrEmpty)
+	}
+
+	m, err := q.front()
+	if err != nil {
+		return Message{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return m, nil
+}
+
+// Xóa message đầu hàng đợi sau khi đã gửi thành công
+func (q *Queue) Ack() error {
+	const op = "outbox.Ack"
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return fmt.Errorf("%s -> %w", op, ErrClosed)
+	}
+
+	if len(q.entries) == 0 {
+		return fmt.Errorf("%s -> %w", op, ErrEmpty)
+	}
+
+	if err := q.advance(); err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return nil
+}
+
+// Bỏ message đầu hàng đợi với lý do reason
+func (q *Queue) Drop(reason DropReason) error {
+	const op = "outbox.Drop"
+
+	q.mu.Lock()
+	var drops []drop
+	defer func() {
+		q.mu.Unlock()
+		q.notify(drops)
+	}()
+
+	if q.closed {
+		return fmt.Errorf("%s -> %w", op, ErrClosed)
+	}
+
+	if len(q.entries) == 0 {
+		return fmt.Errorf("%s -> %w", op, ErrEmpty)
+	}
+
+	m, err := q.pop()
+	if err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+	q.dropped++
+	drops = append(drops, drop{m, reason})
+
+	return nil
+}
+
+// Bỏ các message đã quá thời gian sống
+func (q *Queue) Expire() error {
+	const op = "outbox.Expire"
+
+	q.mu.Lock()
+	var drops []drop
+	defer func() {
+		q.mu.Unlock()
+		q.notify(drops)
+	}()
+
+	if q.closed {
+		return fmt.Errorf("%s -> %w", op, ErrClosed)
+	}
+
+	var err error
+	if drops, err = q.expire(time.Now()); err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return nil
+}
+
+// Số message đang chờ trong hàng đợi
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.entries)
+}
+
+// Tổng dung lượng (byte) các message đang chờ trong hàng đợi
+func (q *Queue) Bytes() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.bytes
+}
+
+// Tuổi của message cũ nhất trong hàng đợi
+func (q *Queue) OldestAge() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.entries) == 0 {
+		return 0
+	}
+
+	return time.Since(q.entries[0].created)
+}
+
+// Số message đã bị bỏ kể từ khi mở hàng đợi
+func (q *Queue) Dropped() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.dropped
+}
+
+// Số record hỏng đã được bỏ qua kể từ khi mở hàng đợi (xem SetSkipCorrupted)
+func (q *Queue) Corrupted() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.corrupted
+}
+
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+
+	return q.file.Close()
+}
+
+type drop struct {
+	m      Message
+	reason DropReason
+}
+
+// notify gọi hàm onDrop bên ngoài lock để hàm này có thể dùng lại Queue.
+func (q *Queue) notify(drops []drop) {
+	if q.opts.onDrop == nil {
+		return
+	}
+
+	for _, d := range drops {
+		q.opts.onDrop(d.m, d.reason)
+	}
+}
+
+func (q *Queue) full(size int64) bool {
+	if len(q.entries) == 0 {
+		return false
+	}
+
+	if q.opts.maxMessages > 0 && len(q.entries)+1 > q.opts.maxMessages {
+		return true
+	}
+
+	return q.opts.maxBytes > 0 && q.bytes+size > q.opts.maxBytes
+}
+
+func (q *Queue) expire(now time.Time) ([]drop, error) {
+	if q.opts.maxAge <= 0 {
+		return nil, nil
+	}
+
+	var drops []drop
+	for len(q.entries) > 0 && now.Sub(q.entries[0].created) > q.opts.maxAge {
+		m, err := q.pop()
+		if errors.Is(err, ErrEmpty) {
+			break
+		}
+		if err != nil {
+			return drops, err
+		}
+		q.dropped++
+		drops = append(drops, drop{m, DROP_REASON_EXPIRED})
+	}
+
+	return drops, nil
+}
+
+// front đọc message đầu hàng đợi. Record hỏng trả về *CorruptError, hoặc bị
+// bỏ qua nếu bật SetSkipCorrupted; ErrEmpty nếu mọi record đều đã bị bỏ qua.
+func (q *Queue) front() (Message, error) {
+	for len(q.entries) > 0 {
+		e := q.entries[0]
+
+		m, _, err := q.readAt(e.off, q.tail)
+		if !errors.Is(err, errInvalid) {
+			return m, err
+		}
+
+		if err := q.skip(e.off, e.size); err != nil {
+			return Message{}, err
+		}
+		if err := q.advance(); err != nil {
+			return Message{}, err
+		}
+	}
+
+	return Message{}, ErrEmpty
+}
+
+func (q *Queue) pop() (Message, error) {
+	m, err := q.front()
+	if err != nil {
+		return Message{}, err
+	}
+
+	return m, q.advance()
+}
+
+// advance bỏ message đầu hàng đợi. Head trỏ đến message kế tiếp để phần hỏng
+// đã bỏ qua (nếu có) nằm trước head.
+func (q *Queue) advance() error {
+	e := q.entries[0]
+	q.entries = q.entries[1:]
+	q.bytes -= e.size
+
+	if len(q.entries) == 0 {
+		return q.reset()
+	}
+	q.head = q.entries[0].off
+
+	if q.head >= compactThreshold && q.head > q.tail/2 {
+		return q.compact()
+	}
+
+	return writeAck(q.dir, q.gen, q.head, q.opts.sync)
+}
+
+// reset làm rỗng file log khi mọi message đã được ack. Nếu tiến trình dừng
+// trước khi ghi file ack thì head lớn hơn kích thước file và sẽ được đưa về 0
+// khi mở lại.
+func (q *Queue) reset() error {
+	if err := q.file.Truncate(0); err != nil {
+		return err
+	}
+
+	q.head = 0
+	q.tail = 0
+
+	return writeAck(q.dir, q.gen, 0, q.opts.sync)
+}
+
+// compact chép các record chưa ack sang file log của generation kế tiếp.
+// File ack chỉ trỏ sang file mới sau khi file mới đã được ghi xong, nên nếu
+// tiến trình dừng giữa chừng thì hàng đợi vẫn mở lại được từ file cũ.
+func (q *Queue) compact() error {
+	gen := q.gen + 1
+
+	f, err := os.OpenFile(logPath(q.dir, gen), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(f, io.NewSectionReader(q.file, q.head, q.tail-q.head))
+	if err == nil && q.opts.sync {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = writeAck(q.dir, gen, 0, q.opts.sync)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(logPath(q.dir, gen))
+		return err
+	}
+
+	q.file.Close()
+	os.Remove(logPath(q.dir, q.gen))
+
+	shift := q.head
+	for i := range q.entries {
+		q.entries[i].off -= shift
+	}
+
+	q.file = f
+	q.gen = gen
+	q.head = 0
+	q.tail = n
+
+	return nil
+}
+
+// readAt đọc record tại off, record phải nằm trọn trước limit. Record vượt
+// quá limit hoặc sai checksum trả về errInvalid.
+func (q *Queue) readAt(off, limit int64) (Message, int64, error) {
+	var header [headerSize]byte
+	if off+headerSize > limit {
+		return Message{}, 0, errInvalid
+	}
+	if _, err := q.file.ReadAt(header[:], off); err != nil {
+		return Message{}, 0, err
+	}
+
+	length := int64(binary.BigEndian.Uint32(header[0:4]))
+	if off+headerSize+length > limit {
+		return Message{}, 0, errInvalid
+	}
+
+	data := make([]byte, length)
+	if _, err := q.file.ReadAt(data, off+headerSize); err != nil {
+		return Message{}, 0, err
+	}
+
+	m, ok := decodeRecord(data, binary.BigEndian.Uint32(header[4:8]))
+	if !ok {
+		return Message{}, 0, errInvalid
+	}
+
+	return m, headerSize + length, nil
+}
+
+// resync tìm vị trí đầu tiên từ from có một record hợp lệ nằm trọn trước
+// limit, -1 nếu không có
+func (q *Queue) resync(from, limit int64) (int64, error) {
+	if from >= limit {
+		return -1, nil
+	}
+
+	buf := make([]byte, limit-from)
+	if _, err := q.file.ReadAt(buf, from); err != nil {
+		return 0, err
+	}
+
+	for i := 0; i+headerSize <= len(buf); i++ {
+		length := int(binary.BigEndian.Uint32(buf[i : i+4]))
+		if length > len(buf)-i-headerSize {
+			continue
+		}
+
+		data := buf[i+headerSize : i+headerSize+length]
+		if _, ok := decodeRecord(data, binary.BigEndian.Uint32(buf[i+4:i+8])); ok {
+			return from + int64(i), nil
+		}
+	}
+
+	return -1, nil
+}
+
+// skip xử lý phần hỏng [off, off+size) của file log: trả về *CorruptError,
+// hoặc chép phần hỏng vào cuối file queue.corrupt nếu bật SetSkipCorrupted
+func (q *Queue) skip(off, size int64) error {
+	if !q.opts.skipCorrupted {
+		return &CorruptError{Offset: off, Size: size}
+	}
+
+	f, err := os.OpenFile(filepath.Join(q.dir, badFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, io.NewSectionReader(q.file, off, size))
+	if err == nil && q.opts.sync {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	q.corrupted++
+	return f.Close()
+}
+
+func decodeRecord(data []byte, sum uint32) (Message, bool) {
+	var m Message
+	if crc32.ChecksumIEEE(data) != sum || json.Unmarshal(data, &m) != nil {
+		return Message{}, false
+	}
+
+	return m, true
+}
+
+func encodeRecord(m Message) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.ChecksumIEEE(data))
+	copy(rec[headerSize:], data)
+
+	return rec, nil
+}
+
+func logPath(dir string, gen uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%d%s", logPrefix, gen, logSuffix))
+}
+
+func readAck(dir string) (uint64, int64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ackFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("invalid ack file: %q", data)
+	}
+
+	gen, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid ack file: %w", err)
+	}
+
+	head, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid ack file: %w", err)
+	}
+
+	return gen, head, nil
+}
+
+// writeAck ghi file ack qua một file tạm rồi rename để tránh file bị ghi dở.
+func writeAck(dir string, gen uint64, head int64, sync bool) error {
+	tmp := filepath.Join(dir, ackFile+".tmp")
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(f, "%d %d\n", gen, head); err != nil {
+		f.Close()
+		return err
+	}
+
+	if sync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(dir, ackFile))
+}
+
+func removeStaleLogs(dir string, gen uint64) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	current := filepath.Base(logPath(dir, gen))
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, logPrefix) && strings.HasSuffix(name, logSuffix) && name != current {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,281 @@
+package outbox_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go/outbox"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Queue_Order(t *testing.T) {
+	require := require.New(t)
+
+	q, err := outbox.Open(t.TempDir(), outbox.NewOptions())
+	require.NoError(err)
+	defer q.Close()
+
+	for _, p := range []string{"a", "b", "c"} {
+		require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte(p)}))
+	}
+	require.Equal(3, q.Len())
+
+	for _, p := range []string{"a", "b", "c"} {
+		m, err := q.Peek()
+		require.NoError(err)
+		require.Equal(p, string(m.Payload))
+		require.Equal("ch-1", m.ChannelID)
+		require.NoError(q.Ack())
+	}
+
+	_, err = q.Peek()
+	require.ErrorIs(err, outbox.ErrEmpty)
+	require.Equal(int64(0), q.Bytes())
+}
+
+func Test_Queue_Reopen(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	q, err := outbox.Open(dir, outbox.NewOptions())
+	require.NoError(err)
+
+	for _, p := range []string{"a", "b", "c"} {
+		require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte(p)}))
+	}
+	require.NoError(q.Ack())
+	require.NoError(q.Close())
+
+	q, err = outbox.Open(dir, outbox.NewOptions())
+	require.NoError(err)
+	defer q.Close()
+
+	require.Equal(2, q.Len())
+
+	m, err := q.Peek()
+	require.NoError(err)
+	require.Equal("b", string(m.Payload))
+}
+
+func Test_Queue_TornWrite(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	q, err := outbox.Open(dir, outbox.NewOptions())
+	require.NoError(err)
+	require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte("a")}))
+	require.NoError(q.Close())
+
+	logs, err := filepath.Glob(filepath.Join(dir, "queue-*.log"))
+	require.NoError(err)
+	require.Len(logs, 1)
+
+	f, err := os.OpenFile(logs[0], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(err)
+	_, err = f.Write([]byte{0, 0, 0, 42, 1, 2})
+	require.NoError(err)
+	require.NoError(f.Close())
+
+	q, err = outbox.Open(dir, outbox.NewOptions())
+	require.NoError(err)
+	defer q.Close()
+
+	require.Equal(1, q.Len())
+	require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte("b")}))
+	require.Equal(2, q.Len())
+}
+
+func Test_Queue_CorruptRecord(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	q, err := outbox.Open(dir, outbox.NewOptions())
+	require.NoError(err)
+	for _, p := range []string{"a", "b", "c"} {
+		require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte(p)}))
+	}
+	require.NoError(q.Close())
+
+	logs, err := filepath.Glob(filepath.Join(dir, "queue-*.log"))
+	require.NoError(err)
+	data, err := os.ReadFile(logs[0])
+	require.NoError(err)
+	info, err := os.Stat(logs[0])
+	require.NoError(err)
+	first := int64(binary.BigEndian.Uint32(data[0:4])) + 8
+
+	// hỏng dữ liệu hoặc độ dài của record đầu tiên, các record sau vẫn còn
+	// nguyên nên file không bị cắt
+	for _, i := range []int{10, 0} {
+		corrupt := append([]byte(nil), data...)
+		corrupt[i] ^= 0xff
+		require.NoError(os.WriteFile(logs[0], corrupt, 0o600))
+
+		_, err = outbox.Open(dir, outbox.NewOptions())
+		require.True(errors.Is(err, outbox.ErrCorrupted), err)
+		var ce *outbox.CorruptError
+		require.True(errors.As(err, &ce), err)
+		require.Equal(&outbox.CorruptError{Offset: 0, Size: first}, ce)
+
+		after, err := os.Stat(logs[0])
+		require.NoError(err)
+		require.Equal(info.Size(), after.Size())
+	}
+
+	// bỏ qua record hỏng, phần hỏng được chép sang queue.corrupt
+	q, err = outbox.Open(dir, outbox.NewOptions().SetSkipCorrupted(true))
+	require.NoError(err)
+	require.Equal(2, q.Len())
+	require.Equal(uint64(1), q.Corrupted())
+
+	m, err := q.Peek()
+	require.NoError(err)
+	require.Equal("b", string(m.Payload))
+	require.NoError(q.Ack())
+	require.NoError(q.Close())
+
+	bad, err := os.Stat(filepath.Join(dir, "queue.corrupt"))
+	require.NoError(err)
+	require.Equal(first, bad.Size())
+
+	// head đã nằm sau phần hỏng, mở lại không cần bỏ qua
+	q, err = outbox.Open(dir, outbox.NewOptions())
+	require.NoError(err)
+	m, err = q.Peek()
+	require.NoError(err)
+	require.Equal("c", string(m.Payload))
+	require.NoError(q.Close())
+
+	// header ghi dở ở cuối file có độ dài vượt quá phần còn lại của file
+	torn := append(append([]byte(nil), data...), 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0)
+	require.NoError(os.WriteFile(logs[0], torn, 0o600))
+	require.NoError(os.Remove(filepath.Join(dir, "queue.ack")))
+
+	q, err = outbox.Open(dir, outbox.NewOptions())
+	require.NoError(err)
+	defer q.Close()
+	require.Equal(3, q.Len())
+
+	after, err := os.Stat(logs[0])
+	require.NoError(err)
+	require.Equal(info.Size(), after.Size())
+}
+
+func Test_Queue_DropOldest(t *testing.T) {
+	require := require.New(t)
+
+	var dropped []string
+	opts := outbox.NewOptions().
+		SetMaxMessages(2).
+		SetOnDrop(func(m outbox.Message, reason outbox.DropReason) {
+			require.Equal(outbox.DROP_REASON_FULL, reason)
+			dropped = append(dropped, string(m.Payload))
+		})
+
+	q, err := outbox.Open(t.TempDir(), opts)
+	require.NoError(err)
+	defer q.Close()
+
+	for _, p := range []string{"a", "b", "c"} {
+		require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte(p)}))
+	}
+
+	require.Equal(2, q.Len())
+	require.Equal([]string{"a"}, dropped)
+	require.Equal(uint64(1), q.Dropped())
+
+	m, err := q.Peek()
+	require.NoError(err)
+	require.Equal("b", string(m.Payload))
+}
+
+func Test_Queue_DropNewest(t *testing.T) {
+	require := require.New(t)
+
+	opts := outbox.NewOptions().
+		SetMaxMessages(2).
+		SetDropPolicy(outbox.DROP_NEWEST)
+
+	q, err := outbox.Open(t.TempDir(), opts)
+	require.NoError(err)
+	defer q.Close()
+
+	require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte("a")}))
+	require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte("b")}))
+
+	err = q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte("c")})
+	require.ErrorIs(err, outbox.ErrQueueFull)
+	require.Equal(2, q.Len())
+
+	m, err := q.Peek()
+	require.NoError(err)
+	require.Equal("a", string(m.Payload))
+}
+
+func Test_Queue_MaxBytes(t *testing.T) {
+	require := require.New(t)
+
+	q, err := outbox.Open(t.TempDir(), outbox.NewOptions().SetMaxBytes(64))
+	require.NoError(err)
+	defer q.Close()
+
+	err = q.Append(outbox.Message{ChannelID: "ch-1", Payload: make([]byte, 128)})
+	require.ErrorIs(err, outbox.ErrTooLarge)
+	require.Equal(0, q.Len())
+}
+
+func Test_Queue_MaxAge(t *testing.T) {
+	require := require.New(t)
+
+	q, err := outbox.Open(t.TempDir(), outbox.NewOptions().SetMaxAge(time.Minute))
+	require.NoError(err)
+	defer q.Close()
+
+	require.NoError(q.Append(outbox.Message{
+		ChannelID: "ch-1",
+		Payload:   []byte("old"),
+		CreatedAt: time.Now().Add(-time.Hour),
+	}))
+	require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: []byte("new")}))
+
+	require.NoError(q.Expire())
+	require.Equal(1, q.Len())
+
+	m, err := q.Peek()
+	require.NoError(err)
+	require.Equal("new", string(m.Payload))
+}
+
+func Test_Queue_Compact(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	q, err := outbox.Open(dir, outbox.NewOptions().SetSync(false))
+	require.NoError(err)
+
+	payload := make([]byte, 4096)
+	for i := 0; i < 600; i++ {
+		require.NoError(q.Append(outbox.Message{ChannelID: "ch-1", Payload: payload}))
+	}
+	for i := 0; i < 500; i++ {
+		require.NoError(q.Ack())
+	}
+	require.NoError(q.Close())
+
+	logs, err := filepath.Glob(filepath.Join(dir, "queue-*.log"))
+	require.NoError(err)
+	require.Len(logs, 1)
+
+	info, err := os.Stat(logs[0])
+	require.NoError(err)
+	require.Less(info.Size(), int64(200*4096*2))
+
+	q, err = outbox.Open(dir, outbox.NewOptions())
+	require.NoError(err)
+	defer q.Close()
+	require.Equal(100, q.Len())
+}
@@ -0,0 +1,155 @@
+package aiot
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrPatchConflict được trả về (với loại KIND_CONFLICT) khi metadata liên tục
+// bị thay đổi đồng thời và patch không được áp dụng sau số lần thử lại cho
+// phép
+var ErrPatchConflict = errors.New("metadata was modified concurrently")
+
+// PatchThingMetadata áp dụng patch lên metadata của thing theo JSON Merge
+// Patch (xem Metadata.Merge): chỉ các khóa trong patch bị đặt hoặc xóa, các
+// khóa khác được giữ nguyên. Trả về thing sau khi cập nhật.
+//
+// Gateway không hỗ trợ patch nên metadata được đọc, gộp và ghi lại toàn bộ
+// với Version của lần đọc (xem UpdateThingInput.Version). Các patch cùng
+// thing trong một Client (và các bản sao của nó) được thực hiện lần lượt.
+// Nếu thing bị thay đổi bởi tiến trình khác, hoặc metadata đọc lại sau khi
+// ghi không còn chứa patch, lời gọi được thử lại (xem SetPatchRetries) và trả
+// về lỗi KIND_CONFLICT khi hết lượt.
+func (c Client) PatchThingMetadata(token, thingID string, patch Metadata) (Thing, error) {
+	const op operation = "aiot.PatchThingMetadata"
+
+	defer c.patches.lock("thing/" + thingID)()
+
+	var thing Thing
+	err := c.patchMetadata(patch,
+		func() (Metadata, error) {
+			t, err := c.ThingProfile(token, thingID)
+			thing = t
+			return t.Metadata, err
+		},
+		func(m Metadata) error {
+			return c.UpdateThing(token, UpdateThingInput{ID: thingID, Name: thing.Name, Metadata: m, Version: thing.Version})
+		},
+	)
+
+	if err != nil {
+		return Thing{}, makeE(op, err)
+	}
+
+	return thing, nil
+}
+
+// PatchChannelMetadata áp dụng patch lên metadata của channel, tương tự
+// PatchThingMetadata
+func (c Client) PatchChannelMetadata(token, channelID string, patch Metadata) (Channel, error) {
+	const op operation = "aiot.PatchChannelMetadata"
+
+	defer c.patches.lock("channel/" + channelID)()
+
+	var channel Channel
+	err := c.patchMetadata(patch,
+		func() (Metadata, error) {
+			ch, err := c.ChannelProfile(token, channelID)
+			channel = ch
+			return ch.Metadata, err
+		},
+		func(m Metadata) error {
+			return c.UpdateChannel(token, UpdateChannelInput{ID: channelID, Name: channel.Name, Metadata: m, Version: channel.Version})
+		},
+	)
+
+	if err != nil {
+		return Channel{}, makeE(op, err)
+	}
+
+	return channel, nil
+}
+
+// patchMetadata đọc, gộp và ghi metadata cho đến khi metadata đọc lại sau khi
+// ghi đã chứa patch
+func (c Client) patchMetadata(patch Metadata, read func() (Metadata, error), write func(Metadata) error) error {
+	current, err := read()
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		merged := current.Merge(patch)
+		if merged.Equal(current) {
+			return nil
+		}
+
+		werr := write(merged)
+		if werr != nil && !Is(KIND_CONFLICT, werr) {
+			return werr
+		}
+
+		if current, err = read(); err != nil {
+			return err
+		}
+
+		if werr == nil && current.Merge(patch).Equal(current) {
+			return nil
+		}
+
+		if attempt >= c.patchRetries {
+			return makeE(KIND_CONFLICT, ErrPatchConflict)
+		}
+
+		// chờ ngẫu nhiên để các lời gọi đồng thời không ghi đè nhau lần nữa
+		if err := sleep(c.context(), time.Duration(rand.Int63n(int64(20*time.Millisecond)))+time.Millisecond); err != nil {
+			return err
+		}
+	}
+}
+
+// patchLocks tuần tự hóa các patch cùng đối tượng
+type patchLocks struct {
+	mu    sync.Mutex
+	locks map[string]*patchLock
+}
+
+type patchLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newPatchLocks() *patchLocks {
+	return &patchLocks{locks: map[string]*patchLock{}}
+}
+
+// lock khóa đối tượng và trả về hàm mở khóa
+func (p *patchLocks) lock(key string) func() {
+	if p == nil {
+		return func() {}
+	}
+
+	p.mu.Lock()
+	l, ok := p.locks[key]
+	if !ok {
+		l = &patchLock{}
+		p.locks[key] = l
+	}
+	l.refs++
+	p.mu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+
+		p.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(p.locks, key)
+		}
+		p.mu.Unlock()
+	}
+}
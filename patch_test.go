@@ -0,0 +1,196 @@
+package aiot_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MetadataMerge(t *testing.T) {
+	require := require.New(t)
+
+	// ví dụ trong RFC 7396
+	m := aiot.Metadata{
+		"title":   "Goodbye!",
+		"author":  map[string]interface{}{"givenName": "John", "familyName": "Doe"},
+		"tags":    []interface{}{"example", "sample"},
+		"content": "This will be unchanged",
+	}
+	patch := aiot.Metadata{
+		"title":       "Hello!",
+		"phoneNumber": "+01-123-456-7890",
+		"author":      map[string]interface{}{"familyName": nil},
+		"tags":        []interface{}{"example"},
+	}
+
+	require.Equal(aiot.Metadata{
+		"title":       "Hello!",
+		"author":      map[string]interface{}{"givenName": "John"},
+		"tags":        []interface{}{"example"},
+		"content":     "This will be unchanged",
+		"phoneNumber": "+01-123-456-7890",
+	}, m.Merge(patch))
+
+	// m không bị thay đổi
+	require.Equal("Goodbye!", m["title"])
+	require.Equal("Doe", m["author"].(map[string]interface{})["familyName"])
+
+	// object thay thế giá trị không phải object
+	require.Equal(aiot.Metadata{"a": map[string]interface{}{"b": "c"}},
+		aiot.Metadata{"a": "x"}.Merge(aiot.Metadata{"a": map[string]interface{}{"b": "c", "d": nil}}))
+	require.Equal(aiot.Metadata{"a": 1}, aiot.Metadata(nil).Merge(aiot.Metadata{"a": 1, "b": nil}))
+}
+
+func Test_PatchThingMetadata(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions())
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor", Metadata: aiot.Metadata{
+		"floor":    1,
+		"room":     "a",
+		"location": map[string]interface{}{"building": "A", "lat": 10.75},
+	}}))
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+
+	thing, err := client.PatchThingMetadata(token, things[0].ID, aiot.Metadata{
+		"floor":    2,
+		"room":     nil,
+		"location": map[string]interface{}{"lat": nil, "lng": 106.7},
+	})
+	require.NoError(err)
+	require.Equal("sensor", thing.Name)
+
+	want := aiot.Metadata{
+		"floor":    2,
+		"location": map[string]interface{}{"building": "A", "lng": 106.7},
+	}
+	require.True(want.Equal(thing.Metadata), thing.Metadata)
+
+	thing, err = client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.True(want.Equal(thing.Metadata), thing.Metadata)
+
+	_, err = client.PatchThingMetadata(token, "missing", aiot.Metadata{"floor": 3})
+	require.Error(err)
+}
+
+func Test_PatchChannelMetadata(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions())
+
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "alerts", Metadata: aiot.Metadata{"level": "high"}}))
+	channels, err := inventory.Channels(client, token)
+	require.NoError(err)
+
+	ch, err := client.PatchChannelMetadata(token, channels[0].ID, aiot.Metadata{"retention": 30})
+	require.NoError(err)
+	require.Equal("alerts", ch.Name)
+	require.True(aiot.Metadata{"level": "high", "retention": 30}.Equal(ch.Metadata), ch.Metadata)
+}
+
+func Test_PatchConcurrent(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions().SetPatchRetries(50))
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+
+	// các lời gọi đồng thời đặt các khóa khác nhau không ghi đè nhau
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = client.PatchThingMetadata(token, things[0].ID, aiot.Metadata{fmt.Sprintf("k%d", i): i})
+		}(i)
+	}
+	wg.Wait()
+
+	want := aiot.Metadata{}
+	for i, err := range errs {
+		require.NoError(err)
+		want[fmt.Sprintf("k%d", i)] = i
+	}
+
+	thing, err := client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.True(want.Equal(thing.Metadata), thing.Metadata)
+}
+
+func Test_PatchConflict(t *testing.T) {
+	require := require.New(t)
+
+	// giả lập một tiến trình khác luôn ghi đè metadata ngay sau mỗi lần ghi
+	var mu sync.Mutex
+	writes := 0
+	_, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetPatchRetries(2).
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			if call.Method == http.MethodPut {
+				mu.Lock()
+				writes++
+				mu.Unlock()
+				body := call.Body.(map[string]interface{})
+				body["metadata"] = aiot.Metadata{"owner": "other"}
+			}
+			return next(call)
+		}))
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+
+	_, err = client.PatchThingMetadata(token, things[0].ID, aiot.Metadata{"floor": 1})
+	require.True(errors.Is(err, aiot.ErrPatchConflict), err)
+	require.True(aiot.Is(aiot.KIND_CONFLICT, err), err)
+	require.Equal(3, writes)
+
+	// patch không thay đổi gì thì không ghi
+	_, err = client.PatchThingMetadata(token, things[0].ID, aiot.Metadata{"owner": "other", "missing": nil})
+	require.NoError(err)
+	require.Equal(3, writes)
+}
+
+func Test_PatchCancelled(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// tiến trình khác ghi đè metadata, người gọi hủy sau khi đọc lại và trước
+	// lần thử lại
+	writes := 0
+	_, client, token := limitedClient(t, aiot.NewClientOptions().
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			if call.Method == http.MethodPut {
+				writes++
+				body := call.Body.(map[string]interface{})
+				body["metadata"] = aiot.Metadata{"owner": "other"}
+			}
+			if call.Method == http.MethodGet && writes > 0 {
+				defer cancel()
+			}
+			return next(call)
+		}))
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+
+	_, err = client.WithContext(ctx).PatchThingMetadata(token, things[0].ID, aiot.Metadata{"floor": 1})
+	require.True(errors.Is(err, context.Canceled), err)
+	require.Equal(1, writes)
+}
@@ -0,0 +1,299 @@
+package aiot
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tên profile dùng khi không chỉ định profile và file cấu hình không có
+// current
+const DefaultProfile = "default"
+
+// Config là nội dung file cấu hình, mặc định ~/.config/aiot/config.yaml:
+//
+//	current: dev
+//	profiles:
+//	  dev:
+//	    gateway: https://dev.aiot.vn
+//	    email: ops@aiot.vn
+//	    password_env: AIOT_DEV_PASSWORD
+//	    timeout: 10s
+//	  prod:
+//	    gateway: https://aiot.vn
+//	    token_env: AIOT_PROD_TOKEN
+//	    tls:
+//	      ca_file: /etc/aiot/ca.pem
+type Config struct {
+	Current  string             `yaml:"current"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile là thông tin kết nối tới một AIOT gateway. Password và token có thể
+// ghi trực tiếp hoặc tham chiếu tới biến môi trường hay file để không phải lưu
+// trong file cấu hình.
+type Profile struct {
+	Name         string        `yaml:"-"`
+	Gateway      string        `yaml:"gateway"`
+	Failover     []string      `yaml:"failover"`
+	Email        string        `yaml:"email"`
+	Password     string        `yaml:"password"`
+	PasswordEnv  string        `yaml:"password_env"`
+	PasswordFile string        `yaml:"password_file"`
+	Token        string        `yaml:"token"`
+	TokenEnv     string        `yaml:"token_env"`
+	Timeout      time.Duration `yaml:"timeout"`
+	TLS          ProfileTLS    `yaml:"tls"`
+}
+
+type ProfileTLS struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// ConfigPath trả về đường dẫn file cấu hình: AIOT_CONFIG nếu có, nếu không là
+// $XDG_CONFIG_HOME/aiot/config.yaml hoặc ~/.config/aiot/config.yaml
+func ConfigPath() string {
+	return ConfigPathEnv(os.Getenv)
+}
+
+// ConfigPathEnv giống ConfigPath nhưng đọc biến môi trường qua getenv
+func ConfigPathEnv(getenv func(string) string) string {
+	if path := getenv("AIOT_CONFIG"); path != "" {
+		return path
+	}
+
+	dir := getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "aiot", "config.yaml")
+}
+
+// Đọc file cấu hình
+func LoadConfig(path string) (Config, error) {
+	const op operation = "aiot.LoadConfig"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, makeE(op, err)
+	}
+
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return Config{}, makeE(op, fmt.Errorf("invalid config file %s: %w", path, err))
+	}
+
+	return c, nil
+}
+
+// Profile trả về profile theo tên. Tên rỗng là profile current của file cấu
+// hình, hoặc DefaultProfile
+func (c Config) Profile(name string) (Profile, error) {
+	const op operation = "aiot.Config.Profile"
+
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, makeE(op, fmt.Errorf("profile %q not found", name))
+	}
+
+	p.Name = name
+	return p, nil
+}
+
+// LoadProfile đọc profile từ file cấu hình tại ConfigPath() và tạo Session.
+// Tên rỗng là giá trị của AIOT_PROFILE, sau đó là profile current của file
+// cấu hình
+func LoadProfile(name string) (*Session, error) {
+	const op operation = "aiot.LoadProfile"
+
+	if name == "" {
+		name = os.Getenv("AIOT_PROFILE")
+	}
+
+	c, err := LoadConfig(ConfigPath())
+	if err != nil {
+		return nil, makeE(op, err)
+	}
+
+	p, err := c.Profile(name)
+	if err != nil {
+		return nil, makeE(op, err)
+	}
+
+	s, err := NewSession(p)
+	if err != nil {
+		return nil, makeE(op, err)
+	}
+
+	return s, nil
+}
+
+// HTTPClient tạo http.Client theo timeout và cấu hình TLS của profile
+func (p Profile) HTTPClient() (*http.Client, error) {
+	const op operation = "aiot.Profile.HTTPClient"
+
+	client := &http.Client{Timeout: p.Timeout}
+	if p.TLS == (ProfileTLS{}) {
+		return client, nil
+	}
+
+	cfg := &tls.Config{
+		ServerName:         p.TLS.ServerName,
+		InsecureSkipVerify: p.TLS.InsecureSkipVerify,
+	}
+
+	if p.TLS.CAFile != "" {
+		pem, err := os.ReadFile(p.TLS.CAFile)
+		if err != nil {
+			return nil, makeE(op, err)
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, makeE(op, fmt.Errorf("no certificates found in %s", p.TLS.CAFile))
+		}
+	}
+
+	if p.TLS.CertFile != "" || p.TLS.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(p.TLS.CertFile, p.TLS.KeyFile)
+		if err != nil {
+			return nil, makeE(op, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	client.Transport = transport
+
+	return client, nil
+}
+
+// NewClient tạo Client kết nối tới gateway của profile
+func (p Profile) NewClient() (Client, error) {
+	const op operation = "aiot.Profile.NewClient"
+
+	if p.Gateway == "" {
+		return Client{}, makeE(op, fmt.Errorf("profile %q has no gateway", p.Name))
+	}
+
+	httpClient, err := p.HTTPClient()
+	if err != nil {
+		return Client{}, makeE(op, err)
+	}
+
+	opts := NewClientOptions().
+		SetHTTPClient(httpClient).
+		SetFailoverAddrs(p.Failover...)
+
+	return NewClientWithOptions(p.Gateway, opts), nil
+}
+
+// Credentials trả về password và token của profile sau khi đọc các biến môi
+// trường và file được tham chiếu. Giá trị ghi trực tiếp được ưu tiên
+func (p Profile) Credentials() (password, token string, err error) {
+	const op operation = "aiot.Profile.Credentials"
+
+	password = p.Password
+	if password == "" && p.PasswordEnv != "" {
+		password = os.Getenv(p.PasswordEnv)
+	}
+	if password == "" && p.PasswordFile != "" {
+		data, err := os.ReadFile(p.PasswordFile)
+		if err != nil {
+			return "", "", makeE(op, err)
+		}
+		password = strings.TrimSpace(string(data))
+	}
+
+	token = p.Token
+	if token == "" && p.TokenEnv != "" {
+		token = os.Getenv(p.TokenEnv)
+	}
+
+	return password, token, nil
+}
+
+// Session là Client của một profile kèm token, đăng nhập khi cần
+type Session struct {
+	Profile Profile
+	Client  Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// Tạo Session từ profile
+func NewSession(p Profile) (*Session, error) {
+	const op operation = "aiot.NewSession"
+
+	client, err := p.NewClient()
+	if err != nil {
+		return nil, makeE(op, err)
+	}
+
+	return &Session{Profile: p, Client: client}, nil
+}
+
+// Token trả về token của profile, hoặc đăng nhập bằng email và password ở
+// lần gọi đầu tiên
+func (s *Session) Token() (string, error) {
+	const op operation = "aiot.Session.Token"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" {
+		return s.token, nil
+	}
+
+	password, token, err := s.Profile.Credentials()
+	if err != nil {
+		return "", makeE(op, err)
+	}
+
+	if token == "" {
+		if s.Profile.Email == "" || password == "" {
+			return "", makeE(op, ErrMissingOrInvalidCredentials)
+		}
+
+		token, err = s.Client.Token(s.Profile.Email, password)
+		if err != nil {
+			return "", makeE(op, err)
+		}
+	}
+
+	s.token = token
+	return token, nil
+}
+
+// Bỏ token đã lưu, lần gọi Token tiếp theo sẽ đăng nhập lại
+func (s *Session) Reset() {
+	s.mu.Lock()
+	s.token = ""
+	s.mu.Unlock()
+}
@@ -0,0 +1,105 @@
+package aiot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("AIOT_CONFIG", path)
+
+	return path
+}
+
+func Test_LoadProfile(t *testing.T) {
+	require := require.New(t)
+
+	writeConfig(t, `
+current: dev
+profiles:
+  dev:
+    gateway: `+gatewayAddr+`
+    email: `+validEmail+`
+    password_env: AIOT_TEST_PASSWORD
+    timeout: 5s
+  broken:
+    gateway: `+gatewayAddr+`
+    email: `+validEmail+`
+    password: `+invalidPassword+`
+`)
+	t.Setenv("AIOT_TEST_PASSWORD", validPassword)
+
+	s, err := aiot.LoadProfile("")
+	require.NoError(err)
+	require.Equal("dev", s.Profile.Name)
+	require.Equal(5*time.Second, s.Profile.Timeout)
+
+	token, err := s.Token()
+	require.NoError(err)
+
+	ok, err := s.Client.TokenVerify(token)
+	require.NoError(err)
+	require.True(ok)
+
+	t.Setenv("AIOT_PROFILE", "broken")
+	s, err = aiot.LoadProfile("")
+	require.NoError(err)
+	require.Equal("broken", s.Profile.Name)
+
+	_, err = s.Token()
+	require.Error(err)
+
+	_, err = aiot.LoadProfile("prod")
+	require.EqualError(err, `aiot.LoadProfile -> aiot.Config.Profile -> profile "prod" not found`)
+}
+
+func Test_ProfileCredentials(t *testing.T) {
+	require := require.New(t)
+
+	file := filepath.Join(t.TempDir(), "password")
+	require.NoError(os.WriteFile(file, []byte("from-file\n"), 0o600))
+	t.Setenv("AIOT_TEST_TOKEN", "from-env")
+
+	p := aiot.Profile{PasswordEnv: "AIOT_TEST_UNSET", PasswordFile: file, TokenEnv: "AIOT_TEST_TOKEN"}
+	password, token, err := p.Credentials()
+	require.NoError(err)
+	require.Equal("from-file", password)
+	require.Equal("from-env", token)
+
+	p.Password, p.Token = "inline", "inline-token"
+	password, token, err = p.Credentials()
+	require.NoError(err)
+	require.Equal("inline", password)
+	require.Equal("inline-token", token)
+}
+
+func Test_ProfileHTTPClient(t *testing.T) {
+	require := require.New(t)
+
+	p := aiot.Profile{Name: "dev", Timeout: time.Second}
+	client, err := p.HTTPClient()
+	require.NoError(err)
+	require.Equal(time.Second, client.Timeout)
+
+	_, err = p.NewClient()
+	require.EqualError(err, `aiot.Profile.NewClient -> profile "dev" has no gateway`)
+
+	bad := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(os.WriteFile(bad, []byte("not a certificate"), 0o600))
+
+	p.TLS.CAFile = bad
+	_, err = p.HTTPClient()
+	require.Error(err)
+
+	p.TLS = aiot.ProfileTLS{InsecureSkipVerify: true}
+	client, err = p.HTTPClient()
+	require.NoError(err)
+	require.NotNil(client.Transport)
+}
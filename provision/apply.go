@@ -0,0 +1,208 @@
+package provision
+
+import (
+	"fmt"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+)
+
+type Options struct {
+	prune   bool
+	onApply func(Action)
+}
+
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// Xóa thing, channel và gateway không có trong manifest, mặc định là không
+func (opts *Options) SetPrune(prune bool) *Options {
+	opts.prune = prune
+	return opts
+}
+
+// Hàm được gọi sau mỗi thao tác thực hiện thành công
+func (opts *Options) SetOnApply(fn func(Action)) *Options {
+	opts.onApply = fn
+	return opts
+}
+
+// Reconciler lập và thực hiện kế hoạch cho tài khoản sở hữu token
+type Reconciler struct {
+	api   aiot.API
+	token string
+	opts  *Options
+}
+
+func NewReconciler(api aiot.API, token string, opts *Options) *Reconciler {
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	return &Reconciler{api: api, token: token, opts: opts}
+}
+
+// Plan đọc trạng thái hiện tại và lập kế hoạch đưa về manifest m
+func (r *Reconciler) Plan(m Manifest) (Plan, error) {
+	const op = "provision.Plan"
+
+	live, err := inventory.Take(r.api, r.token)
+	if err != nil {
+		return Plan{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	plan, err := Diff(m, live, r.opts)
+	if err != nil {
+		return Plan{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return plan, nil
+}
+
+// Apply thực hiện lần lượt các thao tác của plan và dừng ở lỗi đầu tiên. Vì
+// Plan luôn được lập lại từ trạng thái hiện tại, có thể chạy lại Plan và
+// Apply sau khi sửa lỗi để tiếp tục.
+func (r *Reconciler) Apply(plan Plan) error {
+	const op = "provision.Apply"
+
+	ids := resolver{api: r.api, token: r.token}
+
+	for _, a := range plan.Actions {
+		if err := r.apply(&ids, a); err != nil {
+			return fmt.Errorf("%s -> %s: %w", op, a, err)
+		}
+
+		if r.opts.onApply != nil {
+			r.opts.onApply(a)
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) apply(ids *resolver, a Action) error {
+	switch a.Kind {
+	case KIND_THING:
+		return r.applyThing(ids, a)
+	case KIND_CHANNEL:
+		return r.applyChannel(a)
+	case KIND_GATEWAY:
+		return r.applyGateway(ids, a)
+	}
+
+	return fmt.Errorf("unknown kind %q", a.Kind)
+}
+
+func (r *Reconciler) applyThing(ids *resolver, a Action) error {
+	switch a.Op {
+	case OP_CREATE:
+		return r.api.CreateThing(r.token, aiot.CreateThingInput{Name: a.Name, Metadata: a.metadata})
+	case OP_UPDATE:
+		return r.api.UpdateThing(r.token, aiot.UpdateThingInput{ID: a.id, Name: a.Name, Metadata: a.metadata, Version: a.version})
+	case OP_DELETE:
+		return r.api.DeleteThing(r.token, a.id)
+	case OP_DISCONNECT:
+		return r.api.Disconnect(r.token, a.channelID, a.id)
+	case OP_CONNECT:
+		thingID, err := ids.thing(a.Name)
+		if err != nil {
+			return err
+		}
+		channelID, err := ids.channel(a.Channel)
+		if err != nil {
+			return err
+		}
+		return r.api.Connect(r.token, []string{channelID}, []string{thingID})
+	}
+
+	return fmt.Errorf("unknown op %q", a.Op)
+}
+
+func (r *Reconciler) applyChannel(a Action) error {
+	switch a.Op {
+	case OP_CREATE:
+		return r.api.CreateChannel(r.token, aiot.CreateChannelInput{Name: a.Name, Metadata: a.metadata})
+	case OP_UPDATE:
+		return r.api.UpdateChannel(r.token, aiot.UpdateChannelInput{ID: a.id, Name: a.Name, Metadata: a.metadata, Version: a.version})
+	case OP_DELETE:
+		return r.api.DeleteChannel(r.token, a.id)
+	}
+
+	return fmt.Errorf("unknown op %q", a.Op)
+}
+
+func (r *Reconciler) applyGateway(ids *resolver, a Action) error {
+	switch a.Op {
+	case OP_CREATE:
+		thingID, err := ids.thing(a.thing)
+		if err != nil {
+			return err
+		}
+		return r.api.CreateGateway(r.token, aiot.CreateGatewayInput{Name: a.Name, Description: a.description, ThingID: thingID})
+	case OP_UPDATE:
+		return r.api.UpdateGateway(r.token, aiot.UpdateGatewayInput{ID: a.id, Name: a.Name, Description: a.description, Version: a.version})
+	case OP_DELETE:
+		return r.api.DeleteGateway(r.token, a.id)
+	}
+
+	return fmt.Errorf("unknown op %q", a.Op)
+}
+
+// resolver tìm id theo tên cho các thing và channel được tạo trong lúc Apply,
+// vì CreateThing và CreateChannel không trả về id. Danh sách được đọc lại khi
+// gặp một tên chưa biết.
+type resolver struct {
+	api   aiot.API
+	token string
+
+	things   map[string][]string
+	channels map[string][]string
+}
+
+func (r *resolver) thing(name string) (string, error) {
+	if ids, ok := r.things[name]; ok {
+		return unique("thing", name, ids)
+	}
+
+	things, err := inventory.Things(r.api, r.token)
+	if err != nil {
+		return "", err
+	}
+
+	r.things = map[string][]string{}
+	for _, t := range things {
+		r.things[t.Name] = append(r.things[t.Name], t.ID)
+	}
+
+	return unique("thing", name, r.things[name])
+}
+
+func (r *resolver) channel(name string) (string, error) {
+	if ids, ok := r.channels[name]; ok {
+		return unique("channel", name, ids)
+	}
+
+	channels, err := inventory.Channels(r.api, r.token)
+	if err != nil {
+		return "", err
+	}
+
+	r.channels = map[string][]string{}
+	for _, c := range channels {
+		r.channels[c.Name] = append(r.channels[c.Name], c.ID)
+	}
+
+	return unique("channel", name, r.channels[name])
+}
+
+func unique(kind, name string, ids []string) (string, error) {
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("%s %q not found", kind, name)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%s %q is ambiguous, several %ss have this name", kind, name, kind)
+	}
+}
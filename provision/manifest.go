@@ -0,0 +1,148 @@
+// Package provision tạo thing, channel, kết nối và gateway theo một manifest
+// khai báo. Reconciler so sánh manifest với trạng thái hiện tại, lập kế hoạch
+// (Plan) gồm các thao tác tạo, sửa, kết nối, ngắt kết nối và xóa, sau đó thực
+// hiện kế hoạch. Chạy lại với cùng manifest không tạo ra thao tác nào.
+//
+// Thing, channel và gateway được nhận diện bằng tên, vì vậy tên phải duy nhất
+// trong manifest và trong tài khoản.
+package provision
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest mô tả trạng thái mong muốn, ở dạng YAML hoặc JSON:
+//
+//	things:
+//	  - name: sensor-1
+//	    metadata:
+//	      floor: "1"
+//	    channels: [telemetry]
+//	  - name: gw-thing
+//	channels:
+//	  - name: telemetry
+//	gateways:
+//	  - name: gw-1
+//	    description: floor 1
+//	    thing: gw-thing
+type Manifest struct {
+	Things   []ThingSpec   `yaml:"things" json:"things"`
+	Channels []ChannelSpec `yaml:"channels" json:"channels"`
+	Gateways []GatewaySpec `yaml:"gateways" json:"gateways"`
+}
+
+// ThingSpec là một thing và tên các channel mà thing kết nối tới
+type ThingSpec struct {
+	Name     string        `yaml:"name" json:"name"`
+	Metadata aiot.Metadata `yaml:"metadata" json:"metadata"`
+	Channels []string      `yaml:"channels" json:"channels"`
+}
+
+type ChannelSpec struct {
+	Name     string        `yaml:"name" json:"name"`
+	Metadata aiot.Metadata `yaml:"metadata" json:"metadata"`
+}
+
+// GatewaySpec là một gateway và tên thing của gateway
+type GatewaySpec struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Thing       string `yaml:"thing" json:"thing"`
+}
+
+// Đọc manifest từ file YAML hoặc JSON
+func LoadManifest(path string) (Manifest, error) {
+	const op = "provision.LoadManifest"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	m, err := ParseManifest(data)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%s -> %s: %w", op, path, err)
+	}
+
+	return m, nil
+}
+
+// Đọc manifest từ nội dung YAML hoặc JSON và kiểm tra tính hợp lệ
+func ParseManifest(data []byte) (Manifest, error) {
+	var m Manifest
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return Manifest{}, err
+	}
+
+	if err := m.Validate(); err != nil {
+		return Manifest{}, err
+	}
+
+	return m, nil
+}
+
+// Validate kiểm tra tên không rỗng, không trùng lặp và các tham chiếu tới
+// thing, channel đều có trong manifest
+func (m Manifest) Validate() error {
+	things := map[string]bool{}
+	for _, t := range m.Things {
+		if t.Name == "" {
+			return errors.New("thing without name")
+		}
+		if things[t.Name] {
+			return fmt.Errorf("duplicate thing %q", t.Name)
+		}
+		things[t.Name] = true
+	}
+
+	channels := map[string]bool{}
+	for _, c := range m.Channels {
+		if c.Name == "" {
+			return errors.New("channel without name")
+		}
+		if channels[c.Name] {
+			return fmt.Errorf("duplicate channel %q", c.Name)
+		}
+		channels[c.Name] = true
+	}
+
+	for _, t := range m.Things {
+		seen := map[string]bool{}
+		for _, c := range t.Channels {
+			if !channels[c] {
+				return fmt.Errorf("thing %q: unknown channel %q", t.Name, c)
+			}
+			if seen[c] {
+				return fmt.Errorf("thing %q: duplicate channel %q", t.Name, c)
+			}
+			seen[c] = true
+		}
+	}
+
+	gateways := map[string]bool{}
+	for _, g := range m.Gateways {
+		if g.Name == "" {
+			return errors.New("gateway without name")
+		}
+		if gateways[g.Name] {
+			return fmt.Errorf("duplicate gateway %q", g.Name)
+		}
+		gateways[g.Name] = true
+
+		if !things[g.Thing] {
+			return fmt.Errorf("gateway %q: unknown thing %q", g.Name, g.Thing)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,405 @@
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+)
+
+type Op string
+type Kind string
+
+var (
+	OP_CREATE     Op = "create"
+	OP_UPDATE     Op = "update"
+	OP_DELETE     Op = "delete"
+	OP_CONNECT    Op = "connect"
+	OP_DISCONNECT Op = "disconnect"
+
+	KIND_THING   Kind = "thing"
+	KIND_CHANNEL Kind = "channel"
+	KIND_GATEWAY Kind = "gateway"
+)
+
+// Action là một thao tác trong kế hoạch. Với OP_CONNECT và OP_DISCONNECT,
+// Name là tên thing và Channel là tên channel.
+type Action struct {
+	Op      Op
+	Kind    Kind
+	Name    string
+	Channel string
+	Changes []string
+
+	// id hiện tại của đối tượng, có với OP_UPDATE, OP_DELETE, OP_DISCONNECT
+	id        string
+	channelID string
+	// phiên bản của đối tượng lúc lập kế hoạch, có với OP_UPDATE
+	version string
+
+	metadata    aiot.Metadata
+	description string
+	thing       string
+}
+
+func (a Action) String() string {
+	var b strings.Builder
+
+	switch a.Op {
+	case OP_CREATE:
+		fmt.Fprintf(&b, "+ %s %s", a.Kind, a.Name)
+	case OP_UPDATE:
+		fmt.Fprintf(&b, "~ %s %s", a.Kind, a.Name)
+	case OP_DELETE:
+		fmt.Fprintf(&b, "- %s %s", a.Kind, a.Name)
+	case OP_CONNECT:
+		fmt.Fprintf(&b, "+ connect thing %s -> channel %s", a.Name, a.Channel)
+	case OP_DISCONNECT:
+		fmt.Fprintf(&b, "- disconnect thing %s -> channel %s", a.Name, a.Channel)
+	}
+
+	if len(a.Changes) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(a.Changes, ", "))
+	}
+
+	return b.String()
+}
+
+// Plan là danh sách thao tác theo đúng thứ tự thực hiện
+type Plan struct {
+	Actions []Action
+}
+
+// Empty cho biết trạng thái hiện tại đã khớp với manifest
+func (p Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// Count đếm số thao tác theo loại
+func (p Plan) Count(op Op) int {
+	n := 0
+	for _, a := range p.Actions {
+		if a.Op == op {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (p Plan) String() string {
+	var b strings.Builder
+	for _, a := range p.Actions {
+		b.WriteString(a.String())
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// Diff lập kế hoạch đưa trạng thái live về manifest m. Thứ tự thao tác:
+// xóa gateway, ngắt kết nối, tạo/sửa channel, tạo/sửa thing, kết nối,
+// tạo/sửa gateway, rồi xóa thing và channel. Đối tượng không có trong
+// manifest chỉ bị xóa khi bật SetPrune.
+func Diff(m Manifest, live inventory.Snapshot, opts *Options) (Plan, error) {
+	const op = "provision.Diff"
+
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	if err := m.Validate(); err != nil {
+		return Plan{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	d := differ{m: m, live: live, opts: opts}
+	if err := d.index(); err != nil {
+		return Plan{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	d.diffGateways()
+	d.diffConnections()
+	d.diffChannels()
+	d.diffThings()
+
+	var plan Plan
+	plan.Actions = append(plan.Actions, d.gatewayDeletes...)
+	plan.Actions = append(plan.Actions, d.disconnects...)
+	plan.Actions = append(plan.Actions, d.channelChanges...)
+	plan.Actions = append(plan.Actions, d.thingChanges...)
+	plan.Actions = append(plan.Actions, d.connects...)
+	plan.Actions = append(plan.Actions, d.gatewayChanges...)
+	plan.Actions = append(plan.Actions, d.thingDeletes...)
+	plan.Actions = append(plan.Actions, d.channelDeletes...)
+
+	return plan, nil
+}
+
+type differ struct {
+	m    Manifest
+	live inventory.Snapshot
+	opts *Options
+
+	// đối tượng live được quản lý bởi manifest, theo tên
+	things   map[string]aiot.Thing
+	channels map[string]aiot.Channel
+	gateways map[string]aiot.Gateway
+
+	gatewayDeletes []Action
+	disconnects    []Action
+	channelChanges []Action
+	thingChanges   []Action
+	connects       []Action
+	gatewayChanges []Action
+	thingDeletes   []Action
+	channelDeletes []Action
+}
+
+// index tìm đối tượng live tương ứng với từng tên trong manifest. Một tên có
+// nhiều đối tượng live là lỗi vì không biết đối tượng nào được quản lý.
+func (d *differ) index() error {
+	d.things = map[string]aiot.Thing{}
+	d.channels = map[string]aiot.Channel{}
+	d.gateways = map[string]aiot.Gateway{}
+
+	wanted := map[string]bool{}
+	for _, t := range d.m.Things {
+		wanted[t.Name] = true
+	}
+	for _, t := range d.live.Things {
+		if !wanted[t.Name] {
+			continue
+		}
+		if _, dup := d.things[t.Name]; dup {
+			return fmt.Errorf("thing %q is ambiguous, several things have this name", t.Name)
+		}
+		d.things[t.Name] = t
+	}
+
+	wanted = map[string]bool{}
+	for _, c := range d.m.Channels {
+		wanted[c.Name] = true
+	}
+	for _, c := range d.live.Channels {
+		if !wanted[c.Name] {
+			continue
+		}
+		if _, dup := d.channels[c.Name]; dup {
+			return fmt.Errorf("channel %q is ambiguous, several channels have this name", c.Name)
+		}
+		d.channels[c.Name] = c
+	}
+
+	wanted = map[string]bool{}
+	for _, g := range d.m.Gateways {
+		wanted[g.Name] = true
+	}
+	for _, g := range d.live.Gateways {
+		if !wanted[g.Name] {
+			continue
+		}
+		if _, dup := d.gateways[g.Name]; dup {
+			return fmt.Errorf("gateway %q is ambiguous, several gateways have this name", g.Name)
+		}
+		d.gateways[g.Name] = g
+	}
+
+	return nil
+}
+
+func (d *differ) diffGateways() {
+	for _, spec := range d.m.Gateways {
+		create := Action{Op: OP_CREATE, Kind: KIND_GATEWAY, Name: spec.Name, description: spec.Description, thing: spec.Thing}
+
+		g, ok := d.gateways[spec.Name]
+		if !ok {
+			d.gatewayChanges = append(d.gatewayChanges, create)
+			continue
+		}
+
+		// gateway không đổi được thing nên phải xóa rồi tạo lại
+		thing, ok := d.things[spec.Thing]
+		if !ok || thing.ID != g.UnderlayThing.ID {
+			change := fmt.Sprintf("thing: %q -> %q", g.UnderlayThing.Name, spec.Thing)
+			d.gatewayDeletes = append(d.gatewayDeletes, Action{Op: OP_DELETE, Kind: KIND_GATEWAY, Name: g.Name, Changes: []string{change}, id: g.ID})
+			create.Changes = []string{change}
+			d.gatewayChanges = append(d.gatewayChanges, create)
+			continue
+		}
+
+		if g.Description != spec.Description {
+			d.gatewayChanges = append(d.gatewayChanges, Action{
+				Op:          OP_UPDATE,
+				Kind:        KIND_GATEWAY,
+				Name:        spec.Name,
+				Changes:     []string{fmt.Sprintf("description: %q -> %q", g.Description, spec.Description)},
+				id:          g.ID,
+				version:     g.Version,
+				description: spec.Description,
+			})
+		}
+	}
+
+	if !d.opts.prune {
+		return
+	}
+	for _, g := range d.live.Gateways {
+		if managed, ok := d.gateways[g.Name]; ok && managed.ID == g.ID {
+			continue
+		}
+		d.gatewayDeletes = append(d.gatewayDeletes, Action{Op: OP_DELETE, Kind: KIND_GATEWAY, Name: g.Name, id: g.ID})
+	}
+}
+
+func (d *differ) diffConnections() {
+	for _, spec := range d.m.Things {
+		desired := map[string]bool{}
+		for _, c := range spec.Channels {
+			desired[c] = true
+		}
+
+		thing, exists := d.things[spec.Name]
+		connected := map[string]bool{}
+
+		if exists {
+			for _, id := range d.live.ChannelsOf(thing.ID) {
+				c, _ := d.live.Channel(id)
+				if managed, ok := d.channels[c.Name]; ok && managed.ID == id && desired[c.Name] {
+					connected[c.Name] = true
+					continue
+				}
+
+				// channel sẽ bị xóa thì kết nối cũng mất theo
+				if d.opts.prune && !d.isManagedChannel(id) {
+					continue
+				}
+
+				d.disconnects = append(d.disconnects, Action{
+					Op:        OP_DISCONNECT,
+					Kind:      KIND_THING,
+					Name:      spec.Name,
+					Channel:   c.Name,
+					id:        thing.ID,
+					channelID: id,
+				})
+			}
+		}
+
+		for _, c := range spec.Channels {
+			if !connected[c] {
+				d.connects = append(d.connects, Action{Op: OP_CONNECT, Kind: KIND_THING, Name: spec.Name, Channel: c})
+			}
+		}
+	}
+}
+
+func (d *differ) isManagedChannel(id string) bool {
+	for _, c := range d.channels {
+		if c.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *differ) diffChannels() {
+	for _, spec := range d.m.Channels {
+		c, ok := d.channels[spec.Name]
+		if !ok {
+			d.channelChanges = append(d.channelChanges, Action{Op: OP_CREATE, Kind: KIND_CHANNEL, Name: spec.Name, metadata: spec.Metadata})
+			continue
+		}
+
+		if changes := diffMetadata(c.Metadata, spec.Metadata); len(changes) > 0 {
+			d.channelChanges = append(d.channelChanges, Action{Op: OP_UPDATE, Kind: KIND_CHANNEL, Name: spec.Name, Changes: changes, id: c.ID, version: c.Version, metadata: spec.Metadata})
+		}
+	}
+
+	if !d.opts.prune {
+		return
+	}
+	for _, c := range d.live.Channels {
+		if !d.isManagedChannel(c.ID) {
+			d.channelDeletes = append(d.channelDeletes, Action{Op: OP_DELETE, Kind: KIND_CHANNEL, Name: c.Name, id: c.ID})
+		}
+	}
+}
+
+func (d *differ) diffThings() {
+	for _, spec := range d.m.Things {
+		t, ok := d.things[spec.Name]
+		if !ok {
+			d.thingChanges = append(d.thingChanges, Action{Op: OP_CREATE, Kind: KIND_THING, Name: spec.Name, metadata: spec.Metadata})
+			continue
+		}
+
+		if changes := diffMetadata(t.Metadata, spec.Metadata); len(changes) > 0 {
+			d.thingChanges = append(d.thingChanges, Action{Op: OP_UPDATE, Kind: KIND_THING, Name: spec.Name, Changes: changes, id: t.ID, version: t.Version, metadata: spec.Metadata})
+		}
+	}
+
+	if !d.opts.prune {
+		return
+	}
+	for _, t := range d.live.Things {
+		if managed, ok := d.things[t.Name]; ok && managed.ID == t.ID {
+			continue
+		}
+		d.thingDeletes = append(d.thingDeletes, Action{Op: OP_DELETE, Kind: KIND_THING, Name: t.Name, id: t.ID})
+	}
+}
+
+// diffMetadata mô tả các khóa metadata khác nhau, sắp xếp theo khóa. Metadata
+// nil và rỗng được coi là như nhau, giá trị được so sánh theo dạng JSON.
+func diffMetadata(have, want aiot.Metadata) []string {
+	keys := map[string]bool{}
+	for k := range have {
+		keys[k] = true
+	}
+	for k := range want {
+		keys[k] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, k := range sorted {
+		h, hok := have[k]
+		w, wok := want[k]
+		switch {
+		case hok && wok && (aiot.Metadata{k: h}).Equal(aiot.Metadata{k: w}):
+		case !hok:
+			changes = append(changes, fmt.Sprintf("metadata.%s: + %s", k, quote(w)))
+		case !wok:
+			changes = append(changes, fmt.Sprintf("metadata.%s: - %s", k, quote(h)))
+		default:
+			changes = append(changes, fmt.Sprintf("metadata.%s: %s -> %s", k, quote(h), quote(w)))
+		}
+	}
+
+	return changes
+}
+
+// quote hiển thị giá trị metadata, chuỗi được đặt trong dấu nháy để phân biệt
+// với số và bool
+func quote(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(data)
+}
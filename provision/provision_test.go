@@ -0,0 +1,207 @@
+package provision_test
+
+import (
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+	"github.com/mobifone-aiot/aiot-go/provision"
+	"github.com/stretchr/testify/require"
+)
+
+const manifest = `
+things:
+  - name: sensor-1
+    metadata:
+      floor: "1"
+    channels: [telemetry, alerts]
+  - name: sensor-2
+    channels: [telemetry]
+  - name: gw-thing
+channels:
+  - name: telemetry
+  - name: alerts
+    metadata:
+      level: high
+gateways:
+  - name: gw-1
+    description: floor 1
+    thing: gw-thing
+`
+
+func parse(t *testing.T, s string) provision.Manifest {
+	m, err := provision.ParseManifest([]byte(s))
+	require.NoError(t, err)
+
+	return m
+}
+
+func Test_ApplyFromScratch(t *testing.T) {
+	require := require.New(t)
+	client, token := aiottest.NewAccount(t)
+
+	var applied []string
+	r := provision.NewReconciler(client, token, provision.NewOptions().SetOnApply(func(a provision.Action) {
+		applied = append(applied, a.String())
+	}))
+
+	plan, err := r.Plan(parse(t, manifest))
+	require.NoError(err)
+	require.Equal(""+
+		"+ channel telemetry\n"+
+		"+ channel alerts\n"+
+		"+ thing sensor-1\n"+
+		"+ thing sensor-2\n"+
+		"+ thing gw-thing\n"+
+		"+ connect thing sensor-1 -> channel telemetry\n"+
+		"+ connect thing sensor-1 -> channel alerts\n"+
+		"+ connect thing sensor-2 -> channel telemetry\n"+
+		"+ gateway gw-1\n", plan.String())
+	require.Equal(3, plan.Count(provision.OP_CONNECT))
+
+	require.NoError(r.Apply(plan))
+	require.Len(applied, len(plan.Actions))
+
+	live, err := inventory.Take(client, token)
+	require.NoError(err)
+	require.Len(live.Things, 3)
+	require.Len(live.Channels, 2)
+	require.Len(live.Connections, 3)
+	require.Len(live.Gateways, 1)
+	require.Equal("gw-thing", live.Gateways[0].UnderlayThing.Name)
+	require.Equal("floor 1", live.Gateways[0].Description)
+
+	// chạy lại không có thay đổi
+	plan, err = r.Plan(parse(t, manifest))
+	require.NoError(err)
+	require.True(plan.Empty(), plan.String())
+}
+
+func Test_ApplyChanges(t *testing.T) {
+	require := require.New(t)
+	client, token := aiottest.NewAccount(t)
+
+	r := provision.NewReconciler(client, token, nil)
+	plan, err := r.Plan(parse(t, manifest))
+	require.NoError(err)
+	require.NoError(r.Apply(plan))
+
+	// thing và channel ngoài manifest không bị động tới nếu không prune
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "manual"}))
+
+	changed := parse(t, `
+things:
+  - name: sensor-1
+    metadata:
+      floor: "2"
+      room: a
+    channels: [telemetry]
+  - name: sensor-2
+    channels: [telemetry]
+  - name: gw-thing
+  - name: gw-thing-2
+channels:
+  - name: telemetry
+  - name: alerts
+    metadata:
+      level: high
+gateways:
+  - name: gw-1
+    description: floor 2
+    thing: gw-thing-2
+`)
+
+	plan, err = r.Plan(changed)
+	require.NoError(err)
+	require.Equal(""+
+		"- gateway gw-1 (thing: \"gw-thing\" -> \"gw-thing-2\")\n"+
+		"- disconnect thing sensor-1 -> channel alerts\n"+
+		"~ thing sensor-1 (metadata.floor: \"1\" -> \"2\", metadata.room: + \"a\")\n"+
+		"+ thing gw-thing-2\n"+
+		"+ gateway gw-1 (thing: \"gw-thing\" -> \"gw-thing-2\")\n", plan.String())
+
+	require.NoError(r.Apply(plan))
+
+	plan, err = r.Plan(changed)
+	require.NoError(err)
+	require.True(plan.Empty(), plan.String())
+
+	live, err := inventory.Take(client, token)
+	require.NoError(err)
+	require.Len(live.Things, 5)
+	require.Equal("gw-thing-2", live.Gateways[0].UnderlayThing.Name)
+	require.Equal("floor 2", live.Gateways[0].Description)
+
+	// prune xóa những gì không có trong manifest
+	r = provision.NewReconciler(client, token, provision.NewOptions().SetPrune(true))
+	plan, err = r.Plan(parse(t, `
+things:
+  - name: sensor-1
+    channels: [telemetry]
+channels:
+  - name: telemetry
+`))
+	require.NoError(err)
+	require.Equal(""+
+		"- gateway gw-1\n"+
+		"~ thing sensor-1 (metadata.floor: - \"2\", metadata.room: - \"a\")\n"+
+		"- thing gw-thing\n"+
+		"- thing gw-thing-2\n"+
+		"- thing manual\n"+
+		"- thing sensor-2\n"+
+		"- channel alerts\n", plan.String())
+
+	require.NoError(r.Apply(plan))
+
+	live, err = inventory.Take(client, token)
+	require.NoError(err)
+	require.Len(live.Things, 1)
+	require.Len(live.Channels, 1)
+	require.Len(live.Connections, 1)
+	require.Empty(live.Gateways)
+}
+
+func Test_Diff(t *testing.T) {
+	require := require.New(t)
+
+	live := inventory.Snapshot{
+		Things: []aiot.Thing{
+			{ID: "1", Name: "sensor-1"},
+			{ID: "2", Name: "sensor-1"},
+			{ID: "3", Name: "other"},
+		},
+	}
+
+	_, err := provision.Diff(parse(t, "things: [{name: sensor-1}]"), live, nil)
+	require.EqualError(err, `provision.Diff -> thing "sensor-1" is ambiguous, several things have this name`)
+
+	// trùng tên ngoài manifest thì không sao
+	plan, err := provision.Diff(parse(t, "things: [{name: other, metadata: {}}]"), live, nil)
+	require.NoError(err)
+	require.True(plan.Empty())
+}
+
+func Test_ParseManifest(t *testing.T) {
+	require := require.New(t)
+
+	m := parse(t, `{"things": [{"name": "a", "channels": ["c"]}], "channels": [{"name": "c"}]}`)
+	require.Equal([]string{"c"}, m.Things[0].Channels)
+
+	m = parse(t, "")
+	require.Empty(m.Things)
+
+	for s, msg := range map[string]string{
+		"things: [{name: a}, {name: a}]":     `duplicate thing "a"`,
+		"things: [{name: a, channels: [c]}]": `thing "a": unknown channel "c"`,
+		"channels: [{}]":                     "channel without name",
+		"gateways: [{name: g, thing: a}]":    `gateway "g": unknown thing "a"`,
+		"things: [{name: a}]\ngateways: [{name: g, thing: a}, {name: g, thing: a}]": `duplicate gateway "g"`,
+	} {
+		_, err := provision.ParseManifest([]byte(s))
+		require.EqualError(err, msg, s)
+	}
+
+	_, err := provision.ParseManifest([]byte("thingz: []"))
+	require.Error(err)
+}
@@ -0,0 +1,93 @@
+package aiot
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Giá trị thay thế cho thông tin nhạy cảm trong log, cassette và event của
+// package watch
+const Redacted = "[REDACTED]"
+
+// Các trường JSON (không phân biệt hoa thường) có giá trị nhạy cảm: mật khẩu,
+// token và key của thing/channel
+var SensitiveFields = []string{
+	"password",
+	"newPassword",
+	"oldPassword",
+	"token",
+	"authorization",
+	"secret",
+	"key",
+	"thingKey",
+	"channelKey",
+}
+
+// Sensitive báo field có thuộc SensitiveFields không
+func Sensitive(field string) bool {
+	for _, f := range SensitiveFields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Redact trả về body JSON với giá trị của các trường nhạy cảm được thay bằng
+// Redacted, chỉ giữ lại tiền tố "Bearer " của token. Body không phải JSON
+// được thay toàn bộ.
+func Redact(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return []byte(Redacted)
+	}
+
+	out, err := json.Marshal(redact(v))
+	if err != nil {
+		return []byte(Redacted)
+	}
+
+	return out
+}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if Sensitive(k) {
+				v[k] = redactValue(val)
+			} else {
+				v[k] = redact(val)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+
+	return v
+}
+
+// redactValue che toàn bộ giá trị. Giá trị rỗng được giữ nguyên, token dạng
+// "Bearer ..." giữ lại tiền tố để vẫn tách được token khi phát lại cassette.
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return v
+		}
+		if fields := strings.Fields(v); len(fields) == 2 && fields[0] == "Bearer" {
+			return "Bearer " + Redacted
+		}
+	}
+
+	return Redacted
+}
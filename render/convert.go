@@ -0,0 +1,233 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mobifone-aiot/aiot-go"
+)
+
+// List là một trang kết quả của các hàm List... của aiot.Client
+type List struct {
+	Items interface{}
+	Total int
+}
+
+// GatewayState là trạng thái online của một gateway, dùng khi hiển thị kết
+// quả của aiot.Client.GatewayStatus
+type GatewayState struct {
+	ID     string
+	Online bool
+}
+
+// item là một phần tử cần hiển thị: giá trị gốc (dùng cho template) và dạng
+// tài liệu lồng nhau (dùng cho JSON/YAML).
+type item struct {
+	value interface{}
+	doc   map[string]interface{}
+}
+
+var (
+	entityColumns  = []string{"id", "name", "key"}
+	gatewayColumns = []string{"id", "name", "description", "owner", "thing.id", "thing.name", "thing.key", "thing.owner"}
+	userColumns    = []string{"email", "fullName", "phoneNumber", "description", "customerId", "userTypeId", "userStatusId", "userGroupId", "createdBy"}
+	stateColumns   = []string{"id", "online"}
+)
+
+// data là kết quả chuyển đổi một giá trị cần hiển thị.
+type data struct {
+	items   []item
+	columns []string
+	total   *int
+	list    bool
+}
+
+// convert chuyển v thành danh sách phần tử cần hiển thị.
+func convert(v interface{}) (data, error) {
+	switch v := v.(type) {
+	case List:
+		d, err := convert(v.Items)
+		total := v.Total
+		d.total = &total
+		return d, err
+
+	case aiot.Thing:
+		return data{items: []item{thingItem(v)}, columns: entityColumns}, nil
+	case []aiot.Thing:
+		d := data{columns: entityColumns, list: true}
+		for _, t := range v {
+			d.items = append(d.items, thingItem(t))
+		}
+		return d, nil
+
+	case aiot.Channel:
+		return data{items: []item{channelItem(v)}, columns: entityColumns}, nil
+	case []aiot.Channel:
+		d := data{columns: entityColumns, list: true}
+		for _, c := range v {
+			d.items = append(d.items, channelItem(c))
+		}
+		return d, nil
+
+	case aiot.Gateway:
+		return data{items: []item{gatewayItem(v)}, columns: gatewayColumns}, nil
+	case []aiot.Gateway:
+		d := data{columns: gatewayColumns, list: true}
+		for _, g := range v {
+			d.items = append(d.items, gatewayItem(g))
+		}
+		return d, nil
+
+	case aiot.User:
+		return data{items: []item{userItem(v)}, columns: userColumns}, nil
+
+	case map[string]bool:
+		ids := make([]string, 0, len(v))
+		for id := range v {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		d := data{columns: stateColumns, list: true}
+		for _, id := range ids {
+			d.items = append(d.items, stateItem(GatewayState{ID: id, Online: v[id]}))
+		}
+		return d, nil
+
+	case GatewayState:
+		return data{items: []item{stateItem(v)}, columns: stateColumns}, nil
+	case []GatewayState:
+		d := data{columns: stateColumns, list: true}
+		for _, s := range v {
+			d.items = append(d.items, stateItem(s))
+		}
+		return d, nil
+
+	default:
+		return data{}, fmt.Errorf("unsupported value of type %T", v)
+	}
+}
+
+func thingItem(t aiot.Thing) item {
+	return item{
+		value: t,
+		doc:   entityDoc(t.ID, t.Name, t.Key, t.Metadata),
+	}
+}
+
+func channelItem(c aiot.Channel) item {
+	return item{
+		value: c,
+		doc:   entityDoc(c.ID, c.Name, c.Key, c.Metadata),
+	}
+}
+
+func entityDoc(id, name, key string, metadata aiot.Metadata) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       id,
+		"name":     name,
+		"key":      key,
+		"metadata": metadataDoc(metadata),
+	}
+}
+
+// metadataDoc sao chép metadata, object lồng nhau được trải phẳng thành các
+// cột dạng metadata.<key>.<key>
+func metadataDoc(m aiot.Metadata) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := m.Map(k); ok {
+			out[k] = metadataDoc(nested)
+			continue
+		}
+		out[k] = v
+	}
+
+	return out
+}
+
+func gatewayItem(g aiot.Gateway) item {
+	return item{
+		value: g,
+		doc: map[string]interface{}{
+			"id":          g.ID,
+			"name":        g.Name,
+			"description": g.Description,
+			"owner":       g.Owner,
+			"thing": map[string]interface{}{
+				"id":       g.UnderlayThing.ID,
+				"name":     g.UnderlayThing.Name,
+				"key":      g.UnderlayThing.Key,
+				"owner":    g.UnderlayThingOwner,
+				"metadata": metadataDoc(g.UnderlayThing.Metadata),
+			},
+		},
+	}
+}
+
+func userItem(u aiot.User) item {
+	return item{
+		value: u,
+		doc: map[string]interface{}{
+			"email":        u.Email,
+			"fullName":     u.Fullname,
+			"phoneNumber":  u.Phonenumber,
+			"description":  u.Description,
+			"customerId":   u.CustomerId,
+			"userTypeId":   u.UserTypeId,
+			"userStatusId": u.UserStatusId,
+			"userGroupId":  u.UserGroupId,
+			"createdBy":    u.CreatedBy,
+		},
+	}
+}
+
+func stateItem(s GatewayState) item {
+	return item{
+		value: s,
+		doc: map[string]interface{}{
+			"id":     s.ID,
+			"online": s.Online,
+		},
+	}
+}
+
+// flatten trải phẳng tài liệu lồng nhau thành các cột, ví dụ metadata.floor.
+func flatten(doc map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	flattenInto(out, "", doc)
+
+	return out
+}
+
+func flattenInto(out map[string]interface{}, prefix string, doc map[string]interface{}) {
+	for k, v := range doc {
+		if nested, ok := v.(map[string]interface{}); ok {
+			flattenInto(out, prefix+k+".", nested)
+			continue
+		}
+		out[prefix+k] = v
+	}
+}
+
+// defaultColumns trả về các cột cơ bản, sau đó là các cột metadata (đã sắp
+// xếp) có trong ít nhất một phần tử.
+func defaultColumns(base []string, rows []map[string]interface{}) []string {
+	seen := make(map[string]bool, len(base))
+	for _, c := range base {
+		seen[c] = true
+	}
+
+	var extra []string
+	for _, row := range rows {
+		for k := range row {
+			if !seen[k] {
+				seen[k] = true
+				extra = append(extra, k)
+			}
+		}
+	}
+	sort.Strings(extra)
+
+	return append(append([]string(nil), base...), extra...)
+}
@@ -0,0 +1,283 @@
+// Package render hiển thị kết quả của aiot.Client (Thing, Channel, Gateway,
+// User và các trang kết quả) dưới dạng bảng, JSON, YAML, CSV hoặc Go template.
+//
+// Metadata được trải phẳng thành các cột dạng metadata.<key> (với gateway là
+// thing.metadata.<key>), có thể dùng khi chọn cột bằng SetColumns.
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+var (
+	FORMAT_TABLE    Format = "table"
+	FORMAT_JSON     Format = "json"
+	FORMAT_YAML     Format = "yaml"
+	FORMAT_CSV      Format = "csv"
+	FORMAT_TEMPLATE Format = "template"
+)
+
+type Options struct {
+	format   Format
+	columns  []string
+	template string
+	noHeader bool
+}
+
+func NewOptions() *Options {
+	return &Options{
+		format: FORMAT_TABLE,
+	}
+}
+
+// ParseOptions tạo Options từ giá trị của flag --output, ví dụ "json" hoặc
+// "template={{.Name}}"
+func ParseOptions(output string) (*Options, error) {
+	opts := NewOptions()
+	if output == "" {
+		return opts, nil
+	}
+
+	parts := strings.SplitN(output, "=", 2)
+	name := parts[0]
+	switch f := Format(name); f {
+	case FORMAT_TABLE, FORMAT_JSON, FORMAT_YAML, FORMAT_CSV:
+		if len(parts) == 2 {
+			return nil, fmt.Errorf("format %s does not take a template", f)
+		}
+		opts.SetFormat(f)
+	case FORMAT_TEMPLATE:
+		if len(parts) < 2 || parts[1] == "" {
+			return nil, fmt.Errorf("missing template, use template=<template>")
+		}
+		opts.SetTemplate(parts[1])
+	default:
+		return nil, fmt.Errorf("unknown output format %q", name)
+	}
+
+	return opts, nil
+}
+
+// Định dạng đầu ra, mặc định là FORMAT_TABLE
+func (opts *Options) SetFormat(f Format) *Options {
+	opts.format = f
+	return opts
+}
+
+// Các cột cần hiển thị, ví dụ "id", "name", "metadata.floor". Mặc định là các
+// cột cơ bản của từng loại và toàn bộ các cột metadata
+func (opts *Options) SetColumns(columns ...string) *Options {
+	opts.columns = columns
+	return opts
+}
+
+// Go template áp dụng cho từng phần tử, đồng thời chuyển định dạng sang
+// FORMAT_TEMPLATE. Template nhận giá trị gốc (aiot.Thing, aiot.Gateway...)
+func (opts *Options) SetTemplate(tpl string) *Options {
+	opts.format = FORMAT_TEMPLATE
+	opts.template = tpl
+	return opts
+}
+
+// Không in dòng tiêu đề với FORMAT_TABLE và FORMAT_CSV
+func (opts *Options) SetNoHeader(noHeader bool) *Options {
+	opts.noHeader = noHeader
+	return opts
+}
+
+// Render ghi v ra w theo opts. v có thể là aiot.Thing, aiot.Channel,
+// aiot.Gateway, aiot.User, slice của chúng, List, GatewayState hoặc kết quả
+// của aiot.Client.GatewayStatus. opts bằng nil tương đương NewOptions().
+func Render(w io.Writer, v interface{}, opts *Options) error {
+	const op = "render.Render"
+
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	d, err := convert(v)
+	if err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	switch opts.format {
+	case FORMAT_TABLE:
+		err = renderTable(w, d, opts)
+	case FORMAT_JSON:
+		err = renderJSON(w, d, opts)
+	case FORMAT_YAML:
+		err = renderYAML(w, d, opts)
+	case FORMAT_CSV:
+		err = renderCSV(w, d, opts)
+	case FORMAT_TEMPLATE:
+		err = renderTemplate(w, d, opts)
+	default:
+		err = fmt.Errorf("unknown output format %q", opts.format)
+	}
+	if err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return nil
+}
+
+// rows trải phẳng các phần tử và trả về các cột cần hiển thị.
+func rows(d data, opts *Options) ([]map[string]interface{}, []string) {
+	flat := make([]map[string]interface{}, len(d.items))
+	for i, it := range d.items {
+		flat[i] = flatten(it.doc)
+	}
+
+	if len(opts.columns) > 0 {
+		return flat, opts.columns
+	}
+
+	return flat, defaultColumns(d.columns, flat)
+}
+
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}, map[string]interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// renderTable in danh sách dưới dạng bảng và một phần tử đơn lẻ dưới dạng
+// các cặp cột: giá trị.
+func renderTable(w io.Writer, d data, opts *Options) error {
+	flat, columns := rows(d, opts)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	if !d.list {
+		for _, c := range columns {
+			fmt.Fprintf(tw, "%s:\t%s\n", c, cell(flat[0][c]))
+		}
+		return tw.Flush()
+	}
+
+	if !opts.noHeader {
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+	}
+	for _, row := range flat {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = cell(row[c])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func renderCSV(w io.Writer, d data, opts *Options) error {
+	flat, columns := rows(d, opts)
+	cw := csv.NewWriter(w)
+
+	if !opts.noHeader {
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+	}
+	for _, row := range flat {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = cell(row[c])
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// document trả về dữ liệu dùng cho JSON và YAML: tài liệu lồng nhau, hoặc chỉ
+// các cột đã chọn nếu có SetColumns. Trang kết quả có dạng {total, items}.
+func document(d data, opts *Options) interface{} {
+	docs := make([]interface{}, len(d.items))
+	for i, it := range d.items {
+		docs[i] = it.doc
+	}
+
+	if len(opts.columns) > 0 {
+		flat, columns := rows(d, opts)
+		for i, row := range flat {
+			doc := make(map[string]interface{}, len(columns))
+			for _, c := range columns {
+				doc[c] = row[c]
+			}
+			docs[i] = doc
+		}
+	}
+
+	if !d.list {
+		return docs[0]
+	}
+	if d.total != nil {
+		return map[string]interface{}{"total": *d.total, "items": docs}
+	}
+
+	return docs
+}
+
+func renderJSON(w io.Writer, d data, opts *Options) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(document(d, opts))
+}
+
+func renderYAML(w io.Writer, d data, opts *Options) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(document(d, opts)); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join": strings.Join,
+}
+
+// renderTemplate áp dụng template cho từng phần tử, mỗi phần tử một dòng.
+func renderTemplate(w io.Writer, d data, opts *Options) error {
+	tpl, err := template.New("output").Funcs(funcs).Parse(opts.template)
+	if err != nil {
+		return err
+	}
+
+	for _, it := range d.items {
+		if err := tpl.Execute(w, it.value); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/stretchr/testify/require"
+)
+
+var things = []aiot.Thing{
+	{ID: "1", Name: "sensor-1", Key: "k1", Metadata: aiot.Metadata{"floor": "1"}},
+	{ID: "2", Name: "sensor-2", Key: "k2", Metadata: aiot.Metadata{"room": "a"}},
+}
+
+func render(t *testing.T, v interface{}, opts *Options) string {
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, v, opts))
+
+	return buf.String()
+}
+
+func Test_Table(t *testing.T) {
+	require := require.New(t)
+
+	out := render(t, List{Items: things, Total: 5}, nil)
+	require.Equal(""+
+		"ID  NAME      KEY  METADATA.FLOOR  METADATA.ROOM\n"+
+		"1   sensor-1  k1   1               \n"+
+		"2   sensor-2  k2                   a\n", out)
+
+	out = render(t, things, NewOptions().SetColumns("name", "metadata.room").SetNoHeader(true))
+	require.Equal("sensor-1  \nsensor-2  a\n", out)
+
+	out = render(t, things[0], nil)
+	require.Equal(""+
+		"id:              1\n"+
+		"name:            sensor-1\n"+
+		"key:             k1\n"+
+		"metadata.floor:  1\n", out)
+
+	out = render(t, []aiot.Channel{}, nil)
+	require.Equal("ID  NAME  KEY\n", out)
+}
+
+func Test_CSV(t *testing.T) {
+	require := require.New(t)
+
+	out := render(t, things, NewOptions().SetFormat(FORMAT_CSV))
+	require.Equal(""+
+		"id,name,key,metadata.floor,metadata.room\n"+
+		"1,sensor-1,k1,1,\n"+
+		"2,sensor-2,k2,,a\n", out)
+
+	gateways := []aiot.Gateway{{
+		ID:            "g1",
+		Name:          "gw, 1",
+		UnderlayThing: aiot.Thing{ID: "t1", Metadata: aiot.Metadata{"x": "y"}},
+	}}
+	out = render(t, gateways, NewOptions().SetFormat(FORMAT_CSV).SetColumns("name", "thing.id", "thing.metadata.x"))
+	require.Equal("name,thing.id,thing.metadata.x\n\"gw, 1\",t1,y\n", out)
+}
+
+func Test_JSON(t *testing.T) {
+	require := require.New(t)
+
+	out := render(t, List{Items: things[:1], Total: 5}, NewOptions().SetFormat(FORMAT_JSON))
+	require.JSONEq(`{
+		"total": 5,
+		"items": [{"id": "1", "name": "sensor-1", "key": "k1", "metadata": {"floor": "1"}}]
+	}`, out)
+
+	out = render(t, things, NewOptions().SetFormat(FORMAT_JSON).SetColumns("id", "metadata.floor"))
+	require.JSONEq(`[{"id": "1", "metadata.floor": "1"}, {"id": "2", "metadata.floor": null}]`, out)
+
+	out = render(t, map[string]bool{"b": false, "a": true}, NewOptions().SetFormat(FORMAT_JSON))
+	require.JSONEq(`[{"id": "a", "online": true}, {"id": "b", "online": false}]`, out)
+}
+
+func Test_YAML(t *testing.T) {
+	out := render(t, aiot.User{Email: "ops@aiot.vn", CustomerId: 7}, NewOptions().SetFormat(FORMAT_YAML).SetColumns("email", "customerId"))
+	require.Equal(t, "customerId: 7\nemail: ops@aiot.vn\n", out)
+}
+
+func Test_Template(t *testing.T) {
+	require := require.New(t)
+
+	out := render(t, List{Items: things, Total: 2}, NewOptions().SetTemplate(`{{.ID}} {{.Metadata.Text "floor"}}`))
+	require.Equal("1 1\n2 \n", out)
+
+	out = render(t, things[1], NewOptions().SetTemplate(`{{json .Metadata}}`))
+	require.Equal("{\"room\":\"a\"}\n", out)
+
+	require.Error(Render(&bytes.Buffer{}, things, NewOptions().SetTemplate(`{{.Nope}}`)))
+}
+
+func Test_ParseOptions(t *testing.T) {
+	require := require.New(t)
+
+	opts, err := ParseOptions("")
+	require.NoError(err)
+	require.Equal(FORMAT_TABLE, opts.format)
+
+	opts, err = ParseOptions("yaml")
+	require.NoError(err)
+	require.Equal(FORMAT_YAML, opts.format)
+
+	opts, err = ParseOptions("template={{.Name}}={{.ID}}")
+	require.NoError(err)
+	require.Equal(FORMAT_TEMPLATE, opts.format)
+	require.Equal("{{.Name}}={{.ID}}", opts.template)
+
+	for _, s := range []string{"xml", "template", "json=x"} {
+		_, err = ParseOptions(s)
+		require.Error(err, s)
+	}
+}
+
+func Test_Unsupported(t *testing.T) {
+	err := Render(&bytes.Buffer{}, 42, nil)
+	require.EqualError(t, err, "render.Render -> unsupported value of type int")
+}
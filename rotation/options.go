@@ -0,0 +1,28 @@
+package rotation
+
+import "time"
+
+type Options struct {
+	grace     time.Duration
+	stateFile string
+}
+
+func NewOptions() *Options {
+	return &Options{
+		grace: 24 * time.Hour,
+	}
+}
+
+// Thời gian key cũ còn được lưu lại để khôi phục sau khi đổi key, mặc định
+// 24 giờ
+func (opts *Options) SetGracePeriod(d time.Duration) *Options {
+	opts.grace = d
+	return opts
+}
+
+// File lưu các lần đổi key còn trong thời gian khôi phục. File chứa key cũ
+// nên chỉ chủ sở hữu đọc được. Rỗng (mặc định) là chỉ lưu trong bộ nhớ.
+func (opts *Options) SetStateFile(path string) *Options {
+	opts.stateFile = path
+	return opts
+}
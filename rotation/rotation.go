@@ -0,0 +1,381 @@
+// Package rotation đổi key của thing mà vẫn giữ các kết nối với channel và
+// gateway của thing, đồng thời lưu key cũ trong một khoảng thời gian để có
+// thể khôi phục.
+//
+//	r, err := rotation.NewRotator(client, token, rotation.NewOptions().
+//		SetStateFile("rotation.json"))
+//	rec, err := r.Rotate(thingID)
+//	// thiết bị không nhận được key mới
+//	err = r.Rollback(thingID)
+package rotation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+)
+
+var (
+	ErrNoRecord     = errors.New("no key rotation recorded for thing")
+	ErrGraceExpired = errors.New("grace period of key rotation has expired")
+)
+
+// Gateway dùng thing tại thời điểm đổi key
+type Gateway struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+// Record là một lần đổi key của thing
+type Record struct {
+	ThingID string
+	OldKey  string
+	// Rỗng nếu việc đổi key chưa hoàn tất
+	NewKey    string
+	RotatedAt time.Time
+	// Sau thời điểm này không thể khôi phục key cũ
+	Expires time.Time
+	// Các channel kết nối với thing tại thời điểm đổi key
+	Channels []string
+	Gateway  *Gateway
+}
+
+// Rotator đổi và khôi phục key của thing. Mỗi thing chỉ giữ lần đổi key gần
+// nhất, khôi phục sẽ trả về key ngay trước đó.
+type Rotator struct {
+	api   aiot.API
+	token string
+	opts  *Options
+
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// Tạo mới một Rotator. Nếu có state file, các lần đổi key trong đó vẫn có
+// thể được khôi phục.
+func NewRotator(api aiot.API, token string, opts *Options) (*Rotator, error) {
+	const op = "rotation.NewRotator"
+
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	r := &Rotator{
+		api:     api,
+		token:   token,
+		opts:    opts,
+		records: map[string]Record{},
+	}
+
+	if opts.stateFile != "" {
+		records, err := loadState(opts.stateFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s -> %w", op, err)
+		}
+		for _, rec := range records {
+			r.records[rec.ThingID] = rec
+		}
+	}
+
+	return r, nil
+}
+
+// Rotate đổi key của thing. Key cũ được lưu trước khi đổi; sau khi đổi, các
+// kết nối với channel bị mất được kết nối lại và gateway bị mất được tạo lại
+// với thing. Nếu đổi key lỗi, lần đổi key vẫn được lưu với NewKey rỗng.
+func (r *Rotator) Rotate(thingID string) (Record, error) {
+	const op = "rotation.Rotate"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	thing, err := r.api.ThingProfile(r.token, thingID)
+	if err != nil {
+		return Record{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	channels, err := inventory.Connected(r.api, r.token, thingID)
+	if err != nil {
+		return Record{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	gw, err := r.gatewayOf(thingID)
+	if err != nil {
+		return Record{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	now := time.Now()
+	rec := Record{
+		ThingID:   thingID,
+		OldKey:    thing.Key,
+		RotatedAt: now,
+		Expires:   now.Add(r.opts.grace),
+		Gateway:   gw,
+	}
+	for _, c := range channels {
+		rec.Channels = append(rec.Channels, c.ID)
+	}
+
+	// lưu key cũ trước khi đổi để vẫn khôi phục được nếu tiến trình dừng
+	// giữa chừng
+	prev, hadPrev := r.records[thingID]
+	r.records[thingID] = rec
+	if err := r.save(); err != nil {
+		r.forget(thingID, prev, hadPrev)
+		return Record{}, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	// request lỗi vẫn có thể đã đổi key trên gateway, nên giữ lại key cũ
+	// (NewKey rỗng) để có thể khôi phục bằng Rollback
+	key, err := r.api.RotateThingKey(r.token, thingID)
+	if err != nil {
+		return rec, fmt.Errorf("%s -> %w", op, err)
+	}
+	rec.NewKey = key
+
+	restoreErr := r.restore(&rec)
+
+	r.records[thingID] = rec
+	if err := r.save(); err != nil {
+		return rec, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	if restoreErr != nil {
+		return rec, fmt.Errorf("%s -> %w", op, restoreErr)
+	}
+
+	return rec, nil
+}
+
+// Rollback đặt lại key cũ của lần đổi key gần nhất của thing và kết nối lại
+// channel, gateway như lúc đổi key. Trả về ErrNoRecord nếu thing chưa được
+// đổi key, ErrGraceExpired nếu đã hết thời gian khôi phục.
+func (r *Rotator) Rollback(thingID string) error {
+	const op = "rotation.Rollback"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.records[thingID]
+	if !ok {
+		return fmt.Errorf("%s -> %w", op, ErrNoRecord)
+	}
+
+	if time.Now().After(rec.Expires) {
+		return fmt.Errorf("%s -> %w", op, ErrGraceExpired)
+	}
+
+	if err := r.setKey(thingID, rec.OldKey); err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	if err := r.restore(&rec); err != nil {
+		r.records[thingID] = rec
+		r.save()
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	delete(r.records, thingID)
+	if err := r.save(); err != nil {
+		return fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return nil
+}
+
+// Records trả về các lần đổi key đang được lưu, theo thứ tự id của thing
+func (r *Rotator) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Record, 0, len(r.records))
+	for _, rec := range r.records {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ThingID < out[j].ThingID
+	})
+
+	return out
+}
+
+// Prune xóa key cũ của các lần đổi key đã hết thời gian khôi phục và trả về
+// các lần đổi key đã xóa
+func (r *Rotator) Prune() ([]Record, error) {
+	const op = "rotation.Prune"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var pruned []Record
+	for id, rec := range r.records {
+		if now.After(rec.Expires) {
+			pruned = append(pruned, rec)
+			delete(r.records, id)
+		}
+	}
+	sort.Slice(pruned, func(i, j int) bool {
+		return pruned[i].ThingID < pruned[j].ThingID
+	})
+
+	if len(pruned) > 0 {
+		if err := r.save(); err != nil {
+			return nil, fmt.Errorf("%s -> %w", op, err)
+		}
+	}
+
+	return pruned, nil
+}
+
+// restore kết nối lại các channel bị mất và tạo lại gateway nếu thing không
+// còn gateway. Id của gateway mới được cập nhật vào rec.
+func (r *Rotator) restore(rec *Record) error {
+	channels, err := inventory.Connected(r.api, r.token, rec.ThingID)
+	if err != nil {
+		return err
+	}
+
+	connected := map[string]bool{}
+	for _, c := range channels {
+		connected[c.ID] = true
+	}
+
+	var missing []string
+	for _, id := range rec.Channels {
+		if !connected[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := r.api.Connect(r.token, missing, []string{rec.ThingID}); err != nil {
+			return err
+		}
+	}
+
+	if rec.Gateway == nil {
+		return nil
+	}
+
+	gw, err := r.gatewayOf(rec.ThingID)
+	if err != nil || gw != nil {
+		return err
+	}
+
+	err = r.api.CreateGateway(r.token, aiot.CreateGatewayInput{
+		Name:        rec.Gateway.Name,
+		Description: rec.Gateway.Description,
+		ThingID:     rec.ThingID,
+	})
+	if err != nil {
+		return err
+	}
+
+	gw, err = r.gatewayOf(rec.ThingID)
+	if err != nil {
+		return err
+	}
+	if gw != nil {
+		rec.Gateway = gw
+	}
+
+	return nil
+}
+
+// setKey đặt key của thing qua UpdateThing, giữ nguyên tên và metadata
+func (r *Rotator) setKey(thingID, key string) error {
+	thing, err := r.api.ThingProfile(r.token, thingID)
+	if err != nil {
+		return err
+	}
+
+	return r.api.UpdateThing(r.token, aiot.UpdateThingInput{
+		ID:       thing.ID,
+		Name:     thing.Name,
+		Metadata: thing.Metadata,
+		Key:      key,
+		Version:  thing.Version,
+	})
+}
+
+// gatewayOf trả về gateway dùng thing, nil nếu không có
+func (r *Rotator) gatewayOf(thingID string) (*Gateway, error) {
+	gateways, err := r.api.ListGateway(r.token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range gateways {
+		if g.UnderlayThing.ID == thingID {
+			return &Gateway{ID: g.ID, Name: g.Name, Description: g.Description}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *Rotator) forget(thingID string, prev Record, hadPrev bool) {
+	if hadPrev {
+		r.records[thingID] = prev
+	} else {
+		delete(r.records, thingID)
+	}
+}
+
+func (r *Rotator) save() error {
+	if r.opts.stateFile == "" {
+		return nil
+	}
+
+	records := make([]Record, 0, len(r.records))
+	for _, rec := range r.records {
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ThingID < records[j].ThingID
+	})
+
+	return saveState(r.opts.stateFile, records)
+}
+
+func loadState(path string) ([]Record, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("state file %s: %w", path, err)
+	}
+
+	return records, nil
+}
+
+// saveState ghi qua file tạm để state file luôn đầy đủ. File chứa key cũ nên
+// chỉ chủ sở hữu đọc được.
+func saveState(path string, records []Record) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,217 @@
+package rotation_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+	"github.com/mobifone-aiot/aiot-go/rotation"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	client   aiot.Client
+	token    string
+	thing    aiot.Thing
+	channels []string
+}
+
+// setup tạo một thing kết nối với hai channel và được dùng làm gateway
+func setup(t *testing.T) fixture {
+	require := require.New(t)
+
+	client, token := aiottest.NewAccount(t)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "telemetry"}))
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "control"}))
+
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+	channels, err := inventory.Channels(client, token)
+	require.NoError(err)
+
+	f := fixture{client: client, token: token, thing: things[0]}
+	for _, c := range channels {
+		f.channels = append(f.channels, c.ID)
+	}
+
+	require.NoError(client.Connect(token, f.channels, []string{f.thing.ID}))
+	require.NoError(client.CreateGateway(token, aiot.CreateGatewayInput{Name: "gw", Description: "tầng 1", ThingID: f.thing.ID}))
+
+	return f
+}
+
+func (f fixture) state(t *testing.T) (key string, channels []string, gateway string) {
+	thing, err := f.client.ThingProfile(f.token, f.thing.ID)
+	require.NoError(t, err)
+
+	connected, err := inventory.Connected(f.client, f.token, f.thing.ID)
+	require.NoError(t, err)
+	for _, c := range connected {
+		channels = append(channels, c.ID)
+	}
+
+	gateways, err := f.client.ListGateway(f.token)
+	require.NoError(t, err)
+	for _, g := range gateways {
+		if g.UnderlayThing.ID == f.thing.ID {
+			gateway = g.Name
+		}
+	}
+
+	return thing.Key, channels, gateway
+}
+
+func Test_RotateAndRollback(t *testing.T) {
+	require := require.New(t)
+
+	f := setup(t)
+	path := filepath.Join(t.TempDir(), "rotation.json")
+
+	r, err := rotation.NewRotator(f.client, f.token, rotation.NewOptions().SetStateFile(path))
+	require.NoError(err)
+
+	rec, err := r.Rotate(f.thing.ID)
+	require.NoError(err)
+	require.Equal(f.thing.Key, rec.OldKey)
+	require.NotEqual(f.thing.Key, rec.NewKey)
+	require.ElementsMatch(f.channels, rec.Channels)
+	require.Equal("gw", rec.Gateway.Name)
+	require.WithinDuration(time.Now().Add(24*time.Hour), rec.Expires, time.Minute)
+
+	key, channels, gateway := f.state(t)
+	require.Equal(rec.NewKey, key)
+	require.ElementsMatch(f.channels, channels)
+	require.Equal("gw", gateway)
+
+	// key cũ được lưu trong state file chỉ chủ sở hữu đọc được
+	info, err := os.Stat(path)
+	require.NoError(err)
+	require.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	// tiến trình mới vẫn khôi phục được từ state file
+	r, err = rotation.NewRotator(f.client, f.token, rotation.NewOptions().SetStateFile(path))
+	require.NoError(err)
+	require.Len(r.Records(), 1)
+
+	require.NoError(r.Rollback(f.thing.ID))
+	key, channels, gateway = f.state(t)
+	require.Equal(f.thing.Key, key)
+	require.ElementsMatch(f.channels, channels)
+	require.Equal("gw", gateway)
+	require.Empty(r.Records())
+
+	err = r.Rollback(f.thing.ID)
+	require.True(errors.Is(err, rotation.ErrNoRecord), err)
+}
+
+// lossy giả lập backend làm mất kết nối và gateway khi đổi key
+type lossy struct {
+	aiot.API
+}
+
+func (l lossy) RotateThingKey(token, thingID string) (string, error) {
+	key, err := l.API.RotateThingKey(token, thingID)
+	if err != nil {
+		return "", err
+	}
+
+	channels, _ := inventory.Connected(l.API, token, thingID)
+	l.API.Disconnect(token, channels[0].ID, thingID)
+
+	gateways, _ := l.API.ListGateway(token)
+	l.API.DeleteGateway(token, gateways[0].ID)
+
+	return key, nil
+}
+
+func Test_RotateRestores(t *testing.T) {
+	require := require.New(t)
+
+	f := setup(t)
+	gateways, err := f.client.ListGateway(f.token)
+	require.NoError(err)
+
+	r, err := rotation.NewRotator(lossy{f.client}, f.token, nil)
+	require.NoError(err)
+
+	rec, err := r.Rotate(f.thing.ID)
+	require.NoError(err)
+
+	key, channels, gateway := f.state(t)
+	require.Equal(rec.NewKey, key)
+	require.ElementsMatch(f.channels, channels)
+	require.Equal("gw", gateway)
+
+	// gateway được tạo lại với id mới
+	require.NotEqual(gateways[0].ID, rec.Gateway.ID)
+	require.Equal("tầng 1", rec.Gateway.Description)
+}
+
+func Test_GracePeriod(t *testing.T) {
+	require := require.New(t)
+
+	f := setup(t)
+
+	r, err := rotation.NewRotator(f.client, f.token, rotation.NewOptions().SetGracePeriod(time.Millisecond))
+	require.NoError(err)
+
+	_, err = r.Rotate(f.thing.ID)
+	require.NoError(err)
+	time.Sleep(5 * time.Millisecond)
+
+	err = r.Rollback(f.thing.ID)
+	require.True(errors.Is(err, rotation.ErrGraceExpired), err)
+
+	pruned, err := r.Prune()
+	require.NoError(err)
+	require.Len(pruned, 1)
+	require.Equal(f.thing.Key, pruned[0].OldKey)
+	require.Empty(r.Records())
+}
+
+// failing giả lập backend từ chối đổi key
+type failing struct {
+	aiot.API
+}
+
+func (failing) RotateThingKey(token, thingID string) (string, error) {
+	return "", errors.New("rotation disabled")
+}
+
+func Test_RotateFailure(t *testing.T) {
+	require := require.New(t)
+
+	f := setup(t)
+	path := filepath.Join(t.TempDir(), "rotation.json")
+
+	r, err := rotation.NewRotator(failing{f.client}, f.token, rotation.NewOptions().SetStateFile(path))
+	require.NoError(err)
+
+	rec, err := r.Rotate(f.thing.ID)
+	require.EqualError(err, "rotation.Rotate -> rotation disabled")
+	require.Equal(f.thing.Key, rec.OldKey)
+	require.Empty(rec.NewKey)
+	require.Equal([]rotation.Record{rec}, r.Records())
+
+	// state file vẫn giữ key cũ của lần đổi key thất bại để khôi phục
+	r, err = rotation.NewRotator(f.client, f.token, rotation.NewOptions().SetStateFile(path))
+	require.NoError(err)
+	records := r.Records()
+	require.Len(records, 1)
+	require.Equal(f.thing.Key, records[0].OldKey)
+	require.Empty(records[0].NewKey)
+
+	require.NoError(r.Rollback(f.thing.ID))
+	key, channels, gateway := f.state(t)
+	require.Equal(f.thing.Key, key)
+	require.ElementsMatch(f.channels, channels)
+	require.Equal("gw", gateway)
+	require.Empty(r.Records())
+}
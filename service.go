@@ -0,0 +1,58 @@
+package aiot
+
+// Các interface dưới đây được Client hiện thực, cho phép thay Client bằng
+// một bản giả lập (ví dụ package aiotmock) khi viết unit test.
+
+// Đăng nhập, xác thực token và thông tin người dùng
+type AuthService interface {
+	Token(email, password string) (string, error)
+	TokenVerify(token string) (bool, error)
+	ResetPassword(token, newPW, oldPW string) error
+	UserProfile(token string) (User, error)
+}
+
+// Quản lý thing và kết nối giữa thing với channel
+type ThingService interface {
+	ListThingsByUser(token string, opts *ListThingsByUserOptions) ([]Thing, int, error)
+	CreateThing(token string, in CreateThingInput) error
+	DeleteThing(token, thingID string) error
+	ThingProfile(token, thingID string) (Thing, error)
+	UpdateThing(token string, in UpdateThingInput) error
+	RotateThingKey(token, thingID string) (string, error)
+	PatchThingMetadata(token, thingID string, patch Metadata) (Thing, error)
+	ListChannelByThing(token, thingID string, opts *ListChannelByThingOptions) ([]Channel, int, error)
+	Connect(token string, channelIDs, thingIDs []string) error
+	Disconnect(token string, channelID, thingID string) error
+}
+
+// Quản lý channel
+type ChannelService interface {
+	CreateChannel(token string, in CreateChannelInput) error
+	UpdateChannel(token string, in UpdateChannelInput) error
+	PatchChannelMetadata(token, channelID string, patch Metadata) (Channel, error)
+	DeleteChannel(token, channelID string) error
+	ChannelProfile(token, channelID string) (Channel, error)
+	ListAllChannel(token string, opts *ListAllChannelOptions) ([]Channel, int, error)
+	ListChannelByUser(token string, opts *ListChannelByUserOptions) ([]Channel, int, error)
+}
+
+// Quản lý gateway
+type GatewayService interface {
+	CreateGateway(token string, in CreateGatewayInput) error
+	UpdateGateway(token string, in UpdateGatewayInput) error
+	DeleteGateway(token, id string) error
+	GatewayProfile(token, id string) (Gateway, error)
+	ListGateway(token string) ([]Gateway, error)
+	GatewayStatus(token string) (map[string]bool, error)
+	GatewayActiveDeviceCount(token, gateID string) (int, error)
+}
+
+// Toàn bộ các thao tác với nền tảng AIOT
+type API interface {
+	AuthService
+	ThingService
+	ChannelService
+	GatewayService
+}
+
+var _ API = Client{}
@@ -0,0 +1,43 @@
+//go:build go1.21
+
+package aiot
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger dùng l để ghi log của Client. LOG_LEVEL_DEBUG, LOG_LEVEL_INFO
+// và LOG_LEVEL_ERROR tương ứng với slog.LevelDebug, slog.LevelInfo và
+// slog.LevelError.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) Enabled(level LogLevel) bool {
+	return s.l.Enabled(context.Background(), slogLevel(level))
+}
+
+func (s slogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+
+	s.l.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LOG_LEVEL_DEBUG:
+		return slog.LevelDebug
+	case LOG_LEVEL_ERROR:
+		return slog.LevelError
+	}
+
+	return slog.LevelInfo
+}
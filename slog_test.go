@@ -0,0 +1,28 @@
+//go:build go1.21
+
+package aiot_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SlogLogger(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	logger := aiot.NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	require.False(logger.Enabled(aiot.LOG_LEVEL_DEBUG))
+	require.True(logger.Enabled(aiot.LOG_LEVEL_ERROR))
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions().SetLogger(logger))
+	_, err := client.UserProfile(token)
+	require.NoError(err)
+
+	require.Contains(buf.String(), `"level":"INFO","msg":"aiot call","op":"aiot.UserProfile","method":"GET","path":"/api-gw/v1/user/profile","status":200`)
+	require.NotContains(buf.String(), "body")
+}
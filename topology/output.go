@@ -0,0 +1,102 @@
+package topology
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteDOT ghi đồ thị dưới dạng Graphviz DOT, ví dụ để chạy
+// "dot -Tsvg -o topology.svg"
+func (t *Topology) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph aiot {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+
+	shapes := map[NodeKind]string{
+		NODE_GATEWAY: "box3d",
+		NODE_THING:   "ellipse",
+		NODE_CHANNEL: "cds",
+	}
+	for _, nodes := range [][]Node{t.gateways, t.things, t.channels} {
+		for _, n := range nodes {
+			fmt.Fprintf(bw, "  %s [label=%s, shape=%s];\n", strconv.Quote(n.ID), strconv.Quote(n.Name), shapes[n.Kind])
+		}
+	}
+
+	for _, e := range t.edges {
+		fmt.Fprintf(bw, "  %s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteMermaid ghi đồ thị dưới dạng sơ đồ Mermaid (graph LR), có thể nhúng
+// vào Markdown
+func (t *Topology) WriteMermaid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	// id của Mermaid chỉ nên gồm chữ và số
+	ids := map[string]string{}
+	prefix := map[NodeKind]string{NODE_GATEWAY: "g", NODE_THING: "t", NODE_CHANNEL: "c"}
+	shape := map[NodeKind][2]string{
+		NODE_GATEWAY: {"[[", "]]"},
+		NODE_THING:   {"(", ")"},
+		NODE_CHANNEL: {"[(", ")]"},
+	}
+
+	fmt.Fprintln(bw, "graph LR")
+	for _, nodes := range [][]Node{t.gateways, t.things, t.channels} {
+		for i, n := range nodes {
+			id := prefix[n.Kind] + strconv.Itoa(i)
+			ids[n.ID] = id
+
+			s := shape[n.Kind]
+			fmt.Fprintf(bw, "  %s%s\"%s\"%s\n", id, s[0], mermaidEscape(n.Name), s[1])
+		}
+	}
+
+	for _, e := range t.edges {
+		from, ok1 := ids[e.From]
+		to, ok2 := ids[e.To]
+		if ok1 && ok2 {
+			fmt.Fprintf(bw, "  %s --> %s\n", from, to)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s)
+}
+
+// WriteJSON ghi đồ thị dưới dạng {"nodes": [...], "edges": [...]}
+func (t *Topology) WriteJSON(w io.Writer) error {
+	var doc struct {
+		Nodes []Node `json:"nodes"`
+		Edges []Edge `json:"edges"`
+	}
+
+	doc.Nodes = append(doc.Nodes, t.gateways...)
+	doc.Nodes = append(doc.Nodes, t.things...)
+	doc.Nodes = append(doc.Nodes, t.channels...)
+	doc.Edges = t.edges
+
+	if doc.Nodes == nil {
+		doc.Nodes = []Node{}
+	}
+	if doc.Edges == nil {
+		doc.Edges = []Edge{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(doc)
+}
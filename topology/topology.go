@@ -0,0 +1,214 @@
+// Package topology dựng đồ thị các thing, channel, kết nối và gateway của một
+// tài khoản AIOT, hỗ trợ truy vấn và xuất ra Graphviz DOT, Mermaid hoặc JSON.
+//
+// Đồ thị có hai loại cạnh: gateway -> thing của gateway và thing -> channel mà
+// thing kết nối tới.
+package topology
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+)
+
+type NodeKind string
+type EdgeKind string
+
+var (
+	NODE_THING   NodeKind = "thing"
+	NODE_CHANNEL NodeKind = "channel"
+	NODE_GATEWAY NodeKind = "gateway"
+
+	// gateway -> thing của gateway
+	EDGE_GATEWAY EdgeKind = "gateway"
+	// thing -> channel
+	EDGE_CONNECTION EdgeKind = "connection"
+)
+
+type Node struct {
+	ID       string        `json:"id"`
+	Kind     NodeKind      `json:"kind"`
+	Name     string        `json:"name"`
+	Metadata aiot.Metadata `json:"metadata,omitempty"`
+}
+
+type Edge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+// FanOut là số thing kết nối tới một channel
+type FanOut struct {
+	Channel Node
+	Things  int
+}
+
+// Topology là đồ thị trong bộ nhớ. Các danh sách node được sắp xếp theo tên.
+type Topology struct {
+	things   []Node
+	channels []Node
+	gateways []Node
+	edges    []Edge
+
+	nodes map[string]Node
+	out   map[string][]string
+	in    map[string][]string
+}
+
+// Build đọc toàn bộ thing, channel, kết nối và gateway của tài khoản sở hữu
+// token và dựng đồ thị
+func Build(api aiot.API, token string) (*Topology, error) {
+	const op = "topology.Build"
+
+	s, err := inventory.Take(api, token)
+	if err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	return FromSnapshot(s), nil
+}
+
+// FromSnapshot dựng đồ thị từ một snapshot đã có
+func FromSnapshot(s inventory.Snapshot) *Topology {
+	t := &Topology{
+		nodes: map[string]Node{},
+		out:   map[string][]string{},
+		in:    map[string][]string{},
+	}
+
+	for _, th := range s.Things {
+		n := Node{ID: th.ID, Kind: NODE_THING, Name: th.Name, Metadata: th.Metadata}
+		t.things = append(t.things, n)
+		t.nodes[n.ID] = n
+	}
+	for _, c := range s.Channels {
+		n := Node{ID: c.ID, Kind: NODE_CHANNEL, Name: c.Name, Metadata: c.Metadata}
+		t.channels = append(t.channels, n)
+		t.nodes[n.ID] = n
+	}
+	for _, g := range s.Gateways {
+		n := Node{ID: g.ID, Kind: NODE_GATEWAY, Name: g.Name}
+		t.gateways = append(t.gateways, n)
+		t.nodes[n.ID] = n
+	}
+
+	for _, g := range s.Gateways {
+		t.addEdge(Edge{From: g.ID, To: g.UnderlayThing.ID, Kind: EDGE_GATEWAY})
+	}
+	for _, c := range s.Connections {
+		t.addEdge(Edge{From: c.ThingID, To: c.ChannelID, Kind: EDGE_CONNECTION})
+	}
+
+	return t
+}
+
+func (t *Topology) addEdge(e Edge) {
+	t.edges = append(t.edges, e)
+	t.out[e.From] = append(t.out[e.From], e.To)
+	t.in[e.To] = append(t.in[e.To], e.From)
+}
+
+func (t *Topology) Things() []Node {
+	return append([]Node(nil), t.things...)
+}
+
+func (t *Topology) Channels() []Node {
+	return append([]Node(nil), t.channels...)
+}
+
+func (t *Topology) Gateways() []Node {
+	return append([]Node(nil), t.gateways...)
+}
+
+func (t *Topology) Edges() []Edge {
+	return append([]Edge(nil), t.edges...)
+}
+
+// Node tìm node theo id
+func (t *Topology) Node(id string) (Node, bool) {
+	n, ok := t.nodes[id]
+	return n, ok
+}
+
+// ChannelsOf trả về các channel mà thing kết nối tới
+func (t *Topology) ChannelsOf(thingID string) []Node {
+	return t.neighbours(t.out[thingID], NODE_CHANNEL)
+}
+
+// ThingsOn trả về các thing kết nối tới channel
+func (t *Topology) ThingsOn(channelID string) []Node {
+	return t.neighbours(t.in[channelID], NODE_THING)
+}
+
+// GatewayOf trả về gateway dùng thing làm thing của gateway, nếu có
+func (t *Topology) GatewayOf(thingID string) (Node, bool) {
+	gateways := t.neighbours(t.in[thingID], NODE_GATEWAY)
+	if len(gateways) == 0 {
+		return Node{}, false
+	}
+
+	return gateways[0], true
+}
+
+func (t *Topology) neighbours(ids []string, kind NodeKind) []Node {
+	var out []Node
+	for _, id := range ids {
+		if n, ok := t.nodes[id]; ok && n.Kind == kind {
+			out = append(out, n)
+		}
+	}
+	sortNodes(out)
+
+	return out
+}
+
+// OrphanChannels trả về các channel không có thing nào kết nối tới
+func (t *Topology) OrphanChannels() []Node {
+	var out []Node
+	for _, c := range t.channels {
+		if len(t.ThingsOn(c.ID)) == 0 {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// ThingsWithoutChannels trả về các thing không kết nối tới channel nào. Thing
+// của gateway cũng được tính.
+func (t *Topology) ThingsWithoutChannels() []Node {
+	var out []Node
+	for _, th := range t.things {
+		if len(t.ChannelsOf(th.ID)) == 0 {
+			out = append(out, th)
+		}
+	}
+
+	return out
+}
+
+// FanOut trả về số thing kết nối tới từng channel, nhiều nhất trước
+func (t *Topology) FanOut() []FanOut {
+	out := make([]FanOut, 0, len(t.channels))
+	for _, c := range t.channels {
+		out = append(out, FanOut{Channel: c, Things: len(t.ThingsOn(c.ID))})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Things > out[j].Things
+	})
+
+	return out
+}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Name != nodes[j].Name {
+			return nodes[i].Name < nodes[j].Name
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+}
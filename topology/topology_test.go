@@ -0,0 +1,130 @@
+package topology_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+	"github.com/mobifone-aiot/aiot-go/topology"
+	"github.com/stretchr/testify/require"
+)
+
+// gw-1 -> t1 -> c1, t2 -> c1, t3 không có channel, c2 không có thing
+var snapshot = inventory.Snapshot{
+	Things: []aiot.Thing{
+		{ID: "t1", Name: "gw-thing"},
+		{ID: "t2", Name: "sensor"},
+		{ID: "t3", Name: "spare"},
+	},
+	Channels: []aiot.Channel{
+		{ID: "c2", Name: "alerts"},
+		{ID: "c1", Name: `tele "metry"`},
+	},
+	Connections: []inventory.Connection{
+		{ThingID: "t1", ChannelID: "c1"},
+		{ThingID: "t2", ChannelID: "c1"},
+	},
+	Gateways: []aiot.Gateway{
+		{ID: "g1", Name: "gw-1", UnderlayThing: aiot.Thing{ID: "t1"}},
+	},
+}
+
+func names(nodes []topology.Node) []string {
+	var out []string
+	for _, n := range nodes {
+		out = append(out, n.Name)
+	}
+
+	return out
+}
+
+func Test_Queries(t *testing.T) {
+	require := require.New(t)
+	topo := topology.FromSnapshot(snapshot)
+
+	require.Equal([]string{"alerts"}, names(topo.OrphanChannels()))
+	require.Equal([]string{"spare"}, names(topo.ThingsWithoutChannels()))
+	require.Equal([]string{"gw-thing", "sensor"}, names(topo.ThingsOn("c1")))
+	require.Equal([]string{`tele "metry"`}, names(topo.ChannelsOf("t2")))
+
+	fanOut := topo.FanOut()
+	require.Len(fanOut, 2)
+	require.Equal("c1", fanOut[0].Channel.ID)
+	require.Equal(2, fanOut[0].Things)
+	require.Equal(0, fanOut[1].Things)
+
+	gw, ok := topo.GatewayOf("t1")
+	require.True(ok)
+	require.Equal("gw-1", gw.Name)
+
+	_, ok = topo.GatewayOf("t2")
+	require.False(ok)
+}
+
+func Test_Output(t *testing.T) {
+	require := require.New(t)
+	topo := topology.FromSnapshot(snapshot)
+
+	var buf bytes.Buffer
+	require.NoError(topo.WriteDOT(&buf))
+	require.Equal(`digraph aiot {
+  rankdir=LR;
+  "g1" [label="gw-1", shape=box3d];
+  "t1" [label="gw-thing", shape=ellipse];
+  "t2" [label="sensor", shape=ellipse];
+  "t3" [label="spare", shape=ellipse];
+  "c2" [label="alerts", shape=cds];
+  "c1" [label="tele \"metry\"", shape=cds];
+  "g1" -> "t1";
+  "t1" -> "c1";
+  "t2" -> "c1";
+}
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(topo.WriteMermaid(&buf))
+	require.Equal(`graph LR
+  g0[["gw-1"]]
+  t0("gw-thing")
+  t1("sensor")
+  t2("spare")
+  c0[("alerts")]
+  c1[("tele #quot;metry#quot;")]
+  g0 --> t0
+  t0 --> c1
+  t1 --> c1
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(topo.WriteJSON(&buf))
+	require.Contains(buf.String(), `"kind": "gateway"`)
+	require.Contains(buf.String(), `{
+      "from": "t2",
+      "to": "c1",
+      "kind": "connection"
+    }`)
+}
+
+func Test_Build(t *testing.T) {
+	require := require.New(t)
+
+	client, token := aiottest.NewAccount(t)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "telemetry"}))
+
+	topo, err := topology.Build(client, token)
+	require.NoError(err)
+	require.Equal([]string{"telemetry"}, names(topo.OrphanChannels()))
+
+	things, channels := topo.Things(), topo.Channels()
+	require.NoError(client.Connect(token, []string{channels[0].ID}, []string{things[0].ID}))
+
+	topo, err = topology.Build(client, token)
+	require.NoError(err)
+	require.Empty(topo.OrphanChannels())
+	require.Empty(topo.ThingsWithoutChannels())
+	require.Len(topo.Edges(), 1)
+}
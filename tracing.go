@@ -0,0 +1,123 @@
+package aiot
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tên các thuộc tính của span
+const (
+	ATTR_THING_ID    = "aiot.thing.id"
+	ATTR_CHANNEL_ID  = "aiot.channel.id"
+	ATTR_GATEWAY_ID  = "aiot.gateway.id"
+	ATTR_HTTP_METHOD = "http.request.method"
+	ATTR_URL_PATH    = "url.path"
+	ATTR_HTTP_STATUS = "http.response.status_code"
+	ATTR_ERROR_KIND  = "error.type"
+)
+
+// SpanContext là định danh của một span theo W3C trace context
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&1 == 1
+}
+
+// TraceParent trả về giá trị header traceparent của span
+func (sc SpanContext) TraceParent() string {
+	return fmt.Sprintf("00-%x-%x-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceParent đọc giá trị header traceparent, ví dụ
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func ParseTraceParent(s string) (SpanContext, error) {
+	const op operation = "aiot.ParseTraceParent"
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || parts[0] == "ff" || len(parts[0]) != 2 ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 ||
+		parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, makeE(op, fmt.Errorf("invalid traceparent %q", s))
+	}
+
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, makeE(op, fmt.Errorf("invalid traceparent %q", s))
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, makeE(op, fmt.Errorf("invalid traceparent %q", s))
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return SpanContext{}, makeE(op, fmt.Errorf("invalid traceparent %q", s))
+	}
+	sc.Flags = byte(flags)
+
+	if !sc.IsValid() {
+		return SpanContext{}, makeE(op, fmt.Errorf("invalid traceparent %q", s))
+	}
+
+	return sc, nil
+}
+
+// Span là một thao tác được theo dõi
+type Span interface {
+	SpanContext() SpanContext
+	SetAttributes(attrs ...Field)
+	// Ghi nhận lời gọi bị lỗi
+	SetError(err error)
+	End()
+}
+
+// Tracer tạo span, ví dụ bằng một adapter cho OpenTelemetry. Span cha được
+// lấy từ ctx, là context truyền vào Client.WithContext.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// TracingInterceptor tạo một span cho mỗi lời gọi API với tên là op (ví dụ
+// "aiot.CreateThing"), gửi header traceparent của span trong request và
+// gắn id của các đối tượng, method, path và status vào span.
+func TracingInterceptor(t Tracer) Interceptor {
+	return func(call *Call, next Handler) (*Response, error) {
+		ctx, span := t.Start(call.Context, call.Op)
+		defer span.End()
+
+		call.Context = ctx
+		if sc := span.SpanContext(); sc.IsValid() {
+			call.Header.Set("traceparent", sc.TraceParent())
+		}
+
+		attrs := []Field{
+			{ATTR_HTTP_METHOD, call.Method},
+			{ATTR_URL_PATH, call.Path},
+		}
+		span.SetAttributes(append(attrs, call.Attributes...)...)
+
+		res, err := next(call)
+		if res != nil {
+			span.SetAttributes(Field{ATTR_HTTP_STATUS, res.StatusCode})
+		}
+
+		switch {
+		case err != nil:
+			span.SetAttributes(Field{ATTR_ERROR_KIND, kindOf(err).String()})
+			span.SetError(err)
+		case res.StatusCode != 200 && res.StatusCode != 201:
+			span.SetError(fmt.Errorf("status %d", res.StatusCode))
+		}
+
+		return res, err
+	}
+}
@@ -0,0 +1,140 @@
+package aiot_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+type span struct {
+	name   string
+	parent aiot.SpanContext
+	sc     aiot.SpanContext
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *span) SpanContext() aiot.SpanContext { return s.sc }
+
+func (s *span) SetAttributes(attrs ...aiot.Field) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *span) SetError(err error) { s.err = err }
+func (s *span) End()               { s.ended = true }
+
+type tracer struct {
+	mu    sync.Mutex
+	spans []*span
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, aiot.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := &span{name: name, attrs: map[string]interface{}{}}
+	if parent, ok := ctx.Value(spanKey{}).(aiot.SpanContext); ok {
+		s.parent = parent
+		s.sc.TraceID = parent.TraceID
+	} else {
+		s.sc.TraceID[0] = 0xaa
+	}
+	s.sc.SpanID[7] = byte(len(t.spans) + 1)
+	s.sc.Flags = 1
+	t.spans = append(t.spans, s)
+
+	return context.WithValue(ctx, spanKey{}, s.sc), s
+}
+
+func Test_Tracing(t *testing.T) {
+	require := require.New(t)
+
+	tr := &tracer{}
+	var headers []string
+	_, client, token := limitedClient(t, aiot.NewClientOptions().
+		SetTracer(tr).
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			headers = append(headers, call.Header.Get("traceparent"))
+			return next(call)
+		}))
+
+	parent, err := aiot.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(err)
+	ctx := context.WithValue(context.Background(), spanKey{}, parent)
+
+	require.Error(client.WithContext(ctx).DeleteThing(token, "t1"))
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+
+	require.Len(tr.spans, 2)
+	s := tr.spans[0]
+	require.Equal("aiot.DeleteThing", s.name)
+	require.True(s.ended)
+	require.Equal(parent, s.parent)
+	require.Equal(map[string]interface{}{
+		aiot.ATTR_HTTP_METHOD: http.MethodDelete,
+		aiot.ATTR_URL_PATH:    "/api-gw/v1/thing/t1",
+		aiot.ATTR_THING_ID:    "t1",
+		aiot.ATTR_HTTP_STATUS: http.StatusNotFound,
+	}, s.attrs)
+	require.EqualError(s.err, "status 404")
+	require.Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000001-01", headers[0])
+
+	// không có span cha thì bắt đầu trace mới
+	s = tr.spans[1]
+	require.Equal("aiot.UserProfile", s.name)
+	require.False(s.parent.IsValid())
+	require.NoError(s.err)
+	require.Equal(s.sc.TraceParent(), headers[1])
+}
+
+func Test_WithContext(t *testing.T) {
+	require := require.New(t)
+
+	tr := &tracer{}
+	_, client, token := limitedClient(t, aiot.NewClientOptions().SetTracer(tr))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.WithContext(ctx).UserProfile(token)
+	require.True(errors.Is(err, context.Canceled), err)
+	require.Equal("other", tr.spans[0].attrs[aiot.ATTR_ERROR_KIND])
+	require.Error(tr.spans[0].err)
+
+	// bản sao không ảnh hưởng đến client gốc
+	_, err = client.UserProfile(token)
+	require.NoError(err)
+}
+
+func Test_ParseTraceParent(t *testing.T) {
+	require := require.New(t)
+
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := aiot.ParseTraceParent(tp)
+	require.NoError(err)
+	require.True(sc.Sampled())
+	require.Equal(tp, sc.TraceParent())
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		_, err := aiot.ParseTraceParent(s)
+		require.Error(err, s)
+	}
+}
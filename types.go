@@ -1,5 +1,7 @@
 package aiot
 
+import "net/http"
+
 type User struct {
 	Email        string
 	Password     string
@@ -20,20 +22,26 @@ type Gateway struct {
 	Owner              string
 	UnderlayThing      Thing
 	UnderlayThingOwner string
+	// Phiên bản dùng cho UpdateGatewayInput.Version
+	Version string
 }
 
 type Thing struct {
 	ID       string
 	Key      string
 	Name     string
-	Metadata map[string]string
+	Metadata Metadata
+	// Phiên bản dùng cho UpdateThingInput.Version
+	Version string
 }
 
 type Channel struct {
 	ID       string
 	Key      string
 	Name     string
-	Metadata map[string]string
+	Metadata Metadata
+	// Phiên bản dùng cho UpdateChannelInput.Version
+	Version string
 }
 
 type CreateGatewayInput struct {
@@ -44,35 +52,52 @@ type CreateGatewayInput struct {
 
 type CreateThingInput struct {
 	Name     string
-	Metadata map[string]string
+	Metadata Metadata
 }
 
 type CreateChannelInput struct {
 	Name     string
-	Metadata map[string]string
+	Metadata Metadata
 }
 
 type UpdateThingInput struct {
 	ID       string
 	Name     string
-	Metadata map[string]string
+	Metadata Metadata
+	// Nếu khác rỗng, đặt key mới cho thing. Id và các kết nối của thing được
+	// giữ nguyên; key không được trùng với key của thing hoặc channel khác
+	Key string
+	// Nếu khác rỗng, chỉ cập nhật khi đối tượng vẫn ở phiên bản này (Version
+	// của lần đọc trước), ngược lại trả về lỗi KIND_CONFLICT
+	Version string
 }
 
 type UpdateGatewayInput struct {
 	ID          string
 	Name        string
 	Description string
+	// Nếu khác rỗng, chỉ cập nhật khi gateway vẫn ở phiên bản này (Version
+	// của lần đọc trước), ngược lại trả về lỗi KIND_CONFLICT
+	Version string
 }
 
 type UpdateChannelInput struct {
 	ID       string
 	Name     string
-	Metadata map[string]string
+	Metadata Metadata
+	// Nếu khác rỗng, chỉ cập nhật khi đối tượng vẫn ở phiên bản này (Version
+	// của lần đọc trước), ngược lại trả về lỗi KIND_CONFLICT
+	Version string
 }
 
 type request struct {
+	Op     operation
 	Path   string
 	Method string
 	Token  string
 	Body   interface{}
+	// Id của các đối tượng trong request, xem Call.Attributes
+	Attrs []Field
+	// Header thêm vào request, ví dụ If-Match
+	Header http.Header
 }
@@ -0,0 +1,172 @@
+package aiot
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Giới hạn của các trường trong input
+const (
+	MAX_NAME_LENGTH        = 1024
+	MAX_DESCRIPTION_LENGTH = 1024
+	// Kích thước tối đa của metadata sau khi mã hóa JSON (byte)
+	MAX_METADATA_SIZE = 64 * 1024
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// FieldError là một trường không hợp lệ của input
+type FieldError struct {
+	// Tên trường trong struct input, ví dụ "Name" hoặc "Metadata.floor"
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError liệt kê mọi trường không hợp lệ của một input. Client trả
+// về lỗi này (với loại KIND_INVALID) trước khi gửi request, lấy ra bằng
+// errors.As.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+
+	return "invalid input: " + strings.Join(msgs, "; ")
+}
+
+// Validate kiểm tra các trường bắt buộc, độ dài và kích thước metadata.
+// Lỗi trả về có kiểu *ValidationError.
+func (in CreateThingInput) Validate() error {
+	var v validator
+	v.name("Name", in.Name)
+	v.metadata("Metadata", in.Metadata)
+
+	return v.err()
+}
+
+func (in CreateChannelInput) Validate() error {
+	var v validator
+	v.name("Name", in.Name)
+	v.metadata("Metadata", in.Metadata)
+
+	return v.err()
+}
+
+// Validate yêu cầu ThingID là UUID của thing nền.
+func (in CreateGatewayInput) Validate() error {
+	var v validator
+	v.name("Name", in.Name)
+	v.description("Description", in.Description)
+	v.id("ThingID", in.ThingID)
+
+	return v.err()
+}
+
+// Validate yêu cầu ID (và Key nếu khác rỗng) là UUID, các trường khác như
+// CreateThingInput.
+func (in UpdateThingInput) Validate() error {
+	var v validator
+	v.id("ID", in.ID)
+	v.name("Name", in.Name)
+	v.metadata("Metadata", in.Metadata)
+	if in.Key != "" {
+		v.key("Key", in.Key)
+	}
+
+	return v.err()
+}
+
+func (in UpdateChannelInput) Validate() error {
+	var v validator
+	v.id("ID", in.ID)
+	v.name("Name", in.Name)
+	v.metadata("Metadata", in.Metadata)
+
+	return v.err()
+}
+
+func (in UpdateGatewayInput) Validate() error {
+	var v validator
+	v.id("ID", in.ID)
+	v.name("Name", in.Name)
+	v.description("Description", in.Description)
+
+	return v.err()
+}
+
+// validator gom các lỗi của từng trường theo thứ tự kiểm tra
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) name(field, s string) {
+	switch {
+	case strings.TrimSpace(s) == "":
+		v.add(field, "must not be empty")
+	case utf8.RuneCountInString(s) > MAX_NAME_LENGTH:
+		v.add(field, "must be at most %d characters", MAX_NAME_LENGTH)
+	}
+}
+
+func (v *validator) description(field, s string) {
+	if utf8.RuneCountInString(s) > MAX_DESCRIPTION_LENGTH {
+		v.add(field, "must be at most %d characters", MAX_DESCRIPTION_LENGTH)
+	}
+}
+
+func (v *validator) id(field, s string) {
+	switch {
+	case s == "":
+		v.add(field, "must not be empty")
+	case !uuidPattern.MatchString(s):
+		v.add(field, "must be a UUID, got %q", s)
+	}
+}
+
+// key yêu cầu key là UUID như key do gateway cấp. Giá trị không được đưa vào
+// thông báo lỗi.
+func (v *validator) key(field, s string) {
+	if !uuidPattern.MatchString(s) {
+		v.add(field, "must be a UUID")
+	}
+}
+
+func (v *validator) metadata(field string, m Metadata) {
+	for k := range m {
+		if strings.TrimSpace(k) == "" {
+			v.add(field, "keys must not be empty")
+			break
+		}
+	}
+
+	data, err := json.Marshal(m)
+	switch {
+	case err != nil:
+		v.add(field, "must be encodable as JSON: %s", err)
+	case len(data) > MAX_METADATA_SIZE:
+		v.add(field, "must be at most %d bytes as JSON, got %d", MAX_METADATA_SIZE, len(data))
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Errors: v.errs}
+}
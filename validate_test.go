@@ -0,0 +1,92 @@
+package aiot_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/stretchr/testify/require"
+)
+
+const validID = "6f1c2a7e-3b4d-4e5f-8a9b-0c1d2e3f4a5b"
+
+func Test_Validate(t *testing.T) {
+	require := require.New(t)
+
+	long := strings.Repeat("ư", aiot.MAX_NAME_LENGTH+1)
+	big := aiot.Metadata{"blob": strings.Repeat("x", aiot.MAX_METADATA_SIZE)}
+
+	tests := []struct {
+		input  interface{ Validate() error }
+		fields []string
+	}{
+		{aiot.CreateThingInput{Name: "sensor", Metadata: aiot.Metadata{"floor": 1}}, nil},
+		{aiot.CreateThingInput{Name: strings.Repeat("ư", aiot.MAX_NAME_LENGTH)}, nil},
+		{aiot.CreateThingInput{Name: "  "}, []string{"Name"}},
+		{aiot.CreateThingInput{Name: long, Metadata: big}, []string{"Name", "Metadata"}},
+		{aiot.CreateThingInput{Name: "sensor", Metadata: aiot.Metadata{"": 1}}, []string{"Metadata"}},
+		{aiot.CreateThingInput{Name: "sensor", Metadata: aiot.Metadata{"f": func() {}}}, []string{"Metadata"}},
+		{aiot.CreateChannelInput{}, []string{"Name"}},
+		{aiot.CreateGatewayInput{Name: "gw", ThingID: validID}, nil},
+		{aiot.CreateGatewayInput{Name: "gw"}, []string{"ThingID"}},
+		{aiot.CreateGatewayInput{Description: long, ThingID: "t1"}, []string{"Name", "Description", "ThingID"}},
+		{aiot.UpdateThingInput{ID: validID, Name: "sensor"}, nil},
+		{aiot.UpdateThingInput{ID: "t1", Name: "sensor"}, []string{"ID"}},
+		{aiot.UpdateThingInput{ID: validID, Name: "sensor", Key: validID}, nil},
+		{aiot.UpdateThingInput{ID: validID, Name: "sensor", Key: "leaked key"}, []string{"Key"}},
+		{aiot.UpdateThingInput{ID: validID, Name: "sensor", Key: validID + "0"}, []string{"Key"}},
+		{aiot.UpdateChannelInput{Name: "telemetry", Metadata: big}, []string{"ID", "Metadata"}},
+		{aiot.UpdateGatewayInput{ID: strings.ToUpper(validID), Name: "gw"}, nil},
+		{aiot.UpdateGatewayInput{ID: validID}, []string{"Name"}},
+	}
+
+	for i, tt := range tests {
+		err := tt.input.Validate()
+		if tt.fields == nil {
+			require.NoError(err, i)
+			continue
+		}
+
+		var verr *aiot.ValidationError
+		require.True(errors.As(err, &verr), i)
+
+		var fields []string
+		for _, fe := range verr.Errors {
+			fields = append(fields, fe.Field)
+		}
+		require.Equal(tt.fields, fields, i)
+	}
+
+	err := aiot.CreateGatewayInput{Name: "gw", ThingID: "t1"}.Validate()
+	require.EqualError(err, `invalid input: ThingID: must be a UUID, got "t1"`)
+}
+
+func Test_ValidateBeforeRequest(t *testing.T) {
+	require := require.New(t)
+
+	var calls int
+	_, client, token := limitedClient(t, aiot.NewClientOptions().
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			calls++
+			return next(call)
+		}))
+
+	err := client.CreateGateway(token, aiot.CreateGatewayInput{Description: "floor 1"})
+	require.True(aiot.Is(aiot.KIND_INVALID, err), err)
+	require.Contains(err.Error(), "aiot.CreateGateway")
+
+	var verr *aiot.ValidationError
+	require.True(errors.As(err, &verr))
+	require.Len(verr.Errors, 2)
+
+	require.True(aiot.Is(aiot.KIND_INVALID, client.CreateThing(token, aiot.CreateThingInput{})))
+	require.True(aiot.Is(aiot.KIND_INVALID, client.CreateChannel(token, aiot.CreateChannelInput{})))
+	require.True(aiot.Is(aiot.KIND_INVALID, client.UpdateThing(token, aiot.UpdateThingInput{ID: "t1", Name: "x"})))
+	require.True(aiot.Is(aiot.KIND_INVALID, client.UpdateChannel(token, aiot.UpdateChannelInput{ID: "c1", Name: "x"})))
+	require.True(aiot.Is(aiot.KIND_INVALID, client.UpdateGateway(token, aiot.UpdateGatewayInput{ID: "g1", Name: "x"})))
+	require.Zero(calls)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	require.Equal(1, calls)
+}
@@ -0,0 +1,103 @@
+package aiot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrConflict được trả về (với loại KIND_CONFLICT) khi đối tượng đã bị thay
+// đổi so với Version truyền vào lời gọi cập nhật
+var ErrConflict = errors.New("entity was modified since the expected version")
+
+// Version của Thing, Channel và Gateway là ETag do gateway trả về (dạng chuỗi
+// trong dấu nháy), hoặc nếu gateway không hỗ trợ ETag thì là mã băm nội dung
+// do client tính.
+
+func isETag(version string) bool {
+	return strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`)
+}
+
+// contentVersion băm các trường của đối tượng theo dạng JSON, khóa của map
+// được sắp xếp nên kết quả không phụ thuộc thứ tự
+func contentVersion(fields ...interface{}) string {
+	data, _ := json.Marshal(fields)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:16])
+}
+
+func thingVersion(t Thing) string {
+	return contentVersion(t.ID, t.Name, t.Key, hashable(t.Metadata))
+}
+
+func channelVersion(ch Channel) string {
+	return contentVersion(ch.ID, ch.Name, ch.Key, hashable(ch.Metadata))
+}
+
+// hashable coi metadata rỗng và nil là như nhau
+func hashable(m Metadata) interface{} {
+	if len(m) == 0 {
+		return nil
+	}
+
+	return m
+}
+
+func gatewayVersion(g Gateway) string {
+	return contentVersion(g.ID, g.Name, g.Description, g.UnderlayThing.ID)
+}
+
+// Kết quả của các API danh sách không có ETag nên Version là mã băm nội dung
+func withThingVersions(things []Thing) []Thing {
+	for i := range things {
+		things[i].Version = thingVersion(things[i])
+	}
+
+	return things
+}
+
+func withChannelVersions(channels []Channel) []Channel {
+	for i := range channels {
+		channels[i].Version = channelVersion(channels[i])
+	}
+
+	return channels
+}
+
+// profileVersion là ETag của response nếu có, ngược lại là mã băm nội dung
+func profileVersion(resp *http.Response, hash string) string {
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return etag
+	}
+
+	return hash
+}
+
+// precondition chuẩn bị lời gọi cập nhật có điều kiện. Với ETag, header
+// If-Match được trả về để gateway tự kiểm tra. Với mã băm nội dung, current
+// đọc lại đối tượng và trả về mã băm hiện tại để so sánh; giữa lúc so sánh và
+// lúc ghi vẫn có thể có thay đổi khác.
+func precondition(version string, current func() (string, error)) (http.Header, error) {
+	if version == "" {
+		return nil, nil
+	}
+
+	if isETag(version) {
+		return http.Header{"If-Match": {version}}, nil
+	}
+
+	hash, err := current()
+	if err != nil {
+		return nil, err
+	}
+
+	if hash != version {
+		return nil, makeE(KIND_CONFLICT, ErrConflict)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,152 @@
+package aiot_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UpdateVersionHash(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions())
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor", Metadata: aiot.Metadata{"floor": 1}}))
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+
+	// phiên bản từ danh sách và từ profile là như nhau
+	thing, err := client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.NotEmpty(thing.Version)
+	require.Equal(things[0].Version, thing.Version)
+
+	// operator A cập nhật trước, operator B vẫn giữ phiên bản cũ
+	require.NoError(client.UpdateThing(token, aiot.UpdateThingInput{ID: thing.ID, Name: "sensor-a", Version: thing.Version}))
+
+	err = client.UpdateThing(token, aiot.UpdateThingInput{ID: thing.ID, Name: "sensor-b", Version: thing.Version})
+	require.True(aiot.Is(aiot.KIND_CONFLICT, err), err)
+	require.True(errors.Is(err, aiot.ErrConflict), err)
+
+	thing, err = client.ThingProfile(token, thing.ID)
+	require.NoError(err)
+	require.Equal("sensor-a", thing.Name)
+
+	// không có Version thì cập nhật không điều kiện như trước
+	require.NoError(client.UpdateThing(token, aiot.UpdateThingInput{ID: thing.ID, Name: "sensor-c"}))
+}
+
+func Test_UpdateVersionChannelGateway(t *testing.T) {
+	require := require.New(t)
+
+	_, client, token := limitedClient(t, aiot.NewClientOptions())
+
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "alerts"}))
+	channels, err := inventory.Channels(client, token)
+	require.NoError(err)
+	ch := channels[0]
+
+	require.NoError(client.UpdateChannel(token, aiot.UpdateChannelInput{ID: ch.ID, Name: "alerts-1", Version: ch.Version}))
+	err = client.UpdateChannel(token, aiot.UpdateChannelInput{ID: ch.ID, Name: "alerts-2", Version: ch.Version})
+	require.True(aiot.Is(aiot.KIND_CONFLICT, err), err)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "gw-thing"}))
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+	require.NoError(client.CreateGateway(token, aiot.CreateGatewayInput{Name: "gw", ThingID: things[0].ID}))
+
+	gateways, err := client.ListGateway(token)
+	require.NoError(err)
+	gw, err := client.GatewayProfile(token, gateways[0].ID)
+	require.NoError(err)
+	require.Equal(gateways[0].Version, gw.Version)
+
+	require.NoError(client.UpdateGateway(token, aiot.UpdateGatewayInput{ID: gw.ID, Name: "gw-1", Version: gw.Version}))
+	err = client.UpdateGateway(token, aiot.UpdateGatewayInput{ID: gw.ID, Name: "gw-2", Description: "x", Version: gw.Version})
+	require.True(aiot.Is(aiot.KIND_CONFLICT, err), err)
+
+	// thay đổi metadata của thing không làm thay đổi phiên bản của gateway
+	require.NoError(client.UpdateThing(token, aiot.UpdateThingInput{ID: things[0].ID, Name: "gw-thing", Metadata: aiot.Metadata{"floor": 2}}))
+	gw, err = client.GatewayProfile(token, gw.ID)
+	require.NoError(err)
+	version := gw.Version
+	gw, err = client.GatewayProfile(token, gw.ID)
+	require.NoError(err)
+	require.Equal(version, gw.Version)
+	require.NoError(client.UpdateGateway(token, aiot.UpdateGatewayInput{ID: gw.ID, Name: "gw-3", Version: gw.Version}))
+}
+
+func Test_UpdateVersionETag(t *testing.T) {
+	require := require.New(t)
+
+	var ifMatch []string
+	srv, client, token := limitedClient(t, aiot.NewClientOptions().
+		AddInterceptor(func(call *aiot.Call, next aiot.Handler) (*aiot.Response, error) {
+			if v := call.Header.Get("If-Match"); v != "" {
+				ifMatch = append(ifMatch, v)
+			}
+			return next(call)
+		}))
+	srv.EnableETags(true)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+
+	thing, err := client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.True(strings.HasPrefix(thing.Version, `"`), thing.Version)
+
+	require.NoError(client.UpdateThing(token, aiot.UpdateThingInput{ID: thing.ID, Name: "sensor-a", Version: thing.Version}))
+	err = client.UpdateThing(token, aiot.UpdateThingInput{ID: thing.ID, Name: "sensor-b", Version: thing.Version})
+	require.True(aiot.Is(aiot.KIND_CONFLICT, err), err)
+	require.True(errors.Is(err, aiot.ErrConflict), err)
+	require.Contains(err.Error(), "PRECONDITION_FAILED")
+	require.Equal([]string{thing.Version, thing.Version}, ifMatch)
+
+	// phiên bản từ danh sách vẫn là mã băm nội dung
+	things, err = inventory.Things(client, token)
+	require.NoError(err)
+	require.NoError(client.UpdateThing(token, aiot.UpdateThingInput{ID: thing.ID, Name: "sensor-c", Version: things[0].Version}))
+	require.Len(ifMatch, 2)
+}
+
+func Test_PatchAcrossClients(t *testing.T) {
+	require := require.New(t)
+
+	srv, client, token := limitedClient(t, aiot.NewClientOptions())
+	srv.EnableETags(true)
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	things, err := inventory.Things(client, token)
+	require.NoError(err)
+
+	// mỗi dịch vụ dùng Client riêng nên chỉ có ETag ngăn các lần ghi đè nhau
+	var wg sync.WaitGroup
+	errs := make([]error, 6)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := aiot.NewClientWithOptions(srv.URL, aiot.NewClientOptions().SetPatchRetries(50))
+			_, errs[i] = c.PatchThingMetadata(token, things[0].ID, aiot.Metadata{fmt.Sprintf("k%d", i): i})
+		}(i)
+	}
+	wg.Wait()
+
+	want := aiot.Metadata{}
+	for i, err := range errs {
+		require.NoError(err)
+		want[fmt.Sprintf("k%d", i)] = i
+	}
+
+	thing, err := client.ThingProfile(token, things[0].ID)
+	require.NoError(err)
+	require.True(want.Equal(thing.Metadata), thing.Metadata)
+}
@@ -0,0 +1,209 @@
+package watch
+
+import (
+	"sort"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+)
+
+type EventType string
+type Kind string
+
+var (
+	EVENT_CREATED      EventType = "created"
+	EVENT_UPDATED      EventType = "updated"
+	EVENT_DELETED      EventType = "deleted"
+	EVENT_CONNECTED    EventType = "connected"
+	EVENT_DISCONNECTED EventType = "disconnected"
+
+	KIND_THING      Kind = "thing"
+	KIND_CHANNEL    Kind = "channel"
+	KIND_GATEWAY    Kind = "gateway"
+	KIND_CONNECTION Kind = "connection"
+)
+
+// Event là một thay đổi giữa hai snapshot. Với KIND_CONNECTION, ThingID và
+// ChannelID là hai đầu của kết nối và ID rỗng.
+type Event struct {
+	Type      EventType `json:"type"`
+	Kind      Kind      `json:"kind"`
+	ID        string    `json:"id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	ThingID   string    `json:"thingId,omitempty"`
+	ChannelID string    `json:"channelId,omitempty"`
+	Changes   []Change  `json:"changes,omitempty"`
+}
+
+// Change là một trường thay đổi của EVENT_UPDATED, ví dụ "name",
+// "metadata.floor" hoặc "thing.id" với gateway. Old hoặc New rỗng khi khóa
+// metadata được thêm hoặc bị xóa. Với trường "key", Old và New là
+// aiot.Redacted.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Diff trả về các thay đổi từ old sang new theo thứ tự: tạo mới, cập nhật,
+// kết nối, ngắt kết nối, xóa.
+func Diff(old, new inventory.Snapshot) []Event {
+	var created, updated, connected, disconnected, deleted []Event
+
+	oldThings := map[string]aiot.Thing{}
+	for _, t := range old.Things {
+		oldThings[t.ID] = t
+	}
+	newThings := map[string]aiot.Thing{}
+	for _, t := range new.Things {
+		newThings[t.ID] = t
+		prev, ok := oldThings[t.ID]
+		if !ok {
+			created = append(created, Event{Type: EVENT_CREATED, Kind: KIND_THING, ID: t.ID, Name: t.Name})
+			continue
+		}
+		if changes := entityChanges(prev.Name, t.Name, prev.Key, t.Key, prev.Metadata, t.Metadata); len(changes) > 0 {
+			updated = append(updated, Event{Type: EVENT_UPDATED, Kind: KIND_THING, ID: t.ID, Name: t.Name, Changes: changes})
+		}
+	}
+
+	oldChannels := map[string]aiot.Channel{}
+	for _, c := range old.Channels {
+		oldChannels[c.ID] = c
+	}
+	newChannels := map[string]aiot.Channel{}
+	for _, c := range new.Channels {
+		newChannels[c.ID] = c
+		prev, ok := oldChannels[c.ID]
+		if !ok {
+			created = append(created, Event{Type: EVENT_CREATED, Kind: KIND_CHANNEL, ID: c.ID, Name: c.Name})
+			continue
+		}
+		if changes := entityChanges(prev.Name, c.Name, prev.Key, c.Key, prev.Metadata, c.Metadata); len(changes) > 0 {
+			updated = append(updated, Event{Type: EVENT_UPDATED, Kind: KIND_CHANNEL, ID: c.ID, Name: c.Name, Changes: changes})
+		}
+	}
+
+	oldGateways := map[string]aiot.Gateway{}
+	for _, g := range old.Gateways {
+		oldGateways[g.ID] = g
+	}
+	newGateways := map[string]aiot.Gateway{}
+	for _, g := range new.Gateways {
+		newGateways[g.ID] = g
+		prev, ok := oldGateways[g.ID]
+		if !ok {
+			created = append(created, Event{Type: EVENT_CREATED, Kind: KIND_GATEWAY, ID: g.ID, Name: g.Name})
+			continue
+		}
+		if changes := gatewayChanges(prev, g); len(changes) > 0 {
+			updated = append(updated, Event{Type: EVENT_UPDATED, Kind: KIND_GATEWAY, ID: g.ID, Name: g.Name, Changes: changes})
+		}
+	}
+
+	oldConns := map[inventory.Connection]bool{}
+	for _, c := range old.Connections {
+		oldConns[c] = true
+	}
+	newConns := map[inventory.Connection]bool{}
+	for _, c := range new.Connections {
+		newConns[c] = true
+		if !oldConns[c] {
+			connected = append(connected, Event{Type: EVENT_CONNECTED, Kind: KIND_CONNECTION, ThingID: c.ThingID, ChannelID: c.ChannelID})
+		}
+	}
+	for _, c := range old.Connections {
+		if !newConns[c] {
+			disconnected = append(disconnected, Event{Type: EVENT_DISCONNECTED, Kind: KIND_CONNECTION, ThingID: c.ThingID, ChannelID: c.ChannelID})
+		}
+	}
+
+	for _, g := range old.Gateways {
+		if _, ok := newGateways[g.ID]; !ok {
+			deleted = append(deleted, Event{Type: EVENT_DELETED, Kind: KIND_GATEWAY, ID: g.ID, Name: g.Name})
+		}
+	}
+	for _, t := range old.Things {
+		if _, ok := newThings[t.ID]; !ok {
+			deleted = append(deleted, Event{Type: EVENT_DELETED, Kind: KIND_THING, ID: t.ID, Name: t.Name})
+		}
+	}
+	for _, c := range old.Channels {
+		if _, ok := newChannels[c.ID]; !ok {
+			deleted = append(deleted, Event{Type: EVENT_DELETED, Kind: KIND_CHANNEL, ID: c.ID, Name: c.Name})
+		}
+	}
+
+	var events []Event
+	events = append(events, created...)
+	events = append(events, updated...)
+	events = append(events, connected...)
+	events = append(events, disconnected...)
+	events = append(events, deleted...)
+
+	return events
+}
+
+func entityChanges(oldName, newName, oldKey, newKey string, oldMeta, newMeta aiot.Metadata) []Change {
+	var changes []Change
+	if oldName != newName {
+		changes = append(changes, Change{Field: "name", Old: oldName, New: newName})
+	}
+	if oldKey != newKey {
+		changes = append(changes, Change{Field: "key", Old: aiot.Redacted, New: aiot.Redacted})
+	}
+
+	return append(changes, metadataChanges("metadata.", oldMeta, newMeta)...)
+}
+
+func gatewayChanges(old, new aiot.Gateway) []Change {
+	var changes []Change
+	if old.Name != new.Name {
+		changes = append(changes, Change{Field: "name", Old: old.Name, New: new.Name})
+	}
+	if old.Description != new.Description {
+		changes = append(changes, Change{Field: "description", Old: old.Description, New: new.Description})
+	}
+	if old.UnderlayThing.ID != new.UnderlayThing.ID {
+		changes = append(changes, Change{Field: "thing.id", Old: old.UnderlayThing.ID, New: new.UnderlayThing.ID})
+	}
+
+	return changes
+}
+
+// metadataChanges so sánh metadata theo từng khóa, sắp xếp theo khóa. Object
+// lồng nhau được so sánh theo từng khóa con, ví dụ metadata.location.floor.
+func metadataChanges(prefix string, old, new aiot.Metadata) []Change {
+	keys := map[string]bool{}
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range new {
+		keys[k] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []Change
+	for _, k := range sorted {
+		o, hadKey := old[k]
+		n, hasKey := new[k]
+
+		oldMap, oldIsMap := old.Map(k)
+		newMap, newIsMap := new.Map(k)
+		if oldIsMap && newIsMap {
+			changes = append(changes, metadataChanges(prefix+k+".", oldMap, newMap)...)
+			continue
+		}
+
+		if hadKey != hasKey || !(aiot.Metadata{k: o}).Equal(aiot.Metadata{k: n}) {
+			changes = append(changes, Change{Field: prefix + k, Old: old.Text(k), New: new.Text(k)})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,42 @@
+package watch
+
+import "time"
+
+type Options struct {
+	interval  time.Duration
+	stateFile string
+	initial   bool
+	onError   func(error)
+}
+
+func NewOptions() *Options {
+	return &Options{
+		interval: 30 * time.Second,
+	}
+}
+
+// Khoảng thời gian giữa hai lần chụp snapshot, mặc định 30 giây
+func (opts *Options) SetInterval(d time.Duration) *Options {
+	opts.interval = d
+	return opts
+}
+
+// File lưu snapshot gần nhất để lần chạy sau tiếp tục từ đó thay vì phát lại
+// toàn bộ. Rỗng (mặc định) là không lưu.
+func (opts *Options) SetStateFile(path string) *Options {
+	opts.stateFile = path
+	return opts
+}
+
+// Phát EVENT_CREATED cho mọi đối tượng ở lần chụp đầu tiên khi chưa có
+// snapshot trước đó. Mặc định lần chụp đầu tiên chỉ được ghi nhận.
+func (opts *Options) SetEmitInitial(emit bool) *Options {
+	opts.initial = emit
+	return opts
+}
+
+// Hàm được gọi khi một lần chụp snapshot hoặc lưu state thất bại
+func (opts *Options) SetOnError(fn func(error)) *Options {
+	opts.onError = fn
+	return opts
+}
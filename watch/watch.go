@@ -0,0 +1,215 @@
+// Package watch định kỳ chụp snapshot thing, channel, kết nối và gateway của
+// một tài khoản AIOT, so sánh với lần chụp trước và gửi các thay đổi dưới dạng
+// Event cho các subscriber. Snapshot gần nhất có thể được lưu vào state file để
+// khi khởi động lại chỉ gửi các thay đổi mới.
+//
+//	w, err := watch.NewWatcher(client, token, watch.NewOptions().
+//		SetStateFile("watch.json"))
+//	w.Subscribe(func(e watch.Event) { log.Println(e.Type, e.Kind, e.ID) })
+//	w.Start()
+//	defer w.Close()
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+)
+
+// Watcher định kỳ chụp snapshot thing, channel, kết nối và gateway của tài
+// khoản và gửi các thay đổi so với lần chụp trước cho các subscriber.
+type Watcher struct {
+	api   aiot.API
+	token string
+	opts  *Options
+
+	done    chan struct{}
+	stopped chan struct{}
+	start   sync.Once
+	once    sync.Once
+
+	// poll giữ cho các lần chụp không chạy chồng lên nhau
+	poll sync.Mutex
+	last *inventory.Snapshot
+
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]func(Event)
+}
+
+// Tạo mới một Watcher. Nếu có state file từ lần chạy trước, snapshot trong đó
+// là mốc so sánh cho lần chụp đầu tiên.
+func NewWatcher(api aiot.API, token string, opts *Options) (*Watcher, error) {
+	const op = "watch.NewWatcher"
+
+	if opts == nil {
+		opts = NewOptions()
+	}
+
+	w := &Watcher{
+		api:     api,
+		token:   token,
+		opts:    opts,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		subs:    map[int]func(Event){},
+	}
+
+	if opts.stateFile != "" {
+		s, err := loadState(opts.stateFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s -> %w", op, err)
+		}
+		w.last = s
+	}
+
+	return w, nil
+}
+
+// Subscribe đăng ký fn nhận các event theo thứ tự, trả về hàm hủy đăng ký.
+// fn được gọi trên goroutine thực hiện lần chụp nên không nên chặn lâu.
+func (w *Watcher) Subscribe(fn func(Event)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.subs[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		delete(w.subs, id)
+		w.mu.Unlock()
+	}
+}
+
+// Poll chụp snapshot ngay, gửi các thay đổi cho subscriber và lưu snapshot
+// vào state file. Các event cũng được trả về.
+func (w *Watcher) Poll() ([]Event, error) {
+	const op = "watch.Poll"
+
+	w.poll.Lock()
+	defer w.poll.Unlock()
+
+	s, err := inventory.Take(w.api, w.token)
+	if err != nil {
+		return nil, fmt.Errorf("%s -> %w", op, err)
+	}
+
+	var events []Event
+	if w.last != nil || w.opts.initial {
+		var prev inventory.Snapshot
+		if w.last != nil {
+			prev = *w.last
+		}
+		events = Diff(prev, s)
+	}
+
+	// lưu trước khi gửi để event không bị phát lại nếu tiến trình dừng giữa chừng
+	if w.opts.stateFile != "" {
+		if err := saveState(w.opts.stateFile, s); err != nil {
+			return nil, fmt.Errorf("%s -> %w", op, err)
+		}
+	}
+	w.last = &s
+
+	w.mu.Lock()
+	subs := make([]func(Event), 0, len(w.subs))
+	for id := 0; id < w.nextID; id++ {
+		if fn, ok := w.subs[id]; ok {
+			subs = append(subs, fn)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, e := range events {
+		for _, fn := range subs {
+			fn(e)
+		}
+	}
+
+	return events, nil
+}
+
+// Start chụp snapshot ngay và sau đó theo chu kỳ cho đến khi Close được gọi.
+// Lỗi được báo qua Options.SetOnError.
+func (w *Watcher) Start() {
+	w.start.Do(func() {
+		go w.run()
+	})
+}
+
+// Close dừng Watcher và chờ lần chụp đang thực hiện (nếu có) kết thúc
+func (w *Watcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+
+	started := true
+	w.start.Do(func() {
+		started = false
+	})
+	if started {
+		<-w.stopped
+	}
+
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer close(w.stopped)
+
+	t := time.NewTicker(w.opts.interval)
+	defer t.Stop()
+
+	for {
+		if _, err := w.Poll(); err != nil && w.opts.onError != nil {
+			w.opts.onError(err)
+		}
+
+		select {
+		case <-t.C:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func loadState(path string) (*inventory.Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s inventory.Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("state file %s: %w", path, err)
+	}
+
+	return &s, nil
+}
+
+// saveState ghi snapshot qua file tạm để state file luôn đầy đủ. Snapshot có
+// key của thing và channel nên file chỉ chủ sở hữu đọc được.
+func saveState(path string, s inventory.Snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
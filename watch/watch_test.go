@@ -0,0 +1,160 @@
+package watch_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mobifone-aiot/aiot-go"
+	"github.com/mobifone-aiot/aiot-go/aiottest"
+	"github.com/mobifone-aiot/aiot-go/inventory"
+	"github.com/mobifone-aiot/aiot-go/watch"
+	"github.com/stretchr/testify/require"
+)
+
+// describe rút gọn event để so sánh
+func describe(events []watch.Event) []string {
+	var out []string
+	for _, e := range events {
+		s := string(e.Type) + " " + string(e.Kind) + " " + e.ID + e.ThingID
+		if e.ChannelID != "" {
+			s += " -> " + e.ChannelID
+		}
+		for _, c := range e.Changes {
+			s += " " + c.Field + "=" + c.Old + ":" + c.New
+		}
+		out = append(out, s)
+	}
+
+	return out
+}
+
+func Test_Diff(t *testing.T) {
+	require := require.New(t)
+
+	old := inventory.Snapshot{
+		Things: []aiot.Thing{
+			{ID: "t1", Name: "sensor", Key: "k1", Metadata: aiot.Metadata{"floor": "1", "room": "a"}},
+			{ID: "t2", Name: "gone"},
+		},
+		Channels:    []aiot.Channel{{ID: "c1", Name: "telemetry"}},
+		Connections: []inventory.Connection{{ThingID: "t1", ChannelID: "c1"}, {ThingID: "t2", ChannelID: "c1"}},
+		Gateways:    []aiot.Gateway{{ID: "g1", Name: "gw", UnderlayThing: aiot.Thing{ID: "t2"}}},
+	}
+	new := inventory.Snapshot{
+		Things: []aiot.Thing{
+			{ID: "t1", Name: "sensor-1", Key: "k2", Metadata: aiot.Metadata{"floor": "2", "zone": "b"}},
+			{ID: "t3", Name: "fresh"},
+		},
+		Channels:    []aiot.Channel{{ID: "c1", Name: "telemetry"}, {ID: "c2", Name: "alerts"}},
+		Connections: []inventory.Connection{{ThingID: "t1", ChannelID: "c1"}, {ThingID: "t3", ChannelID: "c2"}},
+		Gateways:    []aiot.Gateway{{ID: "g1", Name: "gw", Description: "floor 1", UnderlayThing: aiot.Thing{ID: "t3"}}},
+	}
+
+	require.Equal([]string{
+		"created thing t3",
+		"created channel c2",
+		"updated thing t1 name=sensor:sensor-1 key=[REDACTED]:[REDACTED] metadata.floor=1:2 metadata.room=a: metadata.zone=:b",
+		"updated gateway g1 description=:floor 1 thing.id=t2:t3",
+		"connected connection t3 -> c2",
+		"disconnected connection t2 -> c1",
+		"deleted thing t2",
+	}, describe(watch.Diff(old, new)))
+
+	require.Empty(watch.Diff(new, new))
+
+	// metadata rỗng và không có metadata là như nhau
+	a := inventory.Snapshot{Things: []aiot.Thing{{ID: "t1"}}}
+	b := inventory.Snapshot{Things: []aiot.Thing{{ID: "t1", Metadata: aiot.Metadata{}}}}
+	require.Empty(watch.Diff(a, b))
+
+	// object lồng nhau được so sánh theo từng khóa con, 1 và 1.0 là như nhau
+	a = inventory.Snapshot{Things: []aiot.Thing{{ID: "t1", Metadata: aiot.Metadata{
+		"location": map[string]interface{}{"floor": 1, "room": "a"},
+		"tags":     []interface{}{"x"},
+	}}}}
+	b = inventory.Snapshot{Things: []aiot.Thing{{ID: "t1", Metadata: aiot.Metadata{
+		"location": map[string]interface{}{"floor": 1.0, "room": "b"},
+		"tags":     []interface{}{"x", "y"},
+	}}}}
+	require.Equal([]string{
+		`updated thing t1 metadata.location.room=a:b metadata.tags=["x"]:["x","y"]`,
+	}, describe(watch.Diff(a, b)))
+}
+
+func Test_Watcher(t *testing.T) {
+	require := require.New(t)
+	client, token := aiottest.NewAccount(t)
+	state := filepath.Join(t.TempDir(), "state.json")
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+
+	w, err := watch.NewWatcher(client, token, watch.NewOptions().SetStateFile(state))
+	require.NoError(err)
+
+	var got []watch.Event
+	unsubscribe := w.Subscribe(func(e watch.Event) {
+		got = append(got, e)
+	})
+
+	// lần chụp đầu tiên chỉ là mốc so sánh
+	events, err := w.Poll()
+	require.NoError(err)
+	require.Empty(events)
+
+	require.NoError(client.CreateChannel(token, aiot.CreateChannelInput{Name: "telemetry"}))
+	events, err = w.Poll()
+	require.NoError(err)
+	require.Len(events, 1)
+	require.Equal(watch.EVENT_CREATED, events[0].Type)
+	require.Equal("telemetry", events[0].Name)
+	require.Equal(events, got)
+
+	unsubscribe()
+	s, err := inventory.Take(client, token)
+	require.NoError(err)
+	require.NoError(client.Connect(token, []string{s.Channels[0].ID}, []string{s.Things[0].ID}))
+
+	events, err = w.Poll()
+	require.NoError(err)
+	require.Equal([]string{"connected connection " + s.Things[0].ID + " -> " + s.Channels[0].ID}, describe(events))
+	require.Len(got, 1)
+	require.NoError(w.Close())
+
+	// chạy lại từ state file không phát lại những gì đã thấy
+	require.NoError(client.UpdateThing(token, aiot.UpdateThingInput{ID: s.Things[0].ID, Name: "sensor-1"}))
+
+	w, err = watch.NewWatcher(client, token, watch.NewOptions().SetStateFile(state))
+	require.NoError(err)
+	events, err = w.Poll()
+	require.NoError(err)
+	require.Equal([]string{"updated thing " + s.Things[0].ID + " name=sensor:sensor-1"}, describe(events))
+}
+
+func Test_Start(t *testing.T) {
+	require := require.New(t)
+	client, token := aiottest.NewAccount(t)
+
+	w, err := watch.NewWatcher(client, token, watch.NewOptions().SetInterval(10*time.Millisecond).SetEmitInitial(true))
+	require.NoError(err)
+
+	var mu sync.Mutex
+	var names []string
+	w.Subscribe(func(e watch.Event) {
+		mu.Lock()
+		names = append(names, e.Name)
+		mu.Unlock()
+	})
+
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "sensor"}))
+	w.Start()
+	require.NoError(client.CreateThing(token, aiot.CreateThingInput{Name: "other"}))
+
+	require.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(names) == 2
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(w.Close())
+}